@@ -0,0 +1,201 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// ErrAlgorithmExists is returned when registering an Algorithm with a name, which is already used.
+var ErrAlgorithmExists = errors.New("algorithm already exists")
+
+// A CustomAlgorithm is a search algorithm, which can be used like the built-in ones once it has been registered with
+// RegisterAlgorithm: searches with its Algorithm in the PathSettings hand the SearchProblem to it and turn the result
+// into a Path, e.g. with the waypoints and postures the settings ask for.
+type CustomAlgorithm interface {
+	// Search returns the cells of a path from the start of the problem to a Cell the problem accepts as its goal (see
+	// SearchProblem.IsGoal), including both. Consecutive cells have to be neighbors. If there is no path, it returns
+	// nil.
+	Search(problem *SearchProblem) []*Cell
+}
+
+// A SearchProblem describes a search for a CustomAlgorithm: its start, goal and the moves the PathSettings allow.
+type SearchProblem struct {
+	grid      *Grid
+	settings  *PathSettings
+	heuristic heuristic
+	observer  *searchObserver
+	expanded  int
+}
+
+// Grid returns the Grid searched on.
+func (p *SearchProblem) Grid() *Grid {
+	return p.grid
+}
+
+// Start returns the Cell the path starts on.
+func (p *SearchProblem) Start() *Cell {
+	return p.settings.start
+}
+
+// End returns the end Cell of the search. It's nil for searches for a Goal without an end Cell.
+func (p *SearchProblem) End() *Cell {
+	return p.settings.end
+}
+
+// IsGoal returns if the path may end on the Cell: the end Cell, a Cell the Goal of the settings accepts or one within
+// the range of StopWithinRange.
+func (p *SearchProblem) IsGoal(cell *Cell) bool {
+	return p.grid.isGoal(cell, p.settings)
+}
+
+// Successors returns the neighbors of the Cell the path can move to. Each call counts as an expanded Cell in the
+// SearchStats.
+func (p *SearchProblem) Successors(cell *Cell) []*Cell {
+
+	p.expanded++
+	if p.observer != nil && p.observer.expanded != nil {
+		p.observer.expanded(cell)
+	}
+
+	m := p.grid
+	settings := p.settings
+	successors := make([]*Cell, 0, 8)
+	for _, neighbor := range m.neighbors(cell, settings.diagonals) {
+		if settings.corridor != nil && !settings.corridor[m.cellIndex(neighbor)] {
+			continue
+		}
+		if m.canMove(cell, neighbor, settings) {
+			successors = append(successors, neighbor)
+		}
+	}
+	return successors
+}
+
+// MoveCost returns the cost of moving from one Cell to the neighboring Cell "to". It doesn't check if the move is
+// allowed, see Successors.
+func (p *SearchProblem) MoveCost(from, to *Cell) float64 {
+	return p.grid.moveCost(from, to, p.settings)
+}
+
+// StartCost returns the cost of the start Cell, which counts towards the cost of the path.
+func (p *SearchProblem) StartCost() float64 {
+	return p.grid.cellCost(p.settings.start, p.settings)
+}
+
+// Estimate returns the estimated remaining cost from the Cell to the end Cell like used by AStar. It never
+// overestimates the actual cost unless the Heuristic or the HeuristicWeight of the settings allow it. For searches
+// without an end Cell, it's 0.
+func (p *SearchProblem) Estimate(cell *Cell) float64 {
+	return p.heuristic.estimate(cell)
+}
+
+// firstCustomAlgorithm is the value of the first Algorithm registered with RegisterAlgorithm. It's far above the
+// built-in algorithms, so adding more of them doesn't change the values of registered ones.
+const firstCustomAlgorithm Algorithm = 1 << 16
+
+// algorithmRegistry contains the algorithms registered with RegisterAlgorithm.
+var algorithmRegistry = struct {
+	lock       sync.RWMutex
+	algorithms []CustomAlgorithm
+	names      []string
+}{}
+
+// RegisterAlgorithm registers a CustomAlgorithm under the name and returns the Algorithm to set in the PathSettings to
+// use it. Names are unique, including the names of the built-in algorithms (see Algorithm.String); otherwise an error
+// wrapping ErrAlgorithmExists is returned. Algorithms are registered for the lifetime of the program, usually during
+// the initialization.
+func RegisterAlgorithm(name string, algorithm CustomAlgorithm) (Algorithm, error) {
+
+	if _, exists := AlgorithmByName(name); exists {
+		return 0, fmt.Errorf("registering %q: %w", name, ErrAlgorithmExists)
+	}
+
+	registry := &algorithmRegistry
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	registry.algorithms = append(registry.algorithms, algorithm)
+	registry.names = append(registry.names, name)
+	return firstCustomAlgorithm + Algorithm(len(registry.algorithms)-1), nil
+}
+
+// AlgorithmByName returns the Algorithm with the name, e.g. for algorithms chosen in configuration files. The names of
+// the built-in algorithms are the ones returned by Algorithm.String. If there is none, false is returned.
+func AlgorithmByName(name string) (Algorithm, bool) {
+
+	for _, algorithm := range builtinAlgorithms {
+		if algorithm.String() == name {
+			return algorithm, true
+		}
+	}
+
+	registry := &algorithmRegistry
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	for i, registered := range registry.names {
+		if registered == name {
+			return firstCustomAlgorithm + Algorithm(i), true
+		}
+	}
+	return 0, false
+}
+
+// Algorithms returns all built-in and registered algorithms.
+func Algorithms() []Algorithm {
+
+	registry := &algorithmRegistry
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	algorithms := append([]Algorithm{}, builtinAlgorithms...)
+	for i := range registry.algorithms {
+		algorithms = append(algorithms, firstCustomAlgorithm+Algorithm(i))
+	}
+	return algorithms
+}
+
+// custom returns the CustomAlgorithm registered as the Algorithm and its name, or nil if it's no registered one.
+func (algorithm Algorithm) custom() (CustomAlgorithm, string) {
+
+	registry := &algorithmRegistry
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	index := int(algorithm - firstCustomAlgorithm)
+	if algorithm < firstCustomAlgorithm || index >= len(registry.algorithms) {
+		return nil, ""
+	}
+	return registry.algorithms[index], registry.names[index]
+}
+
+// customSearch runs the CustomAlgorithm and stores its path in the buffer like a search, so it can be finished like
+// the paths of the built-in algorithms. It returns the index of the last Cell of the path and its quantized cost, or
+// -1 if there is no path. Paths breaking the rules of the settings or visiting a Cell twice are treated as no path.
+func (m *Grid) customSearch(algorithm CustomAlgorithm, buffer *searchBuffer, settings *PathSettings, observer *searchObserver, stats *SearchStats) (int32, float64) {
+
+	problem := &SearchProblem{grid: m, settings: settings, heuristic: m.newHeuristic(settings), observer: observer}
+	cells := algorithm.Search(problem)
+	stats.Expanded = problem.expanded
+	if len(cells) == 0 || cells[0] != settings.start || !problem.IsGoal(cells[len(cells)-1]) {
+		return -1, math.Inf(1)
+	}
+
+	index := m.cellIndex(cells[0])
+	cost := settings.quantize(m.cellCost(cells[0], settings))
+	buffer.reach(index, cost, -1)
+	for i := 1; i < len(cells); i++ {
+		parent := index
+		index = m.cellIndex(cells[i])
+		dx, dy := abs(cells[i].X-cells[i-1].X), abs(cells[i].Y-cells[i-1].Y)
+		if buffer.reached[index] == buffer.generation || dx > 1 || dy > 1 || (dx == 1 && dy == 1 && !settings.diagonals) ||
+			!m.canMove(cells[i-1], cells[i], settings) {
+			return -1, math.Inf(1)
+		}
+		cost += settings.quantize(m.moveCost(cells[i-1], cells[i], settings))
+		buffer.reach(index, cost, parent)
+	}
+	return index, cost
+}
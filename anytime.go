@@ -0,0 +1,330 @@
+package paths
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// anytimeStepSize is the amount of cells an AnytimeSearch expands per Step.
+const anytimeStepSize = 256
+
+// An AnytimeSearch finds a path quickly and then keeps improving it as long as there is time left, e.g. for games
+// with a fixed pathfinding budget per frame (Anytime Repairing A*). The first path is found with an inflated estimate
+// of the remaining cost like with PathSettings.HeuristicWeight; each following round lowers the weight and repairs
+// the path found so far, reusing the work of the previous rounds, until the weight reaches 1 and the path is the
+// cheapest one.
+//
+// An AnytimeSearch is a Task, so it can be run by a Scheduler, or run directly with Run. Searches for a Goal or with
+// StopWithinRange aren't supported; the path always leads to the end Cell. Changes of the Grid during the search
+// aren't noticed. An AnytimeSearch must not be used by multiple goroutines at once.
+type AnytimeSearch struct {
+	// OnImprove is called each time a cheaper path has been found, with the path and the factor its cost exceeds the
+	// cost of the cheapest path at most. It's optional.
+	OnImprove func(path *Path, bound float64)
+
+	grid      *Grid
+	settings  PathSettings
+	heuristic heuristic
+	// weight is the current inflation of the estimate, which is lowered by weightStep after each round.
+	weight, weightStep float64
+	// costs and parents contain the quantized cost of the cheapest way found to each cell and the cell it comes from,
+	// indexed like the cells in a search.
+	costs   []float64
+	parents []int32
+	// closed marks the cells expanded in the current round, opened the cells waiting in open and inconsistent the
+	// closed cells, which have become cheaper since and have to be expanded again in the next round.
+	closed, opened, inconsistent []bool
+	open                         openList
+	reopen                       []int32
+	// path is the best path found so far, pathCost its quantized cost and bound its bound.
+	path            *Path
+	pathCost, bound float64
+	done            bool
+}
+
+// NewAnytimeSearch returns an AnytimeSearch for a path from the start to the end Cell of the settings. The first round
+// runs with the initialWeight (at least 1), each following one with a weight lowered by weightStep; a weightStep of 0
+// or less continues with a weight of 1 right after the first path. The HeuristicWeight of the settings is ignored. No
+// search is done until Step or Run is called.
+func (m *Grid) NewAnytimeSearch(settings PathSettings, initialWeight, weightStep float64) *AnytimeSearch {
+
+	settings.Goal, settings.StopWithinRange, settings.HeuristicWeight = nil, 0, 0
+	size := m.Width() * m.Height()
+	a := &AnytimeSearch{
+		grid:         m,
+		settings:     settings,
+		heuristic:    m.newHeuristic(&settings),
+		weight:       math.Max(1, initialWeight),
+		weightStep:   weightStep,
+		costs:        make([]float64, size),
+		parents:      make([]int32, size),
+		closed:       make([]bool, size),
+		opened:       make([]bool, size),
+		inconsistent: make([]bool, size),
+		bound:        math.Inf(1),
+	}
+	for i := range a.costs {
+		a.costs[i] = math.Inf(1)
+	}
+
+	if !settings.endpointsWalkable() {
+		a.done = true
+		return a
+	}
+
+	start := m.cellIndex(settings.start)
+	a.costs[start] = settings.quantize(m.cellCost(settings.start, &settings))
+	a.parents[start] = -1
+	a.push(start)
+
+	return a
+}
+
+// Step does a part of the current round and returns true, once the cheapest path has been found or it's clear there
+// is none.
+func (a *AnytimeSearch) Step() bool {
+
+	if a.done {
+		return true
+	}
+	if !a.improve(anytimeStepSize) {
+		return false
+	}
+
+	a.publish()
+	if a.weight <= 1 || len(a.open)+len(a.reopen) == 0 {
+		a.done = true
+		a.bound = math.Min(a.bound, 1)
+		return true
+	}
+
+	// the next round continues with a lower weight from the cells, whose costs may still improve
+	if a.weightStep > 0 {
+		a.weight = math.Max(1, a.weight-a.weightStep)
+	} else {
+		a.weight = 1
+	}
+	a.startRound()
+	return false
+}
+
+// Run steps the search until the cheapest path has been found or the budget has been used up and returns the best
+// path found so far, see Path.
+func (a *AnytimeSearch) Run(budget time.Duration) *Path {
+
+	began := time.Now()
+	for !a.Step() && time.Since(began) < budget {
+	}
+	return a.Path()
+}
+
+// Stream runs the search in its own goroutine and sends each cheaper path found on the returned channel, so an agent can
+// start moving on the first path and switch to better ones as they arrive. If the receiver falls behind, only the
+// latest path is kept for it. The channel is closed once the cheapest path has been found (an empty Path is sent
+// first, if there is none) or the context is canceled. The Grid must not be changed and the AnytimeSearch not be used
+// otherwise until the channel is closed.
+func (a *AnytimeSearch) Stream(ctx context.Context) <-chan *Path {
+
+	paths := make(chan *Path, 1)
+	send := func(path *Path) {
+		// a path, which hasn't been received yet, is replaced by the cheaper one
+		select {
+		case <-paths:
+		default:
+		}
+		paths <- path
+	}
+
+	go func() {
+
+		defer close(paths)
+		var sent *Path
+		for ctx.Err() == nil {
+			done := a.Step()
+			if a.path != sent && a.path != nil {
+				sent = a.path
+				send(copyPath(a.path))
+			}
+			if done {
+				if a.path == nil {
+					send(a.Path())
+				}
+				return
+			}
+		}
+	}()
+
+	return paths
+}
+
+// Path returns a copy of the best path found so far. If no path has been found yet, nil is returned; if the search is
+// Done without finding one, the Path is empty.
+func (a *AnytimeSearch) Path() *Path {
+
+	if a.path == nil {
+		if a.done {
+			return &Path{StepHeight: int(a.settings.MaxStepHeight)}
+		}
+		return nil
+	}
+	return copyPath(a.path)
+}
+
+// Cost returns the cost of the best path found so far like SearchStats.Cost, or +Inf if none has been found yet.
+func (a *AnytimeSearch) Cost() float64 {
+
+	if a.path == nil {
+		return math.Inf(1)
+	}
+	return a.settings.unquantize(a.pathCost)
+}
+
+// Bound returns the factor the cost of the best path found so far exceeds the cost of the cheapest path at most. It's
+// 1 once the search is Done and +Inf before the first path has been found.
+func (a *AnytimeSearch) Bound() float64 {
+	return a.bound
+}
+
+// Weight returns the weight of the estimate in the current round.
+func (a *AnytimeSearch) Weight() float64 {
+	return a.weight
+}
+
+// Done returns if the search has finished, so the Path is the cheapest one.
+func (a *AnytimeSearch) Done() bool {
+	return a.done
+}
+
+// key returns the priority of the cell with the index in the current round.
+func (a *AnytimeSearch) key(index int32) float64 {
+	return a.costs[index] + a.heuristic.estimate(a.grid.cellAt(index))*a.weight
+}
+
+// push adds the cell with the index to the open cells with its current key.
+func (a *AnytimeSearch) push(index int32) {
+	a.opened[index] = true
+	a.open.push(openItem{index, a.key(index)})
+}
+
+// improve expands up to limit cells of the current round and returns true, once the round is finished: no open cell
+// can lead to a cheaper path to the end Cell anymore.
+func (a *AnytimeSearch) improve(limit int) bool {
+
+	m := a.grid
+	settings := &a.settings
+	end := m.cellIndex(settings.end)
+
+	for expanded := 0; expanded < limit; {
+
+		// the list may contain outdated items of cells, which have been reached cheaper or expanded since
+		for len(a.open) > 0 && (!a.opened[a.open[0].cell] || a.open[0].cost != a.key(a.open[0].cell)) {
+			a.open.pop()
+		}
+		if len(a.open) == 0 || a.costs[end] <= a.open[0].cost {
+			return true
+		}
+
+		index := a.open.pop().cell
+		a.opened[index] = false
+		a.closed[index] = true
+		expanded++
+		cell := m.cellAt(index)
+
+		for _, offset := range neighborOffsets {
+
+			if !settings.diagonals && offset[0] != 0 && offset[1] != 0 {
+				continue
+			}
+			neighbor := m.Get(cell.X+offset[0], cell.Y+offset[1])
+			if neighbor == nil || !m.canMove(cell, neighbor, settings) {
+				continue
+			}
+
+			neighborIndex := m.cellIndex(neighbor)
+			cost := a.costs[index] + settings.quantize(m.moveCost(cell, neighbor, settings))
+			if cost >= a.costs[neighborIndex] {
+				continue
+			}
+			a.costs[neighborIndex] = cost
+			a.parents[neighborIndex] = index
+
+			// cells are expanded once per round; cells becoming cheaper afterwards wait for the next one
+			if !a.closed[neighborIndex] {
+				a.push(neighborIndex)
+			} else if !a.inconsistent[neighborIndex] {
+				a.inconsistent[neighborIndex] = true
+				a.reopen = append(a.reopen, neighborIndex)
+			}
+		}
+	}
+
+	return false
+}
+
+// startRound prepares the next round with the current weight: the open and the inconsistent cells are the open cells
+// of the new round.
+func (a *AnytimeSearch) startRound() {
+
+	indices := a.reopen
+	for _, item := range a.open {
+		if a.opened[item.cell] {
+			a.opened[item.cell] = false
+			indices = append(indices, item.cell)
+		}
+	}
+
+	a.open = a.open[:0]
+	a.reopen = nil
+	for i := range a.closed {
+		a.closed[i] = false
+		a.inconsistent[i] = false
+	}
+	for _, index := range indices {
+		a.push(index)
+	}
+}
+
+// publish stores the path to the end Cell, if it's cheaper than the one found before, and updates the bound.
+func (a *AnytimeSearch) publish() {
+
+	m := a.grid
+	settings := &a.settings
+	end := m.cellIndex(settings.end)
+	if math.IsInf(a.costs[end], 1) {
+		return
+	}
+
+	// no path can be cheaper than the lowest unweighted estimate of the cells, which may still improve
+	lowest := a.costs[end]
+	for _, item := range a.open {
+		if a.opened[item.cell] {
+			lowest = math.Min(lowest, a.costs[item.cell]+a.heuristic.estimate(m.cellAt(item.cell)))
+		}
+	}
+	for _, index := range a.reopen {
+		lowest = math.Min(lowest, a.costs[index]+a.heuristic.estimate(m.cellAt(index)))
+	}
+	bound := a.weight
+	if lowest > 0 {
+		bound = math.Min(bound, a.costs[end]/lowest)
+	}
+
+	// the bound of an older, more expensive path still holds
+	a.bound = math.Min(a.bound, bound)
+	if a.path != nil && a.costs[end] >= a.pathCost {
+		return
+	}
+
+	path := &Path{StepHeight: int(settings.MaxStepHeight)}
+	for i := end; i >= 0; i = a.parents[i] {
+		path.Cells = append(path.Cells, m.cellAt(i))
+	}
+	reverse(path.Cells)
+	m.finishCells(path, settings)
+	a.path, a.pathCost = path, a.costs[end]
+
+	if a.OnImprove != nil {
+		a.OnImprove(copyPath(path), a.bound)
+	}
+}
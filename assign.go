@@ -0,0 +1,125 @@
+package paths
+
+import "math"
+
+// AssignGoals assigns each agent a distinct goal, so the total path cost of all agents is as low as possible, e.g. to
+// send workers to jobs or units to positions. The path costs are computed with one search per agent or per goal,
+// whichever are fewer, the assignment is solved with the Hungarian algorithm. The movement rules are taken from the
+// settings; their start and end are ignored.
+// The returned slice contains the index of the goal of each agent, or -1, if the agent didn't get a goal, because there
+// are more agents than goals or it can't reach any of the remaining goals. The returned cost is the total path cost of
+// all assigned agents.
+func (m *Grid) AssignGoals(agents, goals []*Cell, settings PathSettings) (assignment []int, cost float64) {
+
+	costs := m.costMatrix(agents, goals, &settings)
+
+	// unreachable goals get a cost higher than any possible assignment, so they are only chosen if there is no other
+	// choice; they are removed from the result afterwards
+	unreachable := 1.0
+	for _, row := range costs {
+		for _, c := range row {
+			if !math.IsInf(c, 1) {
+				unreachable += c
+			}
+		}
+	}
+
+	// the matrix is made square by adding dummy agents or goals, which cost nothing
+	size := len(agents)
+	if len(goals) > size {
+		size = len(goals)
+	}
+	matrix := make([][]float64, size)
+	for a := range matrix {
+		matrix[a] = make([]float64, size)
+		for g := range matrix[a] {
+			if a < len(agents) && g < len(goals) {
+				matrix[a][g] = math.Min(costs[a][g], unreachable)
+			}
+		}
+	}
+
+	assignment = make([]int, len(agents))
+	for a, g := range hungarian(matrix) {
+		if a >= len(agents) {
+			break
+		}
+		if g >= len(goals) || math.IsInf(costs[a][g], 1) {
+			assignment[a] = -1
+			continue
+		}
+		assignment[a] = g
+		cost += costs[a][g]
+	}
+
+	return assignment, cost
+}
+
+// hungarian solves the assignment problem for the square cost matrix and returns the column assigned to each row.
+func hungarian(costs [][]float64) []int {
+
+	n := len(costs)
+
+	// potentials of the rows (u) and columns (v); the row assigned to each column (p) and the previous column on the
+	// augmenting path (way). Index 0 is a virtual column, the others are shifted by one.
+	u := make([]float64, n+1)
+	v := make([]float64, n+1)
+	p := make([]int, n+1)
+	way := make([]int, n+1)
+
+	for row := 1; row <= n; row++ {
+
+		p[0] = row
+		column := 0
+		minima := make([]float64, n+1)
+		used := make([]bool, n+1)
+		for i := range minima {
+			minima[i] = math.Inf(1)
+		}
+
+		for p[column] != 0 {
+
+			used[column] = true
+			current := p[column]
+			delta := math.Inf(1)
+			next := 0
+
+			for j := 1; j <= n; j++ {
+				if used[j] {
+					continue
+				}
+				if reduced := costs[current-1][j-1] - u[current] - v[j]; reduced < minima[j] {
+					minima[j] = reduced
+					way[j] = column
+				}
+				if minima[j] < delta {
+					delta = minima[j]
+					next = j
+				}
+			}
+
+			for j := 0; j <= n; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minima[j] -= delta
+				}
+			}
+			column = next
+		}
+
+		// the augmenting path is applied
+		for column != 0 {
+			previous := way[column]
+			p[column] = p[previous]
+			column = previous
+		}
+	}
+
+	assignment := make([]int, n)
+	for j := 1; j <= n; j++ {
+		assignment[p[j]-1] = j - 1
+	}
+	return assignment
+}
@@ -0,0 +1,140 @@
+package paths
+
+// A TileRule derives the Rune of cells from their neighbors, see Grid.AutoTile.
+type TileRule struct {
+	// Applies decides, if the rule is used for the Cell, e.g. only for walls. nil applies to all cells.
+	Applies func(cell *Cell) bool
+	// Connects decides, if the Cell is connected to its neighbor, e.g. if both are walls. nil connects cells with the
+	// same Rune.
+	Connects func(cell, neighbor *Cell) bool
+	// Runes contains the rune for each combination of cardinal directions the Cell is connected in, e.g. North|South
+	// for a vertical wall. Combinations which aren't contained get the Default rune.
+	Runes map[Direction]rune
+	// Default is the rune for combinations missing in Runes. If it's 0, the rule doesn't match these cells, so the
+	// next rule is tried.
+	Default rune
+}
+
+// AutoTile recomputes the Rune of each Cell from its neighbors, so edited grids can be rendered sensibly again, e.g.
+// with VisualisePath. For each Cell, the first matching rule is used; cells no rule matches keep their Rune. The
+// connections are checked before any rune is changed. The amount of changed cells is returned.
+func (m *Grid) AutoTile(rules ...TileRule) int {
+
+	runes := make(map[*Cell]rune)
+
+	for _, cell := range m.AllCells() {
+		for _, rule := range rules {
+			if r, matches := m.tileRune(cell, rule); matches {
+				runes[cell] = r
+				break
+			}
+		}
+	}
+
+	changed := 0
+	for cell, r := range runes {
+		if m.pending(cell).Rune != r {
+			m.Stage(cell).Rune = r
+			changed++
+		}
+	}
+	if changed > 0 {
+		m.markCellsChanged()
+	}
+
+	return changed
+}
+
+// tileRune returns the rune the rule assigns to the Cell, and if the rule matches it at all.
+func (m *Grid) tileRune(cell *Cell, rule TileRule) (rune, bool) {
+
+	if rule.Applies != nil && !rule.Applies(cell) {
+		return 0, false
+	}
+
+	connects := rule.Connects
+	if connects == nil {
+		connects = func(cell, neighbor *Cell) bool {
+			return m.pending(cell).Rune == m.pending(neighbor).Rune
+		}
+	}
+
+	connections := Direction(0)
+	for _, direction := range []Direction{North, East, South, West} {
+		dx, dy := direction.Offset()
+		if neighbor := m.Get(cell.X+dx, cell.Y+dy); neighbor != nil && connects(cell, neighbor) {
+			connections |= direction
+		}
+	}
+
+	if r, exists := rule.Runes[connections]; exists {
+		return r, true
+	}
+	return rule.Default, rule.Default != 0
+}
+
+// WallTiles returns a TileRule drawing unwalkable cells as connected walls with box-drawing characters.
+func WallTiles() TileRule {
+	return TileRule{
+		Applies: func(cell *Cell) bool {
+			return !cell.Walkable
+		},
+		Connects: func(cell, neighbor *Cell) bool {
+			return !neighbor.Walkable
+		},
+		Runes: map[Direction]rune{
+			0:                           '■',
+			North:                       '│',
+			South:                       '│',
+			North | South:               '│',
+			East:                        '─',
+			West:                        '─',
+			East | West:                 '─',
+			North | East:                '└',
+			North | West:                '┘',
+			South | East:                '┌',
+			South | West:                '┐',
+			North | East | South:        '├',
+			North | West | South:        '┤',
+			East | South | West:         '┬',
+			East | North | West:         '┴',
+			North | East | South | West: '┼',
+		},
+	}
+}
+
+// CliffTiles returns a TileRule drawing walkable cells with the cliff rune, if at least one of their walkable neighbors
+// is further than maxStepHeight below them.
+func CliffTiles(maxStepHeight float64, cliff rune) TileRule {
+	return heightEdgeTiles(cliff, func(cell, neighbor *Cell) bool {
+		return neighbor.Walkable && cell.TotalHeight()-neighbor.TotalHeight() > maxStepHeight
+	})
+}
+
+// RampTiles returns a TileRule drawing walkable cells with the ramp rune, if at least one of their walkable neighbors
+// is higher, but not further than maxStepHeight above them.
+func RampTiles(maxStepHeight float64, ramp rune) TileRule {
+	return heightEdgeTiles(ramp, func(cell, neighbor *Cell) bool {
+		difference := neighbor.TotalHeight() - cell.TotalHeight()
+		return neighbor.Walkable && difference > 0 && difference <= maxStepHeight
+	})
+}
+
+// heightEdgeTiles returns a TileRule drawing walkable cells connected to at least one neighbor with the rune.
+func heightEdgeTiles(r rune, connects func(cell, neighbor *Cell) bool) TileRule {
+
+	runes := make(map[Direction]rune)
+	for connections := Direction(1); connections <= CardinalDirections; connections++ {
+		if connections&CardinalDirections == connections {
+			runes[connections] = r
+		}
+	}
+
+	return TileRule{
+		Applies: func(cell *Cell) bool {
+			return cell.Walkable
+		},
+		Connects: connects,
+		Runes:    runes,
+	}
+}
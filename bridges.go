@@ -0,0 +1,164 @@
+package paths
+
+import (
+	"math"
+	"sort"
+)
+
+// A Bridge is a straight span of unwalkable cells proposed by Grid.SuggestBridges.
+type Bridge struct {
+	// From and To are the walkable cells the Bridge connects, Cells the unwalkable cells in between, from From to To.
+	From, To *Cell
+	Cells    []*Cell
+	// Joined is the amount of pairs of points, which can't reach each other without the Bridge, but can with it.
+	// JoinedCost is the sum of the costs of their paths.
+	Joined     int
+	JoinedCost float64
+	// Saving is the sum of the cost reductions of the paths between the pairs of points, which are connected already.
+	Saving float64
+}
+
+// SuggestBridges proposes bridges across unwalkable gaps, e.g. over rivers and chasms in generated maps or as hints for
+// level designers. Each Bridge spans up to maxLength unwalkable cells in a straight line along one of the eight
+// directions (only the straight ones without diagonal movement); crossing it costs bridgeCost per Cell of the Bridge,
+// regardless of the heights. The bridges are rated by the paths between the points they improve: the ones joining the
+// most pairs of points come first, then the ones saving the highest cost, then the ones giving the joined pairs the
+// cheapest paths, then the shorter ones. Without points, one Cell of each region of cells reachable from each other is
+// used, so the bridges joining regions come first. Bridges, which don't improve any path, aren't returned. The movement
+// rules are taken from the settings; their start and end are ignored.
+func (m *Grid) SuggestBridges(points []*Cell, maxLength int, bridgeCost float64, settings PathSettings) []Bridge {
+
+	costsFrom := make([]map[*Cell]float64, len(points))
+	for i, point := range points {
+		costsFrom[i] = m.costsFromStart(point, &settings)
+	}
+	if len(points) == 0 {
+		points, costsFrom = m.bridgeRegions(&settings)
+	}
+	costsTo := make([]map[*Cell]float64, len(points))
+	for i, point := range points {
+		costsTo[i] = m.costsToGoals([]*Cell{point}, &settings)
+	}
+
+	bridges := []Bridge{}
+	for _, bridge := range m.bridgeCandidates(maxLength, &settings) {
+
+		// the Bridge can be crossed in both directions, each move costing like one onto a Cell with the bridgeCost
+		crossing := 0.0
+		previous := bridge.From
+		for _, cell := range bridge.Cells {
+			crossing += moveCostBelow(previous, cell, bridgeCost, cell.Clearance, &settings)
+			previous = cell
+		}
+		crossing += moveCostBelow(previous, bridge.To, m.cellCost(bridge.To, &settings)+transitionCost(previous, bridge.To),
+			bridge.To.Clearance, &settings)
+
+		for i := range points {
+			for j := range points {
+				if i == j {
+					continue
+				}
+				before, connected := costsFrom[i][points[j]]
+				after := math.Min(
+					bridgeRoute(costsFrom[i], costsTo[j], bridge.From, bridge.To, crossing),
+					bridgeRoute(costsFrom[i], costsTo[j], bridge.To, bridge.From, crossing),
+				)
+				switch {
+				case !connected && !math.IsInf(after, 1):
+					bridge.Joined++
+					bridge.JoinedCost += after
+				case connected && after < before:
+					bridge.Saving += before - after
+				}
+			}
+		}
+
+		if bridge.Joined > 0 || bridge.Saving > 0 {
+			bridges = append(bridges, bridge)
+		}
+	}
+
+	sort.SliceStable(bridges, func(i, j int) bool {
+		a, b := bridges[i], bridges[j]
+		if a.Joined != b.Joined {
+			return a.Joined > b.Joined
+		}
+		if a.Saving != b.Saving {
+			return a.Saving > b.Saving
+		}
+		if a.JoinedCost != b.JoinedCost {
+			return a.JoinedCost < b.JoinedCost
+		}
+		return len(a.Cells) < len(b.Cells)
+	})
+	return bridges
+}
+
+// bridgeRoute returns the cost of the path from the point of costsFrom to the point of costsTo across the Bridge
+// entered at "from" and left at "to", or +Inf if there is none.
+func bridgeRoute(costsFrom, costsTo map[*Cell]float64, from, to *Cell, crossing float64) float64 {
+
+	toBridge, reachable := costsFrom[from]
+	fromBridge, reaches := costsTo[to]
+	if !reachable || !reaches {
+		return math.Inf(1)
+	}
+	return toBridge + crossing + fromBridge
+}
+
+// bridgeRegions returns one Cell of each region of cells reachable from it together with the costs from these cells,
+// see SuggestBridges.
+func (m *Grid) bridgeRegions(settings *PathSettings) ([]*Cell, []map[*Cell]float64) {
+
+	regions := []*Cell{}
+	costs := []map[*Cell]float64{}
+	assigned := make(map[*Cell]bool)
+	for _, cell := range m.AllCells() {
+		if !cell.Walkable || assigned[cell] {
+			continue
+		}
+		reachable := m.costsFromStart(cell, settings)
+		for other := range reachable {
+			assigned[other] = true
+		}
+		regions = append(regions, cell)
+		costs = append(costs, reachable)
+	}
+
+	return regions, costs
+}
+
+// bridgeCandidates returns all straight spans of up to maxLength unwalkable cells between two walkable cells.
+func (m *Grid) bridgeCandidates(maxLength int, settings *PathSettings) []Bridge {
+
+	// each span is found from one of its ends only
+	directions := [][2]int{{1, 0}, {0, 1}}
+	if settings.diagonals {
+		directions = append(directions, [2]int{1, 1}, [2]int{-1, 1})
+	}
+
+	candidates := []Bridge{}
+	for _, cell := range m.AllCells() {
+		if !cell.Walkable {
+			continue
+		}
+		for _, direction := range directions {
+			span := []*Cell{}
+			for length := 1; length <= maxLength+1; length++ {
+				next := m.Get(cell.X+direction[0]*length, cell.Y+direction[1]*length)
+				if next == nil {
+					break
+				}
+				if next.Walkable {
+					if len(span) > 0 {
+						candidates = append(candidates, Bridge{From: cell, To: next, Cells: span})
+					}
+					break
+				}
+				span = append(span, next)
+			}
+		}
+	}
+
+	return candidates
+}
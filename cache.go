@@ -0,0 +1,212 @@
+package paths
+
+import "sync"
+
+// A PathCache stores found paths, so repeated requests between the same cells don't need to search again, e.g. for
+// crowds heading to the same area. Besides these exact hits, the corridor of a cached path can be reused for requests
+// between nearby cells (see ReuseDistance): the new search is restricted to the cells around the cached path, which is
+// much faster than searching the whole Grid while still returning a valid path from the actual start to the actual
+// end. Cached paths are only reused for the same Grid, revision and settings (apart from start and end), so changes of
+// the Grid never return outdated paths. The least recently used paths are dropped once the capacity is reached. A
+// PathCache can be used by multiple goroutines.
+type PathCache struct {
+	// ReuseDistance is the maximum distance (measured like with Chebyshev) between the starts and between the ends of
+	// a cached path and a request, for the corridor of the cached path to be reused. Zero only allows exact hits. The
+	// corridor isn't reused for searches with a Goal or with RequireOptimal set, as the restricted search may miss a
+	// cheaper path.
+	ReuseDistance int
+	// CorridorWidth is the amount of cells the corridor extends to each side of the cached path. Wider corridors find
+	// cheaper paths, narrower ones are faster.
+	CorridorWidth int
+
+	lock     sync.Mutex
+	capacity int
+	// entries contains the cached paths, the least recently used one first.
+	entries []*cachedPath
+	stats   CacheStats
+}
+
+// CacheStats contains statistics about the requests of a PathCache.
+type CacheStats struct {
+	// Hits is the amount of requests answered with a cached path.
+	Hits int
+	// CorridorHits is the amount of requests answered by a search in the corridor of a cached path.
+	CorridorHits int
+	// Misses is the amount of requests, which needed a search of the whole Grid.
+	Misses int
+}
+
+// cachedPath is a path stored in a PathCache.
+type cachedPath struct {
+	key        string
+	start, end *Cell
+	path       *Path
+}
+
+// NewPathCache returns an empty PathCache holding up to capacity paths.
+func NewPathCache(capacity int) *PathCache {
+	return &PathCache{capacity: capacity}
+}
+
+// GetPath works like Grid.GetPathFromSettings, but answers the request from the cache if possible and caches the
+// found path. Partial paths and paths to a Goal aren't cached. Each call returns its own copy of the Path, so agents
+// sharing a cached path can walk it independently.
+func (c *PathCache) GetPath(grid *Grid, settings PathSettings) *Path {
+
+	keySettings := settings
+	keySettings.start, keySettings.end = nil, nil
+	key := requestKey(grid, &keySettings)
+
+	// requests with a Goal have no key, as functions can't be compared, so they are neither answered from nor stored
+	// in the cache
+	c.lock.Lock()
+	if entry := c.exact(key, settings.start, settings.end); entry != nil {
+		c.stats.Hits++
+		path := copyPath(entry.path)
+		c.lock.Unlock()
+		return path
+	}
+	var nearby *cachedPath
+	if settings.Goal == nil && !settings.RequireOptimal && settings.start != nil && settings.end != nil {
+		nearby = c.nearest(key, settings.start, settings.end)
+	}
+	var corridor []bool
+	if nearby != nil {
+		corridor = c.corridor(grid, nearby.path, settings.start, settings.end)
+	}
+	c.lock.Unlock()
+
+	// the search in the corridor may fail, e.g. if the new endpoints are separated from the cached path by walls, so
+	// the whole Grid is searched then
+	var path *Path
+	if corridor != nil {
+		corridorSettings := settings
+		corridorSettings.corridor = corridor
+		path = grid.findPath(&corridorSettings, nil)
+		if path != nil && (len(path.Cells) == 0 || path.Partial) {
+			path = nil
+		}
+	}
+	corridorHit := path != nil
+	if path == nil {
+		path = grid.findPath(&settings, nil)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if corridorHit {
+		c.stats.CorridorHits++
+	} else {
+		c.stats.Misses++
+	}
+	if key != "" && path != nil && len(path.Cells) > 0 && !path.Partial && c.capacity > 0 {
+		if len(c.entries) >= c.capacity {
+			c.entries = c.entries[1:]
+		}
+		c.entries = append(c.entries, &cachedPath{key: key, start: settings.start, end: settings.end, path: path})
+	}
+
+	return copyPath(path)
+}
+
+// exact returns the cached path with the key, start and end and marks it as recently used, or nil.
+func (c *PathCache) exact(key string, start, end *Cell) *cachedPath {
+
+	if key == "" {
+		return nil
+	}
+
+	for i, entry := range c.entries {
+		if entry.key == key && entry.start == start && entry.end == end {
+			c.entries = append(append(c.entries[:i], c.entries[i+1:]...), entry)
+			return entry
+		}
+	}
+	return nil
+}
+
+// nearest returns the cached path with the key, whose start and end are within the ReuseDistance and closest to the
+// passed ones, or nil.
+func (c *PathCache) nearest(key string, start, end *Cell) *cachedPath {
+
+	if c.ReuseDistance <= 0 {
+		return nil
+	}
+
+	var nearest *cachedPath
+	nearestDistance := 0
+	for _, entry := range c.entries {
+		if entry.key != key {
+			continue
+		}
+		startDistance := maxInt(abs(entry.start.X-start.X), abs(entry.start.Y-start.Y))
+		endDistance := maxInt(abs(entry.end.X-end.X), abs(entry.end.Y-end.Y))
+		if startDistance > c.ReuseDistance || endDistance > c.ReuseDistance {
+			continue
+		}
+		if nearest == nil || startDistance+endDistance < nearestDistance {
+			nearest, nearestDistance = entry, startDistance+endDistance
+		}
+	}
+
+	return nearest
+}
+
+// corridor returns the cells within the CorridorWidth of the Path and within the ReuseDistance of the new start and
+// end, indexed like the cells of the Grid.
+func (c *PathCache) corridor(grid *Grid, path *Path, start, end *Cell) []bool {
+
+	corridor := make([]bool, grid.Width()*grid.Height())
+	mark := func(x, y, radius int) {
+		for dy := -radius; dy <= radius; dy++ {
+			for dx := -radius; dx <= radius; dx++ {
+				if cell := grid.Get(x+dx, y+dy); cell != nil {
+					corridor[grid.cellIndex(cell)] = true
+				}
+			}
+		}
+	}
+
+	// paths with only their waypoints (see PathSettings.WaypointsOnly) move in straight lines between their cells,
+	// which are followed one step at a time
+	for i, cell := range path.Cells {
+		x, y := cell.X, cell.Y
+		mark(x, y, c.CorridorWidth)
+		if i+1 == len(path.Cells) {
+			break
+		}
+		next := path.Cells[i+1]
+		for x != next.X || y != next.Y {
+			x, y = x+sign(next.X-x), y+sign(next.Y-y)
+			mark(x, y, c.CorridorWidth)
+		}
+	}
+
+	mark(start.X, start.Y, c.ReuseDistance)
+	mark(end.X, end.Y, c.ReuseDistance)
+
+	return corridor
+}
+
+// Stats returns statistics about the requests so far.
+func (c *PathCache) Stats() CacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.stats
+}
+
+// Len returns the amount of cached paths.
+func (c *PathCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.entries)
+}
+
+// Clear removes all cached paths and resets the statistics.
+func (c *PathCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = nil
+	c.stats = CacheStats{}
+}
@@ -0,0 +1,128 @@
+package paths
+
+import "sort"
+
+// RuneRange returns all runes from first to last (both inclusive). It's meant to be used with Grid.Categorize, e.g.
+// for tilesets using a whole block of characters for one kind of terrain.
+func RuneRange(first, last rune) []rune {
+
+	runes := []rune{}
+	for r := first; r <= last; r++ {
+		runes = append(runes, r)
+	}
+
+	return runes
+}
+
+// Categorize adds named categories of runes to the Grid, e.g. "forest" for all the runes used by forest tiles. Cells
+// belong to all categories containing their rune. Categories can then be used instead of single runes, see
+// CellsByCategory, SetCategoryWalkable, SetCategoryCost and PathSettings.BlockedCategories. Existing categories with
+// the same names are replaced.
+func (m *Grid) Categorize(categories map[string][]rune) {
+
+	if m.categories == nil {
+		m.categories = make(map[string]map[rune]bool)
+	}
+
+	for name, runes := range categories {
+		m.categories[name] = make(map[rune]bool, len(runes))
+		for _, r := range runes {
+			m.categories[name][r] = true
+		}
+	}
+
+	m.MarkChanged()
+
+}
+
+// Categories returns the names of all categories of the Grid in alphabetical order.
+func (m *Grid) Categories() []string {
+
+	names := make([]string, 0, len(m.categories))
+	for name := range m.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// CategoryRunes returns the runes of the category in ascending order, or nil if there is no such category.
+func (m *Grid) CategoryRunes(category string) []rune {
+
+	set, exists := m.categories[category]
+	if !exists {
+		return nil
+	}
+
+	runes := make([]rune, 0, len(set))
+	for r := range set {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	return runes
+}
+
+// InCategory returns if the Cell belongs to the category.
+func (m *Grid) InCategory(cell *Cell, category string) bool {
+	return m.categories[category][cell.Rune]
+}
+
+// CategoriesOf returns the names of all categories the Cell belongs to in alphabetical order.
+func (m *Grid) CategoriesOf(cell *Cell) []string {
+
+	names := []string{}
+	for _, name := range m.Categories() {
+		if m.InCategory(cell, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// CellsByCategory returns a slice of pointers to Cells that all belong to the category.
+func (m *Grid) CellsByCategory(category string) []*Cell {
+
+	cells := make([]*Cell, 0)
+
+	for _, cell := range m.AllCells() {
+		if m.InCategory(cell, category) {
+			cells = append(cells, cell)
+		}
+	}
+
+	return cells
+}
+
+// SetCategoryWalkable sets walkability across all cells in the Grid belonging to the category.
+func (m *Grid) SetCategoryWalkable(category string, walkable bool) {
+	for _, r := range m.CategoryRunes(category) {
+		m.SetWalkable(r, walkable)
+	}
+}
+
+// SetCategoryHeightLevel sets the height level for all cells in the Grid belonging to the category.
+func (m *Grid) SetCategoryHeightLevel(category string, heightLevel int) {
+	for _, r := range m.CategoryRunes(category) {
+		m.SetHeightLevel(r, heightLevel)
+	}
+}
+
+// SetCategoryCost sets the movement cost across all cells in the Grid belonging to the category.
+func (m *Grid) SetCategoryCost(category string, cost float64) {
+	for _, r := range m.CategoryRunes(category) {
+		m.SetCost(r, cost)
+	}
+}
+
+// blockedByCategory returns if the Cell belongs to one of the categories blocked by the settings.
+func (m *Grid) blockedByCategory(cell *Cell, settings *PathSettings) bool {
+	for _, category := range settings.BlockedCategories {
+		if m.categories[category][m.runeOf(cell, settings)] {
+			return true
+		}
+	}
+	return false
+}
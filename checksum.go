@@ -0,0 +1,108 @@
+package paths
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+)
+
+// Checksum returns a hash of the state of the Grid: its size, all attributes of its cells, the sea level, the edge
+// costs, the categories, the rune layers and the cost layers. It's the same on all platforms, so networked peers can cheaply
+// verify, that their maps are in sync before trusting locally computed paths. Staged changes (see SetStaging) aren't
+// included.
+func (m *Grid) Checksum() uint64 {
+
+	h := checksum{hash: fnv.New64a()}
+
+	h.int(int64(m.Width()))
+	h.int(int64(m.Height()))
+
+	for _, cell := range m.AllCells() {
+		h.int(int64(cell.HeightLevel))
+		h.float(cell.Elevation)
+		h.float(cell.Cost)
+		h.bool(cell.Walkable)
+		h.int(int64(cell.Rune))
+		h.int(int64(cell.Occupancy))
+		h.float(cell.Clearance)
+		h.float(cell.CostVariance)
+		h.float(cell.BlockProbability)
+		h.bool(cell.Unknown)
+		h.float(cell.EnterCost)
+		h.float(cell.ExitCost)
+	}
+
+	h.float(m.seaLevel)
+
+	for _, key := range m.sortedEdges() {
+		h.int(int64(key.from))
+		h.int(int64(key.to))
+		h.float(m.edgeCosts[key])
+	}
+
+	for _, category := range m.Categories() {
+		h.string(category)
+		for _, r := range m.CategoryRunes(category) {
+			h.int(int64(r))
+		}
+	}
+
+	for _, name := range m.RuneLayers() {
+		h.string(name)
+		for _, row := range m.runeLayers[name].runes {
+			for _, r := range row {
+				h.int(int64(r))
+			}
+		}
+	}
+
+	for _, name := range m.CostLayers() {
+		h.string(name)
+		layer := m.costLayers[name]
+		for _, cell := range m.AllCells() {
+			if modifier, exists := layer.modifiers[cell]; exists {
+				h.int(int64(cell.X))
+				h.int(int64(cell.Y))
+				h.float(modifier.add)
+				h.float(modifier.factor)
+			}
+		}
+	}
+
+	return h.hash.Sum64()
+}
+
+// checksum writes values into a hash in a platform independent encoding.
+type checksum struct {
+	hash   hash.Hash64
+	buffer [8]byte
+}
+
+func (c *checksum) int(value int64) {
+	binary.LittleEndian.PutUint64(c.buffer[:], uint64(value))
+	c.hash.Write(c.buffer[:])
+}
+
+func (c *checksum) float(value float64) {
+	if value == 0 {
+		// -0 and 0 are equal, but have different bits
+		value = 0
+	}
+	binary.LittleEndian.PutUint64(c.buffer[:], math.Float64bits(value))
+	c.hash.Write(c.buffer[:])
+}
+
+func (c *checksum) bool(value bool) {
+	if value {
+		c.int(1)
+	} else {
+		c.int(0)
+	}
+}
+
+func (c *checksum) string(value string) {
+	// the length separates consecutive strings
+	c.int(int64(len(value)))
+	c.hash.Write([]byte(value))
+}
@@ -0,0 +1,254 @@
+package paths
+
+import "sync"
+
+// A reservation is a Cell (by its index) at a timestep, see CooperativePlanner.
+type reservation struct {
+	cell int32
+	time int
+}
+
+// A reservedMove is a move from one Cell to another (by their indices), which starts at a timestep.
+type reservedMove struct {
+	from, to int32
+	time     int
+}
+
+// A timedState is a Cell at a timestep reached by the search of a CooperativePlanner, with its quantized cost and the
+// index of the state it has been reached from.
+type timedState struct {
+	cell   int32
+	time   int
+	cost   float64
+	parent int32
+}
+
+// A CooperativePlanner plans the paths of multiple units, which must not run into each other (Windowed Hierarchical
+// Cooperative A*). Each planned path reserves the cells it occupies at each timestep within the window in a shared
+// reservation table, and the paths planned afterwards avoid them: they wait or take a detour instead of moving through
+// a reserved Cell or swapping places with a unit moving the other way. Beyond the window, the reservations are ignored,
+// so units should plan again before they reach its end, e.g. every Window/2 timesteps.
+//
+// The paths advance one Cell per timestep. Waiting on a Cell for a timestep costs like moving onto it. Each Cell can be
+// reserved by as many units per timestep as the MaxOccupancy of the settings allows, or by one unit if it's
+// unlimited; the Occupancy of the cells is respected like in all searches. A CooperativePlanner can be used by
+// multiple goroutines.
+type CooperativePlanner struct {
+	grid   *Grid
+	window int
+	lock   sync.Mutex
+	// cells counts the units reserving each Cell at each timestep, moves contains the reserved moves and units the
+	// reservations of each unit, so they can be released.
+	cells map[reservation]int
+	moves map[reservedMove]int
+	units map[int][]reservation
+}
+
+// NewCooperativePlanner returns a CooperativePlanner, which reserves the paths for window timesteps (at least 1).
+func (m *Grid) NewCooperativePlanner(window int) *CooperativePlanner {
+
+	if window < 1 {
+		window = 1
+	}
+	return &CooperativePlanner{
+		grid:   m,
+		window: window,
+		cells:  make(map[reservation]int),
+		moves:  make(map[reservedMove]int),
+		units:  make(map[int][]reservation),
+	}
+}
+
+// PlanPath plans the path of the unit from the start to the end Cell (or Goal) of the settings, starting at the
+// timestep startTime, and reserves it, replacing the earlier reservations of the unit. The Cell at index i of the Path
+// is occupied at the timestep startTime+i, so waiting units repeat their Cell. The rest of the path beyond the window
+// follows the cheapest path ignoring the reservations. WaypointsOnly is ignored. If the start isn't walkable or no
+// path can be found, nil is returned and the unit keeps no reservations.
+func (c *CooperativePlanner) PlanPath(unit int, settings PathSettings, startTime int) *Path {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.release(unit)
+
+	m := c.grid
+	settings.WaypointsOnly = false
+	if !settings.endpointsWalkable() {
+		return nil
+	}
+
+	cells := c.search(&settings, startTime)
+	if cells == nil {
+		return nil
+	}
+
+	// the window ended before the goal, so the rest of the path ignores the reservations
+	last := cells[len(cells)-1]
+	if !m.isGoal(last, &settings) {
+		rest := settings
+		rest.start = last
+		restPath := m.findPath(&rest, nil)
+		if restPath == nil || len(restPath.Cells) == 0 || restPath.Partial {
+			return nil
+		}
+		cells = append(cells, restPath.Cells[1:]...)
+	}
+
+	c.reserve(unit, cells, startTime)
+
+	path := &Path{Cells: cells, StepHeight: int(settings.MaxStepHeight)}
+	m.finishCells(path, &settings)
+	return path
+}
+
+// Release removes all reservations of the unit, e.g. once it has arrived or has been removed from the game.
+func (c *CooperativePlanner) Release(unit int) {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.release(unit)
+}
+
+// Reserved returns the amount of units reserving the Cell at the timestep.
+func (c *CooperativePlanner) Reserved(cell *Cell, time int) int {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.cells[reservation{c.grid.cellIndex(cell), time}]
+}
+
+// release removes all reservations of the unit.
+func (c *CooperativePlanner) release(unit int) {
+
+	reservations := c.units[unit]
+	for i, r := range reservations {
+		if c.cells[r]--; c.cells[r] <= 0 {
+			delete(c.cells, r)
+		}
+		if i > 0 {
+			move := reservedMove{reservations[i-1].cell, r.cell, r.time - 1}
+			if c.moves[move]--; c.moves[move] <= 0 {
+				delete(c.moves, move)
+			}
+		}
+	}
+	delete(c.units, unit)
+}
+
+// reserve reserves the cells of the path within the window for the unit. A unit arriving within the window keeps its
+// last Cell reserved until the end of the window.
+func (c *CooperativePlanner) reserve(unit int, cells []*Cell, startTime int) {
+
+	reservations := make([]reservation, 0, c.window+1)
+	for i := 0; i <= c.window; i++ {
+		cell := cells[minInt(i, len(cells)-1)]
+		r := reservation{c.grid.cellIndex(cell), startTime + i}
+		c.cells[r]++
+		if i > 0 {
+			c.moves[reservedMove{reservations[i-1].cell, r.cell, r.time - 1}]++
+		}
+		reservations = append(reservations, r)
+	}
+	c.units[unit] = reservations
+}
+
+// reservationCapacity returns the amount of units, which can reserve a Cell at the same timestep with the settings.
+func reservationCapacity(settings *PathSettings) int {
+	if settings.MaxOccupancy > 0 {
+		return settings.MaxOccupancy
+	}
+	return 1
+}
+
+// free returns if the unit can occupy the Cell with the index at the timestep. With a MaxOccupancy, the agents already
+// standing on the Cell (its Occupancy) take up the capacity as well.
+func (c *CooperativePlanner) free(index int32, time int, settings *PathSettings) bool {
+
+	reserved := c.cells[reservation{index, time}]
+	if settings.MaxOccupancy > 0 {
+		reserved += c.grid.cellAt(index).Occupancy
+	}
+	return reserved < reservationCapacity(settings)
+}
+
+// search returns the cells of the cheapest path from the start to the goal of the settings within the window, which
+// avoids the reservations, one Cell per timestep. If the window ends before the goal, the path leads to the Cell with
+// the lowest estimated total cost reached at the end of the window. If there is none, nil is returned.
+func (c *CooperativePlanner) search(settings *PathSettings, startTime int) []*Cell {
+
+	m := c.grid
+	heuristic := m.newHeuristic(settings)
+	start := m.cellIndex(settings.start)
+
+	states := []timedState{{start, 0, settings.quantize(m.cellCost(settings.start, settings)), -1}}
+	open := openList{{0, states[0].cost + heuristic.estimate(settings.start)}}
+	closed := make(map[reservation]bool)
+
+	for len(open) > 0 {
+
+		item := open.pop()
+		state := states[item.cell]
+		key := reservation{state.cell, state.time}
+		if closed[key] {
+			continue
+		}
+		closed[key] = true
+		cell := m.cellAt(state.cell)
+
+		// the goal has to stay free until the end of the window, as the unit stays there
+		if m.isGoal(cell, settings) && c.freeUntilWindow(state.cell, startTime+state.time, startTime, settings) {
+			return c.timedCells(states, item.cell)
+		}
+		// the first state at the end of the window has the lowest estimated total cost of all of them
+		if state.time == c.window {
+			return c.timedCells(states, item.cell)
+		}
+
+		// the unit can wait or move to a neighbor, if the Cell is free at the next timestep and no unit comes the
+		// other way
+		next := state.time + 1
+		successors := append(m.neighbors(cell, settings.diagonals), cell)
+		for _, successor := range successors {
+
+			index := m.cellIndex(successor)
+			if closed[reservation{index, next}] || !c.free(index, startTime+next, settings) {
+				continue
+			}
+			cost := state.cost + settings.quantize(m.cellCost(successor, settings))
+			if successor != cell {
+				if !m.canMove(cell, successor, settings) || c.moves[reservedMove{index, state.cell, startTime + state.time}] > 0 {
+					continue
+				}
+				cost = state.cost + settings.quantize(m.moveCost(cell, successor, settings))
+			}
+
+			states = append(states, timedState{index, next, cost, item.cell})
+			open.push(openItem{int32(len(states) - 1), cost + heuristic.estimate(successor)})
+		}
+	}
+
+	return nil
+}
+
+// freeUntilWindow returns if the Cell with the index can be occupied from the timestep until the end of the window.
+func (c *CooperativePlanner) freeUntilWindow(index int32, time, startTime int, settings *PathSettings) bool {
+
+	for t := time; t <= startTime+c.window; t++ {
+		if !c.free(index, t, settings) {
+			return false
+		}
+	}
+	return true
+}
+
+// timedCells returns the cells of the states leading to the state with the index, one per timestep.
+func (c *CooperativePlanner) timedCells(states []timedState, index int32) []*Cell {
+
+	cells := make([]*Cell, states[index].time+1)
+	for i := index; i >= 0; i = states[i].parent {
+		cells[states[i].time] = c.grid.cellAt(states[i].cell)
+	}
+	return cells
+}
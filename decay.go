@@ -0,0 +1,96 @@
+package paths
+
+import (
+	"math"
+	"time"
+)
+
+// A DecayLayer holds values deposited on cells, which fade out over time, e.g. smells, alarm noise or the danger of
+// recent fights. The values are added to the additive modifiers (see CostLayer.SetAdditive) of its CostLayer, so
+// searches using the layer avoid the cells while the values are high and forget about them as they decay. Create it
+// with CostLayer.NewDecayLayer and call Tick regularly, e.g. once per frame.
+type DecayLayer struct {
+	layer *CostLayer
+	// HalfLife is the time after which the values have decayed to half of their amount. Zero disables the decay.
+	HalfLife time.Duration
+	// Threshold is the value below which values are removed completely. Zero means 0.001.
+	Threshold float64
+	values    map[*Cell]float64
+}
+
+// NewDecayLayer returns an empty DecayLayer, whose values modify the costs of the layer.
+func (l *CostLayer) NewDecayLayer(halfLife time.Duration) *DecayLayer {
+	return &DecayLayer{layer: l, HalfLife: halfLife, values: make(map[*Cell]float64)}
+}
+
+// Deposit adds the amount to the value of the Cell.
+func (d *DecayLayer) Deposit(cell *Cell, amount float64) {
+
+	if amount == 0 {
+		return
+	}
+
+	d.set(cell, d.values[cell]+amount)
+	d.layer.grid.MarkChanged()
+}
+
+// Value returns the current value of the Cell.
+func (d *DecayLayer) Value(cell *Cell) float64 {
+	return d.values[cell]
+}
+
+// Cells returns all cells with a value.
+func (d *DecayLayer) Cells() []*Cell {
+
+	cells := make([]*Cell, 0, len(d.values))
+	for cell := range d.values {
+		cells = append(cells, cell)
+	}
+	return cells
+}
+
+// Tick lets the values decay for the passed time.
+func (d *DecayLayer) Tick(dt time.Duration) {
+
+	if d.HalfLife <= 0 || dt <= 0 || len(d.values) == 0 {
+		return
+	}
+
+	factor := math.Pow(.5, float64(dt)/float64(d.HalfLife))
+	for cell, value := range d.values {
+		d.set(cell, value*factor)
+	}
+	d.layer.grid.MarkChanged()
+}
+
+// Clear removes all values.
+func (d *DecayLayer) Clear() {
+
+	if len(d.values) == 0 {
+		return
+	}
+
+	for cell := range d.values {
+		d.set(cell, 0)
+	}
+	d.layer.grid.MarkChanged()
+}
+
+// set changes the value of the Cell and its modifier in the CostLayer, without marking the Grid as changed.
+func (d *DecayLayer) set(cell *Cell, value float64) {
+
+	threshold := d.Threshold
+	if threshold == 0 {
+		threshold = .001
+	}
+	if math.Abs(value) < threshold {
+		value = 0
+	}
+
+	d.layer.addPenalty(cell, value-d.values[cell])
+	if value == 0 {
+		delete(d.values, cell)
+	} else {
+		d.values[cell] = value
+	}
+}
@@ -0,0 +1,108 @@
+package paths
+
+import "strings"
+
+// A Direction is one of the eight directions a path can move in. North points to smaller Y values, East to bigger X
+// values. Directions are bit flags, so multiple of them can be combined with | to a set of directions.
+type Direction uint8
+
+const (
+	North Direction = 1 << iota
+	East
+	South
+	West
+	NorthEast
+	SouthEast
+	SouthWest
+	NorthWest
+)
+
+const (
+	// CardinalDirections contains North, East, South and West.
+	CardinalDirections = North | East | South | West
+	// DiagonalDirections contains NorthEast, SouthEast, SouthWest and NorthWest.
+	DiagonalDirections = NorthEast | SouthEast | SouthWest | NorthWest
+	// AllDirections contains all eight directions.
+	AllDirections = CardinalDirections | DiagonalDirections
+)
+
+// directionOffsets contains the x and y offset of each single Direction.
+var directionOffsets = map[Direction][2]int{
+	North:     {0, -1},
+	East:      {1, 0},
+	South:     {0, 1},
+	West:      {-1, 0},
+	NorthEast: {1, -1},
+	SouthEast: {1, 1},
+	SouthWest: {-1, 1},
+	NorthWest: {-1, -1},
+}
+
+// directionsByOffset contains the Direction of each offset, indexed by [dy+1][dx+1].
+var directionsByOffset = [3][3]Direction{
+	{NorthWest, North, NorthEast},
+	{West, 0, East},
+	{SouthWest, South, SouthEast},
+}
+
+var directionNames = map[Direction]string{
+	North:     "North",
+	East:      "East",
+	South:     "South",
+	West:      "West",
+	NorthEast: "NorthEast",
+	SouthEast: "SouthEast",
+	SouthWest: "SouthWest",
+	NorthWest: "NorthWest",
+}
+
+// DirectionBetween returns the Direction from one cell to a neighboring one. If the cells aren't neighbors, 0 is
+// returned.
+func DirectionBetween(from, to *Cell) Direction {
+	return directionOf(to.X-from.X, to.Y-from.Y)
+}
+
+// directionOf returns the Direction of the passed offset, or 0 if the offset doesn't lead to a neighbor.
+func directionOf(dx, dy int) Direction {
+	if dx < -1 || dx > 1 || dy < -1 || dy > 1 {
+		return 0
+	}
+	return directionsByOffset[dy+1][dx+1]
+}
+
+// Offset returns the x and y offset of a single Direction. For combined directions, 0, 0 is returned.
+func (d Direction) Offset() (dx, dy int) {
+	offset := directionOffsets[d]
+	return offset[0], offset[1]
+}
+
+// Opposite returns the opposite of a single Direction, e.g. South for North.
+func (d Direction) Opposite() Direction {
+	dx, dy := d.Offset()
+	for direction, offset := range directionOffsets {
+		if offset[0] == -dx && offset[1] == -dy {
+			return direction
+		}
+	}
+	return 0
+}
+
+// Contains returns if all of the other directions are contained in this set of directions.
+func (d Direction) Contains(other Direction) bool {
+	return d&other == other
+}
+
+func (d Direction) String() string {
+
+	names := []string{}
+	for _, direction := range []Direction{North, East, South, West, NorthEast, SouthEast, SouthWest, NorthWest} {
+		if d.Contains(direction) {
+			names = append(names, directionNames[direction])
+		}
+	}
+
+	if len(names) == 0 {
+		return "None"
+	}
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,61 @@
+package paths
+
+import "math"
+
+// DistanceMatrix returns the cost of the cheapest path between each pair of the points, e.g. for map balance analysis:
+// matrix[i][j] is the cost from points[i] to points[j], without the cost of points[i]. Unreachable points cost +Inf;
+// unwalkable points can neither reach nor be reached, except by themselves. The costs are computed efficiently with one
+// search per point. The movement rules are taken from the settings; their start and end are ignored.
+func (m *Grid) DistanceMatrix(points []*Cell, settings PathSettings) [][]float64 {
+	return m.costMatrix(points, points, &settings)
+}
+
+// costMatrix returns the cost of the cheapest path from each of the sources to each of the targets. Depending on which
+// needs fewer searches, one search is run per source or per target.
+func (m *Grid) costMatrix(sources, targets []*Cell, settings *PathSettings) [][]float64 {
+
+	matrix := make([][]float64, len(sources))
+	for i := range matrix {
+		matrix[i] = make([]float64, len(targets))
+		for j := range matrix[i] {
+			matrix[i][j] = math.Inf(1)
+		}
+	}
+
+	if len(targets) < len(sources) {
+		for j, target := range targets {
+			if !target.Walkable {
+				continue
+			}
+			costs := m.costsToGoals([]*Cell{target}, settings)
+			for i, source := range sources {
+				if cost, reachable := costs[source]; reachable && source.Walkable {
+					matrix[i][j] = cost
+				}
+			}
+		}
+	} else {
+		for i, source := range sources {
+			if !source.Walkable {
+				continue
+			}
+			costs := m.costsFromStart(source, settings)
+			for j, target := range targets {
+				if cost, reachable := costs[target]; reachable && target.Walkable {
+					matrix[i][j] = cost
+				}
+			}
+		}
+	}
+
+	// a point always reaches itself, even if it isn't walkable
+	for i, source := range sources {
+		for j, target := range targets {
+			if source == target {
+				matrix[i][j] = 0
+			}
+		}
+	}
+
+	return matrix
+}
@@ -0,0 +1,111 @@
+package paths
+
+import "math"
+
+// A DistanceField contains the cost from every Cell of a Grid to the nearest of one or more goals, also known as a
+// Dijkstra map. Monsters approach the goals by moving to the Next Cell, which lowers their value; fleeing, auto-explore
+// (with the unexplored cells as goals) and similar behaviours work the same with other goals or a Flee field, so one
+// field serves any amount of agents. Create one with Grid.DistanceField.
+type DistanceField struct {
+	grid     *Grid
+	revision uint64
+	settings PathSettings
+	// costs contains the quantized value of each cell and directions the Direction to the neighbor lowering it the
+	// most, both indexed like the cells in a search. Cells, from which no goal can be reached, have the value +Inf.
+	costs      []float64
+	directions []Direction
+}
+
+// DistanceField computes the DistanceField towards the goals. The movement rules are taken from the settings; their
+// start and end are ignored. Goals, which aren't walkable, are ignored.
+func (m *Grid) DistanceField(settings PathSettings, goals ...*Cell) *DistanceField {
+
+	d := m.newDistanceField(&settings)
+	for _, goal := range goals {
+		if goal != nil && goal.Walkable {
+			d.costs[m.cellIndex(goal)] = 0
+		}
+	}
+	m.spreadCosts(d.costs, d.directions, &settings)
+
+	return d
+}
+
+// newDistanceField returns a DistanceField, whose cells all have the value +Inf.
+func (m *Grid) newDistanceField(settings *PathSettings) *DistanceField {
+
+	size := m.Width() * m.Height()
+	d := &DistanceField{
+		grid:       m,
+		revision:   m.Revision(),
+		settings:   *settings,
+		costs:      make([]float64, size),
+		directions: make([]Direction, size),
+	}
+	for i := range d.costs {
+		d.costs[i] = math.Inf(1)
+	}
+
+	return d
+}
+
+// Flee returns a DistanceField leading away from the goals: the values are multiplied with -factor and then lowered
+// wherever a path to a lower value is cheaper, so agents following it don't run into dead ends but around the goals
+// to distant areas. Factors slightly above 1, e.g. 1.2, make agents prefer such escapes over just increasing the
+// distance.
+func (d *DistanceField) Flee(factor float64) *DistanceField {
+
+	m := d.grid
+	settings := &d.settings
+	flee := m.newDistanceField(settings)
+	for i, cost := range d.costs {
+		if !math.IsInf(cost, 1) {
+			flee.costs[i] = settings.quantize(-settings.unquantize(cost) * factor)
+		}
+	}
+	m.spreadCosts(flee.costs, flee.directions, settings)
+
+	return flee
+}
+
+// Outdated returns if the Grid has been changed since the DistanceField has been computed.
+func (d *DistanceField) Outdated() bool {
+	return d.grid.Revision() != d.revision
+}
+
+// Value returns the value of the Cell, which is the cost of the cheapest path to the nearest goal without the cost of
+// the Cell itself for fields created by Grid.DistanceField. If no goal can be reached, false is returned.
+func (d *DistanceField) Value(cell *Cell) (float64, bool) {
+
+	cost := d.costs[d.grid.cellIndex(cell)]
+	if math.IsInf(cost, 1) {
+		return cost, false
+	}
+	return d.settings.unquantize(cost), true
+}
+
+// Next returns the neighbor of the Cell to move to for lowering the value the most, including the cost of the move. For
+// goals, other local minima and cells, from which no goal can be reached, nil is returned.
+func (d *DistanceField) Next(cell *Cell) *Cell {
+
+	direction := d.directions[d.grid.cellIndex(cell)]
+	if direction == 0 {
+		return nil
+	}
+	dx, dy := direction.Offset()
+	return d.grid.Get(cell.X+dx, cell.Y+dy)
+}
+
+// Values exports the DistanceField as the value of each Cell, indexed by [y][x]. Cells, from which no goal can be
+// reached, have the value +Inf.
+func (d *DistanceField) Values() [][]float64 {
+
+	values := make([][]float64, d.grid.Height())
+	for y := range values {
+		values[y] = make([]float64, d.grid.Width())
+		for x := range values[y] {
+			values[y][x], _ = d.Value(d.grid.Get(x, y))
+		}
+	}
+	return values
+}
@@ -0,0 +1,127 @@
+package paths
+
+import (
+	"math"
+	"sort"
+)
+
+// An edge is a move from one Cell to a neighboring one (by their indices), see Grid.SetEdgeCost.
+type edge struct {
+	from, to int32
+}
+
+// SetEdgeCost overrides the cost of moving from one Cell to the neighboring Cell "to", e.g. for fences, windows or low
+// walls between two cells. The cost replaces the cost of entering "to" (its Cost with the cost layers, the knowledge
+// and the risk of the settings, its EnterCost and the ExitCost of "from"), while the surcharge of diagonal moves and
+// the multipliers of postures and water are still applied. math.Inf(1) and NaN block the move (see BlockedEdge);
+// negative costs are treated as zero. The move in the opposite direction isn't changed. Cells which aren't neighbors are
+// ignored.
+func (m *Grid) SetEdgeCost(from, to *Cell, cost float64) {
+
+	key, ok := m.edgeKey(from, to)
+	if !ok {
+		return
+	}
+	if m.edgeCosts == nil {
+		m.edgeCosts = make(map[edge]float64)
+		m.edgeCells = make(map[int32]int)
+	}
+	if _, exists := m.edgeCosts[key]; !exists {
+		m.edgeCells[key.from]++
+		m.edgeCells[key.to]++
+	}
+	m.edgeCosts[key] = searchCost(cost)
+	m.MarkChanged()
+}
+
+// RemoveEdgeCost removes the cost set by SetEdgeCost for the move from one Cell to the other, so it costs as much as
+// any other move again.
+func (m *Grid) RemoveEdgeCost(from, to *Cell) {
+
+	key, ok := m.edgeKey(from, to)
+	if !ok {
+		return
+	}
+	if _, exists := m.edgeCosts[key]; !exists {
+		return
+	}
+	delete(m.edgeCosts, key)
+	for _, index := range []int32{key.from, key.to} {
+		if m.edgeCells[index]--; m.edgeCells[index] <= 0 {
+			delete(m.edgeCells, index)
+		}
+	}
+	m.MarkChanged()
+}
+
+// EdgeCost returns the cost set by SetEdgeCost for the move from one Cell to the other and if there is one.
+func (m *Grid) EdgeCost(from, to *Cell) (float64, bool) {
+
+	key, ok := m.edgeKey(from, to)
+	if !ok {
+		return 0, false
+	}
+	cost, exists := m.edgeCosts[key]
+	return cost, exists
+}
+
+// edgeKey returns the key of the move between the cells in edgeCosts, if they are neighboring cells of the Grid.
+func (m *Grid) edgeKey(from, to *Cell) (edge, bool) {
+
+	if m.Get(from.X, from.Y) == nil || m.Get(to.X, to.Y) == nil || from == to ||
+		abs(from.X-to.X) > 1 || abs(from.Y-to.Y) > 1 {
+		return edge{}, false
+	}
+	return edge{m.cellIndex(from), m.cellIndex(to)}, true
+}
+
+// enteringCost returns the cost of entering the Cell "to" from its neighbor "from" before the surcharges and multipliers
+// of moveCost are applied: the cost of the edge between them, if there is one, or the cost of "to" plus the cost of the
+// transition.
+func (m *Grid) enteringCost(from, to *Cell, settings *PathSettings) float64 {
+
+	if len(m.edgeCosts) > 0 {
+		if cost, exists := m.edgeCosts[edge{m.cellIndex(from), m.cellIndex(to)}]; exists {
+			return cost
+		}
+	}
+	return m.cellCost(to, settings) + transitionCost(from, to)
+}
+
+// edgeBlocked returns if the move from one Cell to the other is blocked by an edge with an infinite cost.
+func (m *Grid) edgeBlocked(from, to *Cell) bool {
+
+	if len(m.edgeCosts) == 0 {
+		return false
+	}
+	cost, exists := m.edgeCosts[edge{m.cellIndex(from), m.cellIndex(to)}]
+	return exists && math.IsInf(cost, 1)
+}
+
+// hasEdges returns if the Cell is part of a move with an edge cost.
+func (m *Grid) hasEdges(cell *Cell) bool {
+	return len(m.edgeCells) > 0 && m.edgeCells[m.cellIndex(cell)] > 0
+}
+
+// minEdgeCost returns the lowest cost of all edges, or +Inf if there are none.
+func (m *Grid) minEdgeCost() float64 {
+
+	minCost := math.Inf(1)
+	for _, cost := range m.edgeCosts {
+		minCost = math.Min(minCost, cost)
+	}
+	return minCost
+}
+
+// sortedEdges returns the edges with a cost in a fixed order.
+func (m *Grid) sortedEdges() []edge {
+
+	edges := make([]edge, 0, len(m.edgeCosts))
+	for key := range m.edgeCosts {
+		edges = append(edges, key)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].from < edges[j].from || (edges[i].from == edges[j].from && edges[i].to < edges[j].to)
+	})
+	return edges
+}
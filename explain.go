@@ -0,0 +1,105 @@
+package paths
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A StepExplanation explains a single step of a Path: the chosen move and why each other neighbor wasn't taken.
+type StepExplanation struct {
+	// From and To are the cells of the step.
+	From, To *Cell
+	// Cost is the cost of the remaining path when taking this step, i.e. the cost of the move plus the cheapest cost
+	// from To to the end of the Path.
+	Cost float64
+	// Alternatives contains all other neighbors of From, except the cell the Path came from.
+	Alternatives []Alternative
+}
+
+// An Alternative is a neighbor, which could have been taken instead of the chosen step.
+type Alternative struct {
+	Cell *Cell
+	// Blocked tells why the move to this cell isn't possible. If it's NotBlocked, the move was possible but not chosen.
+	Blocked BlockReason
+	// Reachable is false if the end of the Path can't be reached from this cell.
+	Reachable bool
+	// Cost is the cost of the remaining path when moving to this cell instead; only valid if the move wasn't blocked
+	// and the end of the Path is reachable.
+	Cost float64
+}
+
+func (alternative Alternative) String() string {
+
+	position := fmt.Sprintf("X:%d Y:%d", alternative.Cell.X, alternative.Cell.Y)
+
+	if alternative.Blocked != NotBlocked {
+		return fmt.Sprintf("%s: %s", position, alternative.Blocked)
+	}
+	if !alternative.Reachable {
+		return fmt.Sprintf("%s: end not reachable from there", position)
+	}
+	return fmt.Sprintf("%s: cost %f", position, alternative.Cost)
+}
+
+func (step StepExplanation) String() string {
+
+	s := strings.Builder{}
+	s.WriteString(fmt.Sprintf("X:%d Y:%d -> X:%d Y:%d (cost %f)\n", step.From.X, step.From.Y, step.To.X, step.To.Y, step.Cost))
+
+	for _, alternative := range step.Alternatives {
+
+		s.WriteString("    " + alternative.String())
+		if alternative.Blocked == NotBlocked && alternative.Reachable && alternative.Cost < step.Cost {
+			// this only happens for paths searched without PathSettings.RequireOptimal
+			s.WriteString(" (cheaper)")
+		}
+		s.WriteString("\n")
+	}
+
+	return s.String()
+}
+
+// ExplainPath explains why the passed Path goes the way it does. For each step, all other neighbors of the current cell
+// are listed with the reason they weren't taken: Either the move isn't possible (too high, not walkable, ...) or
+// continuing from there to the end of the Path is more expensive. Only the movement rules of the settings are used;
+// the start and end are taken from the Path. This helps to debug surprising routes.
+func ExplainPath(grid *Grid, path *Path, settings PathSettings) []StepExplanation {
+
+	if path == nil || len(path.Cells) < 2 {
+		return nil
+	}
+
+	costsToEnd := grid.costsToGoals([]*Cell{path.Cells[len(path.Cells)-1]}, &settings)
+
+	// remainingCost returns the cost of moving from one cell to the next and then to the end of the path
+	remainingCost := func(from, to *Cell) (float64, bool) {
+		cost, reachable := costsToEnd[to]
+		return grid.moveCost(from, to, &settings) + cost, reachable
+	}
+
+	explanations := []StepExplanation{}
+
+	for i := 0; i < len(path.Cells)-1; i++ {
+
+		from, to := path.Cells[i], path.Cells[i+1]
+		step := StepExplanation{From: from, To: to}
+		step.Cost, _ = remainingCost(from, to)
+
+		for _, neighbor := range grid.neighbors(from, settings.diagonals) {
+
+			if neighbor == to || (i > 0 && neighbor == path.Cells[i-1]) {
+				continue
+			}
+
+			alternative := Alternative{Cell: neighbor, Blocked: grid.checkMove(from, neighbor, &settings)}
+			if alternative.Blocked == NotBlocked {
+				alternative.Cost, alternative.Reachable = remainingCost(from, neighbor)
+			}
+			step.Alternatives = append(step.Alternatives, alternative)
+		}
+
+		explanations = append(explanations, step)
+	}
+
+	return explanations
+}
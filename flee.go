@@ -0,0 +1,50 @@
+package paths
+
+import "math"
+
+// GetFleePath searches a path from the start Cell away from the threats, e.g. for fleeing monsters or civilians. The
+// distance to the threats is the path cost from a Cell to the nearest threat, so walls between the agent and a threat
+// count, unlike with running into the opposite direction. The path leads to the nearest Cell with a distance of at
+// least minDistance; cells the threats can't reach at all are always safe. If there is no such Cell or minDistance is 0
+// or less, the path leads to the reachable Cell farthest away from the threats. The movement rules are taken from the
+// settings; their start and end are ignored. If the start isn't walkable, nil is returned.
+func (m *Grid) GetFleePath(start *Cell, threats []*Cell, minDistance float64, settings PathSettings) *Path {
+
+	distances := m.costsToGoals(threats, &settings)
+
+	settings.start, settings.end = start, nil
+	settings.RequireOptimal = true
+
+	if minDistance > 0 {
+		settings.Goal = func(cell *Cell) bool {
+			distance, reachable := distances[cell]
+			return !reachable || distance >= minDistance
+		}
+		if path := m.findPath(&settings, nil); path == nil || len(path.Cells) > 0 {
+			return path
+		}
+	}
+
+	// there is no safe cell, so all reachable cells are visited to find the farthest one; cells the threats can't reach
+	// are infinitely far away
+	distance := func(cell *Cell) float64 {
+		if distance, reachable := distances[cell]; reachable {
+			return distance
+		}
+		return math.Inf(1)
+	}
+	farthest := start
+	settings.Goal = func(cell *Cell) bool {
+		if distance(cell) > distance(farthest) {
+			farthest = cell
+		}
+		return false
+	}
+	if m.findPath(&settings, nil) == nil {
+		return nil
+	}
+
+	settings.Goal = nil
+	settings.end = farthest
+	return m.findPath(&settings, nil)
+}
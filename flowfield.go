@@ -0,0 +1,148 @@
+package paths
+
+import "math"
+
+// A FlowField contains the Direction and the cost of the cheapest path from every Cell of a Grid to a goal, e.g. for
+// RTS games with many units moving to the same target: instead of searching a path per unit, each unit looks up the
+// Direction of the Cell it stands on. It's computed once by Grid.GenerateFlowField; after changes of the Grid, it has
+// to be generated again (see Outdated).
+type FlowField struct {
+	grid     *Grid
+	goal     *Cell
+	revision uint64
+	settings PathSettings
+	// costs contains the quantized cost from each cell to the goal and directions the Direction to its next cell, both
+	// indexed like the cells in a search. Unreachable cells cost +Inf.
+	costs      []float64
+	directions []Direction
+}
+
+// GenerateFlowField computes the FlowField towards the goal. The movement rules are taken from the settings, so the
+// MaxStepHeight and the walkability are respected like by a path search; the start and end of the settings are
+// ignored. If the goal isn't walkable, no Cell can reach it.
+func (m *Grid) GenerateFlowField(goal *Cell, settings PathSettings) *FlowField {
+
+	size := m.Width() * m.Height()
+	f := &FlowField{
+		grid:       m,
+		goal:       goal,
+		revision:   m.Revision(),
+		settings:   settings,
+		costs:      make([]float64, size),
+		directions: make([]Direction, size),
+	}
+	for i := range f.costs {
+		f.costs[i] = math.Inf(1)
+	}
+	if goal == nil || !goal.Walkable {
+		return f
+	}
+
+	f.costs[m.cellIndex(goal)] = 0
+	m.spreadCosts(f.costs, f.directions, &settings)
+
+	return f
+}
+
+// spreadCosts completes the quantized costs of paths to the cells with finite costs, which are e.g. 0 for goals: each
+// Cell gets the lowest sum of the cost of a path to one of them and its cost. The directions are set to the next Cell of
+// each improved path. The search runs backwards like the one of costsToGoals.
+func (m *Grid) spreadCosts(costs []float64, directions []Direction, settings *PathSettings) {
+
+	open := openList{}
+	for i, cost := range costs {
+		if !math.IsInf(cost, 1) {
+			open.push(openItem{int32(i), cost})
+		}
+	}
+
+	closed := make([]bool, len(costs))
+	for len(open) > 0 {
+
+		item := open.pop()
+		if closed[item.cell] {
+			continue
+		}
+		closed[item.cell] = true
+		current := m.cellAt(item.cell)
+
+		// the search goes backwards: each neighbor, which can move to the current cell, is checked
+		for _, neighbor := range m.neighbors(current, settings.diagonals) {
+
+			index := m.cellIndex(neighbor)
+			if closed[index] || !settings.assumeWalkable(neighbor) || !m.canMove(neighbor, current, settings) {
+				continue
+			}
+
+			cost := costs[item.cell] + settings.quantize(m.moveCost(neighbor, current, settings))
+			if cost < costs[index] {
+				costs[index] = cost
+				directions[index] = DirectionBetween(neighbor, current)
+				open.push(openItem{index, cost})
+			}
+		}
+	}
+}
+
+// Goal returns the Cell the FlowField leads to.
+func (f *FlowField) Goal() *Cell {
+	return f.goal
+}
+
+// Outdated returns if the Grid has been changed since the FlowField has been generated.
+func (f *FlowField) Outdated() bool {
+	return f.grid.Revision() != f.revision
+}
+
+// Cost returns the cost of the cheapest path from the Cell to the goal. The cost of the Cell itself isn't included. If
+// the goal can't be reached, false is returned.
+func (f *FlowField) Cost(cell *Cell) (float64, bool) {
+
+	cost := f.costs[f.grid.cellIndex(cell)]
+	if math.IsInf(cost, 1) {
+		return cost, false
+	}
+	return f.settings.unquantize(cost), true
+}
+
+// Direction returns the Direction to move in from the Cell to get closer to the goal. For the goal and cells, from
+// which the goal can't be reached, 0 is returned.
+func (f *FlowField) Direction(cell *Cell) Direction {
+	return f.directions[f.grid.cellIndex(cell)]
+}
+
+// Next returns the neighbor of the Cell the Direction points to, or nil if the Direction is 0.
+func (f *FlowField) Next(cell *Cell) *Cell {
+
+	direction := f.Direction(cell)
+	if direction == 0 {
+		return nil
+	}
+	dx, dy := direction.Offset()
+	return f.grid.Get(cell.X+dx, cell.Y+dy)
+}
+
+// PathFrom returns the Path following the FlowField from the Cell to the goal, or nil if the goal can't be reached.
+func (f *FlowField) PathFrom(cell *Cell) *Path {
+
+	if _, reachable := f.Cost(cell); !reachable {
+		return nil
+	}
+
+	path := &Path{StepHeight: int(f.settings.MaxStepHeight)}
+	for c := cell; c != nil; c = f.Next(c) {
+		path.Cells = append(path.Cells, c)
+	}
+	return path
+}
+
+// Directions exports the FlowField as the Direction of each Cell, indexed by [y][x], like PathTree.Directions.
+func (f *FlowField) Directions() [][]Direction {
+
+	directions := make([][]Direction, f.grid.Height())
+	for y := range directions {
+		directions[y] = make([]Direction, f.grid.Width())
+		copy(directions[y], f.directions[y*f.grid.Width():])
+	}
+	return directions
+}
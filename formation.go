@@ -0,0 +1,149 @@
+package paths
+
+import "math"
+
+// A Formation defines how a group of units is arranged around the destination of its leader, see
+// Grid.FormationGoals.
+type Formation int
+
+const (
+	// LineFormation places the units side by side, alternating to the right and to the left of the leader.
+	LineFormation Formation = iota
+	// ColumnFormation places the units behind each other along the path of the leader.
+	ColumnFormation
+	// BoxFormation places the units in a square behind the leader, filling it row by row.
+	BoxFormation
+	// WedgeFormation places the units in a V behind the leader, which forms its tip.
+	WedgeFormation
+)
+
+// FormationGoals returns a goal Cell for each of the units of a group following the leader Path, so the units don't
+// all target its last Cell. The formation is oriented in the direction the leader faces at the end of its Path; the
+// first goal is the one of the leader itself. spacing is the distance between neighboring units in cells; 0 means 1.
+// Each slot of the formation is snapped to the nearest free, walkable Cell on the same height level as the last Cell of
+// the Path, so units don't end up on walls or cliffs. If there is no such Cell for a unit, its goal is nil.
+func (m *Grid) FormationGoals(leaderPath *Path, units int, formation Formation, spacing int) []*Cell {
+
+	goals := make([]*Cell, units)
+	if leaderPath == nil || len(leaderPath.Cells) == 0 {
+		return goals
+	}
+	if spacing <= 0 {
+		spacing = 1
+	}
+
+	dest := leaderPath.Cells[len(leaderPath.Cells)-1]
+
+	// the forward and right vectors of the formation
+	forwardX, forwardY := North.Offset()
+	if len(leaderPath.Cells) > 1 {
+		previous := leaderPath.Cells[len(leaderPath.Cells)-2]
+		forwardX, forwardY = sign(dest.X-previous.X), sign(dest.Y-previous.Y)
+	}
+	rightX, rightY := -forwardY, forwardX
+
+	taken := make(map[*Cell]bool, units)
+
+	for unit := 0; unit < units; unit++ {
+
+		var x, y int
+		if formation == ColumnFormation {
+			x, y = columnSlot(leaderPath, unit*spacing, forwardX, forwardY)
+		} else {
+			right, back := formationSlot(formation, unit, units)
+			x = dest.X + (rightX*right-forwardX*back)*spacing
+			y = dest.Y + (rightY*right-forwardY*back)*spacing
+		}
+
+		goals[unit] = m.nearestFreeCell(x, y, dest.HeightLevel, taken)
+		if goals[unit] != nil {
+			taken[goals[unit]] = true
+		}
+	}
+
+	return goals
+}
+
+// formationSlot returns the position of the unit in the formation in units to the right of and behind the leader.
+func formationSlot(formation Formation, unit, units int) (right, back int) {
+
+	switch formation {
+
+	case BoxFormation:
+		side := int(math.Ceil(math.Sqrt(float64(units))))
+		return unit%side - side/2, unit / side
+
+	case WedgeFormation:
+		// the units 1 and 2 form the first row, 3 and 4 the second one and so on
+		row := (unit + 1) / 2
+		if unit%2 == 1 {
+			return row, row
+		}
+		return -row, row
+	}
+
+	// line: 0, 1, -1, 2, -2, ...
+	if unit%2 == 1 {
+		return (unit + 1) / 2, 0
+	}
+	return -unit / 2, 0
+}
+
+// columnSlot returns the position the passed distance back along the path. Behind the start of the path, the column
+// continues straight back.
+func columnSlot(path *Path, distance, forwardX, forwardY int) (x, y int) {
+
+	if distance < len(path.Cells) {
+		slot := path.Cells[len(path.Cells)-1-distance]
+		return slot.X, slot.Y
+	}
+
+	start := path.Cells[0]
+	if len(path.Cells) > 1 {
+		forwardX, forwardY = sign(path.Cells[1].X-start.X), sign(path.Cells[1].Y-start.Y)
+	}
+	beyond := distance - (len(path.Cells) - 1)
+	return start.X - forwardX*beyond, start.Y - forwardY*beyond
+}
+
+// nearestFreeCell returns the walkable Cell on the height level closest to x, y, which isn't taken yet. If there is
+// none, nil is returned.
+func (m *Grid) nearestFreeCell(x, y, heightLevel int, taken map[*Cell]bool) *Cell {
+
+	var nearest *Cell
+	nearestDistance := math.Inf(1)
+	maxRadius := m.Width() + m.Height()
+
+	// the rings around the position are searched until no closer cell can be found anymore
+	for radius := 0; radius <= maxRadius && float64(radius) <= nearestDistance; radius++ {
+		for cy := y - radius; cy <= y+radius; cy++ {
+			for cx := x - radius; cx <= x+radius; cx++ {
+
+				if abs(cx-x) != radius && abs(cy-y) != radius {
+					continue
+				}
+
+				cell := m.Get(cx, cy)
+				if cell == nil || !cell.Walkable || cell.HeightLevel != heightLevel || taken[cell] {
+					continue
+				}
+
+				if distance := math.Hypot(float64(cx-x), float64(cy-y)); distance < nearestDistance {
+					nearest, nearestDistance = cell, distance
+				}
+			}
+		}
+	}
+
+	return nearest
+}
+
+func sign(x int) int {
+	if x < 0 {
+		return -1
+	}
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,128 @@
+package paths
+
+import "math"
+
+// fringe runs a FringeSearch and writes the resulting path into the Path. Returns if a path has been found.
+//
+// The fringe is a doubly linked list of the cells, whose closed stamp is the generation of the buffer. Each iteration
+// walks through the fringe once: cells within the threshold are expanded and replaced by their neighbors, which are
+// inserted right after them, so they are checked in the same iteration. All other cells stay for the next iteration,
+// whose threshold is the lowest estimate beyond the current one.
+func (m *Grid) fringe(path *Path, buffer *searchBuffer, settings *PathSettings, observer *searchObserver, stats *SearchStats) bool {
+
+	if len(buffer.fringeNext) != len(buffer.costs) {
+		buffer.fringeNext = make([]int32, len(buffer.costs))
+		buffer.fringePrevious = make([]int32, len(buffer.costs))
+	}
+	next, previous := buffer.fringeNext, buffer.fringePrevious
+	head := int32(-1)
+
+	insertAfter := func(index, after int32) {
+		buffer.closed[index] = buffer.generation
+		if after < 0 {
+			next[index], previous[index] = head, -1
+			if head >= 0 {
+				previous[head] = index
+			}
+			head = index
+			return
+		}
+		next[index], previous[index] = next[after], after
+		if next[after] >= 0 {
+			previous[next[after]] = index
+		}
+		next[after] = index
+	}
+	remove := func(index int32) {
+		// generation - 1 is never the current generation, even after an overflow
+		buffer.closed[index] = buffer.generation - 1
+		if previous[index] >= 0 {
+			next[previous[index]] = next[index]
+		} else {
+			head = next[index]
+		}
+		if next[index] >= 0 {
+			previous[next[index]] = previous[index]
+		}
+	}
+
+	heuristic := m.newHeuristic(settings)
+	threshold := math.Inf(1)
+	last := int32(-1)
+	for _, start := range append([]*Cell{settings.start}, settings.starts...) {
+		index := m.cellIndex(start)
+		if buffer.reached[index] == buffer.generation || !settings.assumeWalkable(start) {
+			continue
+		}
+		cost := settings.quantize(m.cellCost(start, settings))
+		buffer.reach(index, cost, -1)
+		insertAfter(index, last)
+		last = index
+		threshold = math.Min(threshold, cost+heuristic.estimate(start))
+	}
+
+	for head >= 0 {
+
+		nextThreshold := math.Inf(1)
+		for index := head; index >= 0; {
+
+			cell := m.cellAt(index)
+			estimate := buffer.costs[index] + heuristic.estimate(cell)
+			if estimate > threshold {
+				nextThreshold = math.Min(nextThreshold, estimate)
+				index = next[index]
+				continue
+			}
+
+			if m.isGoal(cell, settings) {
+				m.finishSearch(path, buffer, index, settings, observer)
+				stats.Cost = settings.unquantize(buffer.costs[index])
+				return true
+			}
+
+			stats.Expanded++
+			if observer != nil && observer.expanded != nil {
+				observer.expanded(cell)
+			}
+
+			for _, offset := range neighborOffsets {
+
+				if !settings.diagonals && offset[0] != 0 && offset[1] != 0 {
+					continue
+				}
+				neighbor := m.Get(cell.X+offset[0], cell.Y+offset[1])
+				if neighbor == nil || !m.canMove(cell, neighbor, settings) {
+					continue
+				}
+				neighborIndex := m.cellIndex(neighbor)
+				if settings.corridor != nil && !settings.corridor[neighborIndex] {
+					continue
+				}
+				cost := buffer.costs[index] + settings.quantize(m.moveCost(cell, neighbor, settings))
+				if buffer.reached[neighborIndex] == buffer.generation && buffer.costs[neighborIndex] <= cost {
+					continue
+				}
+
+				// the neighbor moves right behind the expanded cell, even if it's in the fringe already
+				if buffer.closed[neighborIndex] == buffer.generation {
+					remove(neighborIndex)
+				}
+				buffer.reach(neighborIndex, cost, index)
+				insertAfter(neighborIndex, index)
+				stats.Pushed++
+			}
+
+			following := next[index]
+			remove(index)
+			index = following
+		}
+
+		// if no cell has been left for the next iteration, the whole reachable area has been searched
+		if math.IsInf(nextThreshold, 1) {
+			return false
+		}
+		threshold = nextThreshold
+	}
+
+	return false
+}
@@ -0,0 +1,232 @@
+package paths
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// goalBoundsVersion is the version of the format written by GoalBounds.Save.
+const goalBoundsVersion = 1
+
+// ErrGoalBoundsMismatch is returned by Grid.LoadGoalBounds if the loaded GoalBounds have been computed for another Grid.
+var ErrGoalBoundsMismatch = errors.New("goal bounds don't match the grid")
+
+// A goalBox is the bounding box of all cells, whose cheapest paths from a Cell begin with the same move. It's empty if
+// minX is bigger than maxX.
+type goalBox struct {
+	minX, minY, maxX, maxY int32
+}
+
+// contains returns if the position lies within the box.
+func (b goalBox) contains(x, y int) bool {
+	return int32(x) >= b.minX && int32(x) <= b.maxX && int32(y) >= b.minY && int32(y) <= b.maxY
+}
+
+// GoalBounds are precomputed bounding boxes (goal bounding), which let searches on static maps skip whole directions:
+// for each Cell and each of its eight neighbors, they contain the bounding box of all cells whose cheapest path from
+// the Cell begins with the move to that neighbor. A search with PathSettings.GoalBounds doesn't follow moves, whose box
+// doesn't contain the end Cell, which drastically reduces the expanded cells, while the paths stay as cheap as before.
+//
+// Computing them runs a search from every Cell, so it's only worth it for maps, which don't change, e.g. once at build
+// time with the result saved by Save and loaded with the map by Grid.LoadGoalBounds. They are only valid for the
+// movement rules they have been computed with; searches with other rules may miss cheaper paths. Once the Grid is
+// changed, they are ignored, see Outdated.
+type GoalBounds struct {
+	grid     *Grid
+	settings PathSettings
+	// boxes contains the boxes of each Cell (by its index) in the order of neighborOffsets.
+	boxes    []goalBox
+	revision uint64
+}
+
+// NewGoalBounds computes the GoalBounds of the Grid for the movement rules of the settings. Their start, end, Goal and
+// StopWithinRange are ignored. This takes a search from every walkable Cell, so it's slow on big maps.
+func (m *Grid) NewGoalBounds(settings PathSettings) *GoalBounds {
+
+	settings.start, settings.end = nil, nil
+	settings.Goal, settings.StopWithinRange = nil, 0
+	settings.goals, settings.starts, settings.corridor = nil, nil, nil
+	settings.GoalBounds, settings.Landmarks = nil, nil
+
+	size := m.Width() * m.Height()
+	b := &GoalBounds{grid: m, settings: settings, boxes: make([]goalBox, size*len(neighborOffsets)), revision: m.Revision()}
+	for i := range b.boxes {
+		b.boxes[i] = goalBox{math.MaxInt32, math.MaxInt32, math.MinInt32, math.MinInt32}
+	}
+
+	costs := make([]float64, size)
+	first := make([]int8, size)
+	closed := make([]bool, size)
+	for source := 0; source < size; source++ {
+		if cell := m.cellAt(int32(source)); settings.assumeWalkable(cell) {
+			b.computeCell(int32(source), costs, first, closed)
+		}
+	}
+	return b
+}
+
+// computeCell runs a search from the Cell with the index and grows its boxes by each reached Cell. The slices are
+// reused by all cells.
+func (b *GoalBounds) computeCell(source int32, costs []float64, first []int8, closed []bool) {
+
+	m, settings := b.grid, &b.settings
+	for i := range costs {
+		costs[i] = math.Inf(1)
+		first[i] = -1
+		closed[i] = false
+	}
+	costs[source] = 0
+
+	open := openList{{source, 0}}
+	for len(open) > 0 {
+
+		item := open.pop()
+		index := item.cell
+		if closed[index] {
+			continue
+		}
+		closed[index] = true
+		cell := m.cellAt(index)
+
+		if index != source {
+			box := &b.boxes[int(source)*len(neighborOffsets)+int(first[index])]
+			x, y := int32(cell.X), int32(cell.Y)
+			box.minX, box.minY = minInt32(box.minX, x), minInt32(box.minY, y)
+			box.maxX, box.maxY = maxInt32(box.maxX, x), maxInt32(box.maxY, y)
+		}
+
+		for direction, offset := range neighborOffsets {
+
+			if !settings.diagonals && offset[0] != 0 && offset[1] != 0 {
+				continue
+			}
+			neighbor := m.Get(cell.X+offset[0], cell.Y+offset[1])
+			if neighbor == nil || !m.canMove(cell, neighbor, settings) {
+				continue
+			}
+			neighborIndex := m.cellIndex(neighbor)
+			cost := costs[index] + settings.quantize(m.moveCost(cell, neighbor, settings))
+			if closed[neighborIndex] || cost >= costs[neighborIndex] {
+				continue
+			}
+
+			costs[neighborIndex] = cost
+			// the move from the source the path begins with
+			if index == source {
+				first[neighborIndex] = int8(direction)
+			} else {
+				first[neighborIndex] = first[index]
+			}
+			open.push(openItem{neighborIndex, cost})
+		}
+	}
+}
+
+// Outdated returns if the Grid has been changed since the GoalBounds have been computed or loaded. Outdated GoalBounds
+// are ignored by searches.
+func (b *GoalBounds) Outdated() bool {
+	return b.revision != b.grid.Revision()
+}
+
+// Settings returns the movement rules the GoalBounds have been computed with.
+func (b *GoalBounds) Settings() PathSettings {
+	return b.settings
+}
+
+// prunes returns if the move from the Cell with the index in the direction (the index of its offset in
+// neighborOffsets) can't begin a cheapest path to the end Cell.
+func (b *GoalBounds) prunes(index int32, direction int, end *Cell) bool {
+	return !b.boxes[int(index)*len(neighborOffsets)+direction].contains(end.X, end.Y)
+}
+
+// usable returns if the GoalBounds can prune the search of the Grid with the settings: they have to be up-to-date, and
+// the search has to lead to a single end Cell.
+func (b *GoalBounds) usable(m *Grid, settings *PathSettings) bool {
+	return b.grid == m && !b.Outdated() && settings.end != nil && settings.Goal == nil && settings.goals == nil &&
+		settings.StopWithinRange <= 0
+}
+
+// savedGoalBounds is the serializable form of GoalBounds.
+type savedGoalBounds struct {
+	Version  int    `json:"version"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Checksum uint64 `json:"checksum"`
+	Settings Preset `json:"settings"`
+	// Boxes contains minX, minY, maxX and maxY of each box.
+	Boxes []int32 `json:"boxes"`
+}
+
+// Save writes the GoalBounds as JSON together with the Checksum of the Grid, so they can be loaded with the map by
+// Grid.LoadGoalBounds instead of being computed again.
+func (b *GoalBounds) Save(w io.Writer) error {
+
+	saved := savedGoalBounds{
+		Version:  goalBoundsVersion,
+		Width:    b.grid.Width(),
+		Height:   b.grid.Height(),
+		Checksum: b.grid.Checksum(),
+		Settings: newPreset(&b.settings),
+		Boxes:    make([]int32, 0, len(b.boxes)*4),
+	}
+	for _, box := range b.boxes {
+		saved.Boxes = append(saved.Boxes, box.minX, box.minY, box.maxX, box.maxY)
+	}
+	return json.NewEncoder(w).Encode(saved)
+}
+
+// LoadGoalBounds reads GoalBounds written by GoalBounds.Save for this Grid. If they have been computed for a Grid in
+// another state (see Grid.Checksum), an error wrapping ErrGoalBoundsMismatch is returned.
+func (m *Grid) LoadGoalBounds(r io.Reader) (*GoalBounds, error) {
+
+	saved := savedGoalBounds{}
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return nil, err
+	}
+
+	if saved.Version != goalBoundsVersion {
+		return nil, fmt.Errorf("unsupported goal bounds version %d", saved.Version)
+	}
+	if saved.Width != m.Width() || saved.Height != m.Height() {
+		return nil, fmt.Errorf("%w: they are %dx%d, but the grid is %dx%d", ErrGoalBoundsMismatch, saved.Width,
+			saved.Height, m.Width(), m.Height())
+	}
+	if saved.Checksum != m.Checksum() {
+		return nil, fmt.Errorf("%w: the checksums differ", ErrGoalBoundsMismatch)
+	}
+	if len(saved.Boxes) != m.Width()*m.Height()*len(neighborOffsets)*4 {
+		return nil, fmt.Errorf("goal bounds contain %d values, but the grid needs %d", len(saved.Boxes),
+			m.Width()*m.Height()*len(neighborOffsets)*4)
+	}
+
+	settings, err := saved.Settings.PathSettings(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &GoalBounds{grid: m, settings: *settings, boxes: make([]goalBox, len(saved.Boxes)/4), revision: m.Revision()}
+	for i := range b.boxes {
+		values := saved.Boxes[i*4 : i*4+4]
+		b.boxes[i] = goalBox{values[0], values[1], values[2], values[3]}
+	}
+	return b, nil
+}
+
+// minInt32 returns the smaller of the two numbers.
+func minInt32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxInt32 returns the bigger of the two numbers.
+func maxInt32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,114 @@
+package paths
+
+// A GoalFunc decides, if a Cell is a goal of a search, see PathSettings.Goal.
+type GoalFunc func(cell *Cell) bool
+
+// GoalCells returns a GoalFunc, which accepts all of the passed cells.
+func GoalCells(cells ...*Cell) GoalFunc {
+
+	goals := make(map[*Cell]bool, len(cells))
+	for _, cell := range cells {
+		goals[cell] = true
+	}
+
+	return func(cell *Cell) bool {
+		return goals[cell]
+	}
+}
+
+// GoalRect returns a GoalFunc, which accepts all cells inside the rectangle from minX, minY to maxX, maxY (both
+// inclusive).
+func GoalRect(minX, minY, maxX, maxY int) GoalFunc {
+	return func(cell *Cell) bool {
+		return cell.X >= minX && cell.X <= maxX && cell.Y >= minY && cell.Y <= maxY
+	}
+}
+
+// GoalAdjacent returns a GoalFunc, which accepts all eight neighbors of the target Cell, e.g. to walk up to a chest.
+func GoalAdjacent(target *Cell) GoalFunc {
+	return GoalAdjacentFrom(target, AllDirections)
+}
+
+// GoalAdjacentFrom works like GoalAdjacent, but only accepts the neighbors on the passed sides of the target, e.g.
+// South for a lever, which can only be pulled from the front.
+func GoalAdjacentFrom(target *Cell, sides Direction) GoalFunc {
+	return func(cell *Cell) bool {
+		side := DirectionBetween(target, cell)
+		return side != 0 && sides.Contains(side)
+	}
+}
+
+// GetPathToAdjacent searches a path from the start of the settings to a Cell next to the target, e.g. for melee attacks
+// or to interact with objects standing on unwalkable cells. Only the neighbors on the passed sides of the target are
+// accepted; 0 accepts all sides. The end of the settings is ignored. Besides the Path, the Direction the agent faces
+// at its end towards the target is returned. If no path can be found, nil and 0 are returned.
+func (m *Grid) GetPathToAdjacent(target *Cell, sides Direction, settings PathSettings) (*Path, Direction) {
+
+	if sides == 0 {
+		sides = AllDirections
+	}
+
+	settings.end = nil
+	settings.Goal = GoalAdjacentFrom(target, sides)
+
+	path := m.findPath(&settings, nil)
+	if path == nil || len(path.Cells) == 0 || path.Partial {
+		return nil, 0
+	}
+
+	return path, DirectionBetween(path.Cells[len(path.Cells)-1], target)
+}
+
+// GetPathToNearest searches a path from the start of the settings to the nearest of the goals in a single search, e.g.
+// to the closest of multiple resource cells. Unlike a Goal, which can't estimate the remaining cost, the search heads
+// towards the closest goals like AStar. With RequireOptimal, the goal with the cheapest path is reached; otherwise,
+// the first one found. To end at any Cell matching a condition instead, use the Goal of the settings. The end and the
+// Goal of the settings are ignored. Besides the Path, the goal reached is returned. If no path can be found, nil and
+// nil are returned.
+func (m *Grid) GetPathToNearest(goals []*Cell, settings PathSettings) (*Path, *Cell) {
+
+	if len(goals) == 0 {
+		return nil, nil
+	}
+
+	settings.end, settings.StopWithinRange = nil, 0
+	settings.Goal = GoalCells(goals...)
+	settings.goals = goals
+
+	path := m.findPath(&settings, nil)
+	if path == nil || len(path.Cells) == 0 || path.Partial {
+		return nil, nil
+	}
+
+	return path, path.Cells[len(path.Cells)-1]
+}
+
+// GetPathFromNearest searches the cheapest path from any of the starts to the end of the settings in a single search,
+// e.g. from the closest exit of a building. Like with multiple goals (see GetPathToNearest), RequireOptimal guarantees
+// the cheapest path from any start; otherwise, the first one found is returned. IterativeDeepening and registered
+// algorithms only search from one start, so AStar is used instead. The start of the settings is ignored. Besides the
+// Path, the start it begins at is returned. If no path can be found, nil and nil are returned.
+func (m *Grid) GetPathFromNearest(starts []*Cell, settings PathSettings) (*Path, *Cell) {
+
+	settings.start = nil
+	for _, start := range starts {
+		if settings.assumeWalkable(start) {
+			settings.start = start
+			break
+		}
+	}
+	if settings.start == nil {
+		return nil, nil
+	}
+	settings.starts = starts
+	if custom, _ := settings.Algorithm.custom(); custom != nil || settings.Algorithm == IterativeDeepening {
+		settings.Algorithm = AStar
+	}
+
+	path := m.findPath(&settings, nil)
+	if path == nil || len(path.Cells) == 0 || path.Partial {
+		return nil, nil
+	}
+
+	return path, path.Cells[0]
+}
@@ -0,0 +1,285 @@
+package paths
+
+import "container/heap"
+
+// A Grid3D is a voxel world made up of stacked Grids, one per level. Level 0 is the lowest one. A walkable Cell on a
+// level is a voxel an agent can stand on; not walkable cells are air or solid rock. This allows multiple floors,
+// caves and bridges at the same X and Y position, which a single Grid with height levels can't represent.
+type Grid3D struct {
+	Levels []*Grid
+}
+
+// NewGrid3D returns a new Grid3D of (width x height x depth) size. All cells are walkable, like in NewGrid.
+func NewGrid3D(width, height, depth int) *Grid3D {
+
+	g := &Grid3D{}
+	for z := 0; z < depth; z++ {
+		g.Levels = append(g.Levels, NewGrid(width, height))
+	}
+
+	return g
+}
+
+// Width returns the width of the levels.
+func (g *Grid3D) Width() int {
+	if len(g.Levels) == 0 {
+		return 0
+	}
+	return g.Levels[0].Width()
+}
+
+// Height returns the height of the levels.
+func (g *Grid3D) Height() int {
+	if len(g.Levels) == 0 {
+		return 0
+	}
+	return g.Levels[0].Height()
+}
+
+// Depth returns the amount of levels.
+func (g *Grid3D) Depth() int {
+	return len(g.Levels)
+}
+
+// Get returns a pointer to the Cell in the x, y and z position provided, or nil if it's outside of the Grid3D.
+func (g *Grid3D) Get(x, y, z int) *Cell {
+	if z < 0 || z >= len(g.Levels) {
+		return nil
+	}
+	return g.Levels[z].Get(x, y)
+}
+
+// SurfaceAt returns the topmost walkable Cell in the column at x and y and its level. If the column doesn't contain
+// any walkable Cell, nil and -1 are returned.
+func (g *Grid3D) SurfaceAt(x, y int) (*Cell, int) {
+	return g.DropTo(x, y, len(g.Levels)-1)
+}
+
+// DropTo returns the Cell an agent lands on when falling down from level fromZ at x and y, i.e. the highest walkable
+// Cell at or below fromZ, and its level. If there is nothing to land on, nil and -1 are returned.
+func (g *Grid3D) DropTo(x, y, fromZ int) (*Cell, int) {
+
+	if fromZ >= len(g.Levels) {
+		fromZ = len(g.Levels) - 1
+	}
+
+	for z := fromZ; z >= 0; z-- {
+		if cell := g.Levels[z].Get(x, y); cell != nil && cell.Walkable {
+			return cell, z
+		}
+	}
+
+	return nil, -1
+}
+
+// ClearanceAt returns the free height above the Cell at x, y and z. If the Cell has a Clearance, it's returned.
+// Otherwise, the next walkable Cell above is the ceiling. If there is none, 0 (unlimited) is returned.
+func (g *Grid3D) ClearanceAt(x, y, z int) float64 {
+
+	cell := g.Get(x, y, z)
+	if cell == nil {
+		return 0
+	}
+	if cell.Clearance > 0 {
+		return cell.Clearance
+	}
+
+	for above := z + 1; above < len(g.Levels); above++ {
+		if g.Levels[above].Get(x, y).Walkable {
+			return float64(above - z)
+		}
+	}
+
+	return 0
+}
+
+// LevelOf returns the level of the Cell, or -1 if the Cell doesn't belong to the Grid3D.
+func (g *Grid3D) LevelOf(cell *Cell) int {
+	for z, level := range g.Levels {
+		if level.Get(cell.X, cell.Y) == cell {
+			return z
+		}
+	}
+	return -1
+}
+
+// A Path3D is a path through a Grid3D. Unlike a Path, it also contains the cells an agent falls through, so each step
+// knows its level and if the agent is falling there.
+type Path3D struct {
+	Steps []Step3D
+}
+
+// A Step3D is a single Cell of a Path3D.
+type Step3D struct {
+	Cell  *Cell
+	Level int
+	// Falling is true for the cells the agent falls through after stepping off a ledge. These cells aren't walkable;
+	// the step after the last falling one is the landing Cell.
+	Falling bool
+	// Posture is the name of the posture (see PathSettings.Postures) used on the Cell, or an empty string where the
+	// agent stands upright.
+	Posture string
+}
+
+// Cells returns the cells of the path, including the ones fallen through.
+func (p *Path3D) Cells() []*Cell {
+
+	cells := []*Cell{}
+	for _, step := range p.Steps {
+		cells = append(cells, step.Cell)
+	}
+
+	return cells
+}
+
+// GetPath searches the cheapest path from the start Cell to the destination Cell, which may be on different levels.
+// Agents can move to the neighboring columns: they step up onto cells up to MaxStepHeight levels higher and step down
+// onto the highest walkable Cell below them up to MaxDropHeight levels deeper. Deeper drops are falls, which are only
+// possible up to MaxFallHeight levels and cost FallCost per level. The start and end of the settings are ignored. If
+// no path can be found, nil is returned.
+func (g *Grid3D) GetPath(start, dest *Cell, settings PathSettings) *Path3D {
+
+	if start == nil || dest == nil || !start.Walkable || !dest.Walkable {
+		return nil
+	}
+
+	openNodes := minHeap{}
+	startZ := g.LevelOf(start)
+	if startZ < 0 {
+		return nil
+	}
+	startCost := g.Levels[startZ].cellCost(start, &settings)
+	heap.Push(&openNodes, &Node{Cell: start, Cost: startCost})
+	bestCosts := map[*Cell]float64{start: startCost}
+	closed := make(map[*Cell]bool)
+	levels := map[*Cell]int{start: startZ}
+
+	for len(openNodes) > 0 {
+
+		node := heap.Pop(&openNodes).(*Node)
+		if closed[node.Cell] {
+			continue
+		}
+		closed[node.Cell] = true
+
+		if node.Cell == dest {
+			return g.path3D(node, levels, &settings)
+		}
+
+		cell, z := node.Cell, levels[node.Cell]
+
+		for _, offset := range neighborOffsets {
+
+			if !settings.diagonals && offset[0] != 0 && offset[1] != 0 {
+				continue
+			}
+
+			neighbor, neighborZ := g.moveTarget(cell, z, offset[0], offset[1], &settings)
+			if neighbor == nil || closed[neighbor] {
+				continue
+			}
+
+			cellCost := g.Levels[neighborZ].cellCost(neighbor, &settings) + transitionCost(cell, neighbor)
+			cost := node.Cost + moveCostBelow(cell, neighbor, cellCost, g.ClearanceAt(neighbor.X, neighbor.Y, neighborZ), &settings)
+			if fallen := z - neighborZ; isFall(fallen, &settings) {
+				cost += float64(fallen) * settings.FallCost
+			}
+
+			if previousCost, reached := bestCosts[neighbor]; reached && previousCost <= cost {
+				continue
+			}
+			bestCosts[neighbor] = cost
+			levels[neighbor] = neighborZ
+			heap.Push(&openNodes, &Node{Cell: neighbor, Parent: node, Cost: cost})
+		}
+	}
+
+	return nil
+}
+
+// moveTarget returns the Cell (and its level) an agent standing on the passed Cell at level z reaches when moving by
+// the passed offset, or nil if the move isn't possible.
+func (g *Grid3D) moveTarget(from *Cell, z, dx, dy int, settings *PathSettings) (*Cell, int) {
+
+	x, y := from.X+dx, from.Y+dy
+	if x < 0 || y < 0 || x >= g.Width() || y >= g.Height() {
+		return nil, -1
+	}
+	if !settings.allowsDirection(dx, dy) {
+		return nil, -1
+	}
+
+	// the highest cell, which can be stepped on
+	top := len(g.Levels) - 1
+	if settings.MaxStepHeight >= 0 {
+		top = z + int(settings.MaxStepHeight)
+	}
+	to, toZ := g.DropTo(x, y, top)
+	if to == nil {
+		return nil, -1
+	}
+
+	drop := z - toZ
+	if settings.MaxDropHeight >= 0 && float64(drop) > settings.MaxDropHeight && !isFall(drop, settings) {
+		return nil, -1
+	}
+	if settings.MaxOccupancy > 0 && to.Occupancy >= settings.MaxOccupancy {
+		return nil, -1
+	}
+	if g.Levels[toZ].blockedByCategory(to, settings) {
+		return nil, -1
+	}
+	if !fitsClearance(g.ClearanceAt(to.X, to.Y, toZ), settings) {
+		return nil, -1
+	}
+
+	// diagonal moves can't squeeze between two walls at the current level
+	if dx != 0 && dy != 0 && settings.wallBlocksDiagonals {
+		corner1, corner2 := g.Get(from.X, y, z), g.Get(x, from.Y, z)
+		if !corner1.Walkable && !corner2.Walkable {
+			return nil, -1
+		}
+	}
+
+	return to, toZ
+}
+
+// isFall returns if dropping down the passed amount of levels is a fall, which is allowed by the settings.
+func isFall(drop int, settings *PathSettings) bool {
+
+	if settings.MaxDropHeight < 0 || float64(drop) <= settings.MaxDropHeight {
+		return false
+	}
+	return settings.MaxFallHeight < 0 || (settings.MaxFallHeight > 0 && float64(drop) <= settings.MaxFallHeight)
+}
+
+// path3D walks up the parents of the node and returns the resulting Path3D, including the cells fallen through.
+func (g *Grid3D) path3D(node *Node, levels map[*Cell]int, settings *PathSettings) *Path3D {
+
+	path := &Path3D{}
+
+	for n := node; n != nil; n = n.Parent {
+
+		z := levels[n.Cell]
+		posture := settings.postureName(g.ClearanceAt(n.Cell.X, n.Cell.Y, z))
+		path.Steps = append(path.Steps, Step3D{Cell: n.Cell, Level: z, Posture: posture})
+
+		if n.Parent == nil {
+			continue
+		}
+
+		// the cells between the ledge and the landing cell are added backwards as well
+		parentZ := levels[n.Parent.Cell]
+		if fallen := parentZ - z; isFall(fallen, settings) {
+			for level := z + 1; level <= parentZ; level++ {
+				path.Steps = append(path.Steps, Step3D{Cell: g.Get(n.Cell.X, n.Cell.Y, level), Level: level, Falling: true})
+			}
+		}
+	}
+
+	for i, j := 0, len(path.Steps)-1; i < j; i, j = i+1, j-1 {
+		path.Steps[i], path.Steps[j] = path.Steps[j], path.Steps[i]
+	}
+
+	return path
+}
@@ -0,0 +1,236 @@
+package paths
+
+import (
+	"fmt"
+	"math"
+)
+
+// An Algorithm is the order in which a search checks the cells, see PathSettings.Algorithm.
+type Algorithm int
+
+const (
+	// AStar checks the cells in the order of their cost plus an estimate of the remaining cost to the end Cell, so
+	// the search heads towards the end and checks far less cells than UniformCost on big maps. The estimate never
+	// exceeds the actual remaining cost, so RequireOptimal still guarantees a cheapest path. Searches for a Goal
+	// can't estimate the remaining cost and work like UniformCost.
+	AStar Algorithm = iota
+	// UniformCost (Dijkstra's algorithm) checks the cells in the order of their cost only, spreading evenly in all
+	// directions.
+	UniformCost
+	// JumpPointSearch works like AStar, but skips the cells of open areas with uniform costs and heights, which makes
+	// searches on big open maps much faster. The paths are as cheap as with AStar. Wherever the costs or heights vary,
+	// the cells are checked like with AStar. It needs diagonal movement with walls blocking diagonals, all directions
+	// allowed and an end Cell without StopWithinRange; otherwise, AStar is used.
+	JumpPointSearch
+	// ThetaStar (Theta*) works like AStar, but connects each Cell to the farthest Cell before it on the path, which it
+	// can be reached from in a straight line, so the paths go straight at any angle instead of following the eight
+	// directions. Straight lines follow the same rules as moves between neighbors, e.g. walkability and step heights,
+	// for all cells they cross, and cost as much as the moves over these cells, shortened to the length of the line.
+	// The Path only contains the cells the path turns at, besides the start and the end. The default heuristic is
+	// EuclideanHeuristic.
+	ThetaStar
+	// IterativeDeepening (IDA*) searches depth-first along the estimate of AStar and repeats the search with a higher
+	// cost limit until it reaches the end, so it only needs memory for the current path instead of all checked cells,
+	// e.g. for huge grids on embedded targets. It finds the same paths as AStar with RequireOptimal, but checks cells
+	// over and over again: each iteration follows every path without loops, whose estimated cost stays within the
+	// limit, and there may be an iteration per distinct cost below the one of the found path. So the time grows
+	// exponentially with the length of the path; with costs between 1 and 5, a search across an open 10x10 grid can
+	// already take tens of millions of expansions. Use it with uniform costs and short paths. Unreachable ends are
+	// detected before the search, which needs one bit per Cell. The MemoryLimit is ignored.
+	IterativeDeepening
+	// FringeSearch checks the cells along the estimate of AStar in iterations with a rising cost limit like
+	// IterativeDeepening, but keeps the cells at the border of each iteration (the fringe) in a list for the next one
+	// instead of searching again from the start. It doesn't need the sorted list of cells to check, whose upkeep takes
+	// most of the time of AStar, so it's faster if the costs of the paths only take a few different values, e.g. with
+	// integer costs and without diagonal movement. Otherwise, the many iterations make it slower than AStar. It finds
+	// the same paths as AStar with RequireOptimal. The MemoryLimit is ignored.
+	FringeSearch
+)
+
+// builtinAlgorithms contains all algorithms, which don't need to be registered.
+var builtinAlgorithms = []Algorithm{AStar, UniformCost, JumpPointSearch, ThetaStar, IterativeDeepening, FringeSearch}
+
+func (algorithm Algorithm) String() string {
+	if _, name := algorithm.custom(); name != "" {
+		return name
+	}
+	switch algorithm {
+	case AStar:
+		return "A*"
+	case UniformCost:
+		return "uniform cost"
+	case JumpPointSearch:
+		return "jump point search"
+	case ThetaStar:
+		return "theta*"
+	case IterativeDeepening:
+		return "ida*"
+	case FringeSearch:
+		return "fringe"
+	}
+	return fmt.Sprintf("Algorithm(%d)", int(algorithm))
+}
+
+// A Heuristic is the distance measure AStar estimates the remaining cost with, see PathSettings.Heuristic. All of them
+// are multiplied with the lowest possible cost of a move, so they never overestimate unless noted otherwise.
+type Heuristic int
+
+const (
+	// DefaultHeuristic chooses the most accurate heuristic for the movement: OctileHeuristic with diagonal movement,
+	// ManhattanHeuristic without and EuclideanHeuristic for the straight lines of ThetaStar.
+	DefaultHeuristic Heuristic = iota
+	// ManhattanHeuristic is the X plus the Y distance. With diagonal movement, it overestimates the remaining cost,
+	// which makes the search faster, but may miss the cheapest path. While RequireOptimal is set, OctileHeuristic is
+	// used with diagonal movement instead.
+	ManhattanHeuristic
+	// EuclideanHeuristic is the straight-line distance, e.g. for agents whose paths are smoothed afterwards.
+	EuclideanHeuristic
+	// ChebyshevHeuristic is the bigger one of the X and the Y distance, i.e. every move costs the same.
+	ChebyshevHeuristic
+	// OctileHeuristic is the amount of diagonal and straight moves needed with diagonal movement, each with its own
+	// cost.
+	OctileHeuristic
+)
+
+func (h Heuristic) String() string {
+	switch h {
+	case DefaultHeuristic:
+		return "default"
+	case ManhattanHeuristic:
+		return "manhattan"
+	case EuclideanHeuristic:
+		return "euclidean"
+	case ChebyshevHeuristic:
+		return "chebyshev"
+	case OctileHeuristic:
+		return "octile"
+	}
+	return fmt.Sprintf("Heuristic(%d)", int(h))
+}
+
+// heuristic estimates the remaining cost from a Cell to the end of a search, see AStar.
+type heuristic struct {
+	enabled bool
+	end     *Cell
+	// ends are the cells of a search for the nearest of multiple cells, see Grid.GetPathToNearest. The estimate is the
+	// one to the closest of them.
+	ends []*Cell
+	// straight and diagonal are the lowest possible costs of a straight and a diagonal move.
+	straight, diagonal float64
+	// reduction is the amount of moves the search may stop before the end Cell, see PathSettings.StopWithinRange.
+	reduction int
+	kind      Heuristic
+	// landmarks raise the estimate to the one of the Landmarks of the settings, if they are usable.
+	landmarks *Landmarks
+	// weight is multiplied with the estimate, see PathSettings.HeuristicWeight.
+	weight float64
+}
+
+// newHeuristic returns the heuristic of a search with the passed settings. The costs of the moves are estimated with
+// the lowest cost a Cell can have in this search (see MinCost), which includes the cost layers and the knowledge of the
+// settings, and with the lowest multiplier of the water and the postures.
+func (m *Grid) newHeuristic(settings *PathSettings) heuristic {
+
+	if settings.Algorithm == UniformCost || ((settings.Goal != nil || settings.end == nil) && settings.goals == nil) {
+		return heuristic{}
+	}
+
+	// the risk surcharge only increases the costs, so it doesn't need to be considered
+	minCost := m.settingsMinCost(settings)
+	// edges may be cheaper than all cells
+	minCost = math.Min(minCost, m.minEdgeCost())
+
+	multiplier := 1.0
+	if settings.Water != nil && settings.Water.WaterCostMultiplier > 0 {
+		multiplier = math.Min(multiplier, settings.Water.WaterCostMultiplier)
+	}
+	for _, posture := range settings.Postures {
+		multiplier = math.Min(multiplier, posture.CostMultiplier)
+	}
+	multiplier = math.Max(0, multiplier)
+
+	h := heuristic{
+		enabled:  true,
+		end:      settings.end,
+		ends:     settings.goals,
+		straight: settings.quantize(minCost * multiplier),
+		diagonal: settings.quantize((minCost + diagonalCost) * multiplier),
+		kind:     settings.Heuristic,
+		weight:   math.Max(1, settings.HeuristicWeight),
+	}
+	// weighted estimates may miss the cheapest path
+	if settings.RequireOptimal {
+		h.weight = 1
+	}
+	if settings.Landmarks != nil && settings.Landmarks.usable(m, settings) {
+		h.landmarks = settings.Landmarks
+	}
+	if h.kind == DefaultHeuristic {
+		h.kind = ManhattanHeuristic
+		if settings.Algorithm == ThetaStar {
+			h.kind = EuclideanHeuristic
+		} else if settings.diagonals {
+			h.kind = OctileHeuristic
+		}
+	}
+	// the manhattan distance overestimates diagonal moves, which may miss the cheapest path
+	if settings.RequireOptimal && settings.diagonals && h.kind == ManhattanHeuristic {
+		h.kind = OctileHeuristic
+	}
+	// a diagonal move can't be cheaper than two straight ones, or the estimate would be too high on cheap cells
+	h.diagonal = math.Min(h.diagonal, 2*h.straight)
+	if settings.StopWithinRange > 0 {
+		// all metrics allow at most this amount of moves along each axis within the range
+		h.reduction = int(settings.StopWithinRange)
+	}
+
+	return h
+}
+
+// estimate returns the estimated remaining cost from the Cell to the end of the search. It's 0 if the heuristic is
+// disabled.
+func (h *heuristic) estimate(cell *Cell) float64 {
+
+	if !h.enabled {
+		return 0
+	}
+	return h.distance(cell) * h.weight
+}
+
+// distance returns the estimated remaining cost from the Cell to the end of the search without the weight.
+func (h *heuristic) distance(cell *Cell) float64 {
+
+	if h.ends == nil {
+		return h.distanceTo(cell, h.end)
+	}
+	distance := math.Inf(1)
+	for _, end := range h.ends {
+		distance = math.Min(distance, h.distanceTo(cell, end))
+	}
+	return distance
+}
+
+// distanceTo returns the estimated remaining cost from the Cell to the passed end without the weight.
+func (h *heuristic) distanceTo(cell, end *Cell) float64 {
+
+	dx := maxInt(0, abs(cell.X-end.X)-h.reduction)
+	dy := maxInt(0, abs(cell.Y-end.Y)-h.reduction)
+	distance := 0.0
+	switch h.kind {
+	case ManhattanHeuristic:
+		distance = float64(dx+dy) * h.straight
+	case EuclideanHeuristic:
+		// a diagonal move covers a distance of the square root of 2
+		distance = math.Hypot(float64(dx), float64(dy)) * math.Min(h.straight, h.diagonal/math.Sqrt2)
+	case ChebyshevHeuristic:
+		distance = float64(maxInt(dx, dy)) * math.Min(h.straight, h.diagonal)
+	default:
+		diagonalMoves := minInt(dx, dy)
+		distance = float64(diagonalMoves)*h.diagonal + float64(maxInt(dx, dy)-diagonalMoves)*h.straight
+	}
+
+	if h.landmarks != nil {
+		distance = math.Max(distance, h.landmarks.estimate(cell, end))
+	}
+	return distance
+}
@@ -0,0 +1,580 @@
+package paths
+
+import (
+	"math"
+	"sync"
+)
+
+// A HierarchicalGrid answers long-distance path requests on big grids much faster than a search of the whole Grid
+// (HPA*). The Grid is divided into square clusters; the cells at which paths can cross from one cluster into the next
+// (entrances) are connected with the cheapest paths within their clusters in advance. A request only searches the
+// abstract graph of these connections and then refines the result into cells by searching within the clusters
+// passed. The paths are slightly more expensive than the cheapest ones, as they cross the clusters at the entrances
+// only.
+//
+// The abstract graph is built with the movement rules of the settings passed to Grid.NewHierarchicalGrid. After
+// changes of the Grid, call Update with the changed cells, which only rebuilds the affected clusters. If the Grid has
+// been changed without Update, the next request rebuilds the whole graph. A HierarchicalGrid can be used by multiple
+// goroutines.
+type HierarchicalGrid struct {
+	grid        *Grid
+	settings    PathSettings
+	clusterSize int
+	// clustersX and clustersY are the amount of clusters in each row and column.
+	clustersX, clustersY int
+	clusters             []*cluster
+	// revision is the revision of the Grid the graph has been built for.
+	revision uint64
+	// lock is held for the whole request, as the corridor is shared by all requests.
+	lock     sync.Mutex
+	corridor []bool
+}
+
+// A cluster is a square part of the Grid of a HierarchicalGrid.
+type cluster struct {
+	minX, minY, maxX, maxY int
+	// entrances contains the entrances to the clusters east, south, south-east and south-west of this one.
+	entrances []entrance
+	// links contains the connections of each entrance cell of the cluster, to other entrance cells of this cluster
+	// and to the entrance cells of the neighboring clusters.
+	links map[*Cell][]clusterLink
+}
+
+// An entrance is a pair of neighboring cells of two clusters, where paths cross from one into the other.
+type entrance struct {
+	a, b *Cell
+}
+
+// A clusterLink is a connection of the abstract graph of a HierarchicalGrid with its quantized cost.
+type clusterLink struct {
+	to   *Cell
+	cost float64
+}
+
+// entranceSplit is the length of a border section, from which on it gets an entrance at each end instead of one in the
+// middle.
+const entranceSplit = 6
+
+// NewHierarchicalGrid divides the Grid into clusters of clusterSize cells (at least 2) in each direction and builds the
+// abstract graph for the movement rules of the settings. Their start and end are ignored.
+func (m *Grid) NewHierarchicalGrid(clusterSize int, settings PathSettings) *HierarchicalGrid {
+
+	if clusterSize < 2 {
+		clusterSize = 2
+	}
+	settings.start, settings.end, settings.Goal = nil, nil, nil
+
+	h := &HierarchicalGrid{
+		grid:        m,
+		settings:    settings,
+		clusterSize: clusterSize,
+		clustersX:   (m.Width() + clusterSize - 1) / clusterSize,
+		clustersY:   (m.Height() + clusterSize - 1) / clusterSize,
+	}
+	h.build()
+	return h
+}
+
+// build builds the whole abstract graph.
+func (h *HierarchicalGrid) build() {
+
+	h.revision = h.grid.Revision()
+	h.corridor = make([]bool, h.grid.Width()*h.grid.Height())
+	h.clusters = make([]*cluster, h.clustersX*h.clustersY)
+	for cy := 0; cy < h.clustersY; cy++ {
+		for cx := 0; cx < h.clustersX; cx++ {
+			h.clusters[cy*h.clustersX+cx] = &cluster{
+				minX: cx * h.clusterSize,
+				minY: cy * h.clusterSize,
+				maxX: minInt((cx+1)*h.clusterSize, h.grid.Width()) - 1,
+				maxY: minInt((cy+1)*h.clusterSize, h.grid.Height()) - 1,
+			}
+		}
+	}
+
+	for cy := 0; cy < h.clustersY; cy++ {
+		for cx := 0; cx < h.clustersX; cx++ {
+			h.findEntrances(cx, cy)
+		}
+	}
+	for cy := 0; cy < h.clustersY; cy++ {
+		for cx := 0; cx < h.clustersX; cx++ {
+			h.linkCluster(cx, cy)
+		}
+	}
+}
+
+// Update rebuilds the parts of the abstract graph affected by changes of the passed cells.
+func (h *HierarchicalGrid) Update(cells ...*Cell) {
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if len(cells) == 0 {
+		return
+	}
+
+	// the entrances of the changed clusters to all of their neighbors may have changed, and so may the links of the
+	// neighbors
+	changed := make(map[Point]bool)
+	for _, cell := range cells {
+		changed[Point{cell.X / h.clusterSize, cell.Y / h.clusterSize}] = true
+	}
+	affected := make(map[Point]bool)
+	for point := range changed {
+		for y := point.Y - 1; y <= point.Y+1; y++ {
+			for x := point.X - 1; x <= point.X+1; x++ {
+				affected[Point{x, y}] = true
+			}
+		}
+	}
+
+	for point := range affected {
+		for _, offset := range [5][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {-1, 1}} {
+			if changed[Point{point.X + offset[0], point.Y + offset[1]}] {
+				h.findEntrances(point.X, point.Y)
+				break
+			}
+		}
+	}
+	for point := range affected {
+		h.linkCluster(point.X, point.Y)
+	}
+
+	h.revision = h.grid.Revision()
+}
+
+// cluster returns the cluster at the cluster coordinates, or nil if there is none.
+func (h *HierarchicalGrid) cluster(cx, cy int) *cluster {
+	if cx < 0 || cy < 0 || cx >= h.clustersX || cy >= h.clustersY {
+		return nil
+	}
+	return h.clusters[cy*h.clustersX+cx]
+}
+
+// clusterOf returns the cluster containing the Cell.
+func (h *HierarchicalGrid) clusterOf(cell *Cell) *cluster {
+	return h.cluster(cell.X/h.clusterSize, cell.Y/h.clusterSize)
+}
+
+// findEntrances finds the entrances of the cluster at the cluster coordinates to its eastern and southern neighbors and
+// the diagonal crossings at its southern corners.
+func (h *HierarchicalGrid) findEntrances(cx, cy int) {
+
+	c := h.cluster(cx, cy)
+	if c == nil {
+		return
+	}
+
+	m := h.grid
+	c.entrances = nil
+	if h.cluster(cx+1, cy) != nil {
+		c.entrances = append(c.entrances, h.borderEntrances(c.maxX, c.minY, 0, 1, c.maxY-c.minY+1, 1, 0)...)
+	}
+	if h.cluster(cx, cy+1) != nil {
+		c.entrances = append(c.entrances, h.borderEntrances(c.minX, c.maxY, 1, 0, c.maxX-c.minX+1, 0, 1)...)
+	}
+	if h.settings.diagonals {
+		for _, corner := range []entrance{
+			{m.Get(c.maxX, c.maxY), m.Get(c.maxX+1, c.maxY+1)},
+			{m.Get(c.minX, c.maxY), m.Get(c.minX-1, c.maxY+1)},
+		} {
+			if corner.b != nil && h.crossable(corner.a, corner.b) {
+				c.entrances = append(c.entrances, corner)
+			}
+		}
+	}
+}
+
+// crossable returns if both cells are walkable and a path can move between them in at least one direction.
+func (h *HierarchicalGrid) crossable(a, b *Cell) bool {
+	settings := &h.settings
+	if !settings.assumeWalkable(a) || !settings.assumeWalkable(b) {
+		return false
+	}
+	return h.grid.canMove(a, b, settings) || h.grid.canMove(b, a, settings)
+}
+
+// borderEntrances returns the entrances along a border of the given length, which starts at (x, y) and goes in the
+// direction (dx, dy). The neighboring cluster lies in the direction (nx, ny).
+func (h *HierarchicalGrid) borderEntrances(x, y, dx, dy, length, nx, ny int) []entrance {
+
+	m := h.grid
+	entrances := []entrance{}
+
+	// sections of pairs of cells, which can be crossed and moved along, get one entrance in the middle or one at each
+	// end if they are long
+	start := -1
+	for i := 0; i <= length; i++ {
+
+		var a, b *Cell
+		open := false
+		if i < length {
+			a, b = m.Get(x+i*dx, y+i*dy), m.Get(x+i*dx+nx, y+i*dy+ny)
+			open = h.crossable(a, b)
+		}
+		if open && start >= 0 && i > start {
+			// the section ends, if it can't be followed along both sides of the border
+			previousA, previousB := m.Get(a.X-dx, a.Y-dy), m.Get(b.X-dx, b.Y-dy)
+			if !h.followable(previousA, a) || !h.followable(previousB, b) {
+				entrances = h.sectionEntrances(entrances, x, y, dx, dy, nx, ny, start, i)
+				start = -1
+			}
+		}
+
+		if i < length && h.settings.diagonals {
+			// diagonal crossings get an entrance, unless a straight crossing at one of their ends reaches the other end
+			// along the border: the cells next to them may not be reachable otherwise
+			for _, side := range []int{-1, 1} {
+				if i+side < 0 || i+side >= length {
+					continue
+				}
+				next, other := m.Get(a.X+side*dx, a.Y+side*dy), m.Get(b.X+side*dx, b.Y+side*dy)
+				if !h.crossable(a, other) {
+					continue
+				}
+				if open && h.followable(b, other) || h.crossable(next, other) && h.followable(a, next) {
+					continue
+				}
+				entrances = append(entrances, entrance{a, other})
+			}
+		}
+
+		if open && start < 0 {
+			start = i
+		}
+		if open || start < 0 {
+			continue
+		}
+
+		entrances = h.sectionEntrances(entrances, x, y, dx, dy, nx, ny, start, i)
+		start = -1
+		i--
+	}
+
+	return entrances
+}
+
+// followable returns if a path can move between the two neighboring cells in both directions.
+func (h *HierarchicalGrid) followable(a, b *Cell) bool {
+	return h.grid.canMove(a, b, &h.settings) && h.grid.canMove(b, a, &h.settings)
+}
+
+// sectionEntrances appends the entrances of the section of a border from start to end (exclusive), see
+// borderEntrances.
+func (h *HierarchicalGrid) sectionEntrances(entrances []entrance, x, y, dx, dy, nx, ny, start, end int) []entrance {
+
+	positions := []int{(start + end - 1) / 2}
+	if end-start >= entranceSplit {
+		positions = []int{start, end - 1}
+	}
+	for _, position := range positions {
+		a := h.grid.Get(x+position*dx, y+position*dy)
+		entrances = append(entrances, entrance{a, h.grid.Get(a.X+nx, a.Y+ny)})
+	}
+	return entrances
+}
+
+// linkCluster connects the entrance cells of the cluster at the cluster coordinates with each other and with the
+// entrance cells of the neighboring clusters.
+func (h *HierarchicalGrid) linkCluster(cx, cy int) {
+
+	c := h.cluster(cx, cy)
+	if c == nil {
+		return
+	}
+
+	m := h.grid
+	settings := &h.settings
+	c.links = make(map[*Cell][]clusterLink)
+
+	// the entrances to the western and northern neighbors are stored by the neighbors
+	crossings := append([]entrance{}, c.entrances...)
+	for _, offset := range [4][2]int{{-1, 0}, {0, -1}, {-1, -1}, {1, -1}} {
+		if neighbor := h.cluster(cx+offset[0], cy+offset[1]); neighbor != nil {
+			for _, e := range neighbor.entrances {
+				if h.clusterOf(e.b) == c {
+					crossings = append(crossings, entrance{e.b, e.a})
+				}
+			}
+		}
+	}
+
+	for _, crossing := range crossings {
+		if _, exists := c.links[crossing.a]; !exists {
+			c.links[crossing.a] = nil
+		}
+		if m.canMove(crossing.a, crossing.b, settings) {
+			cost := settings.quantize(m.moveCost(crossing.a, crossing.b, settings))
+			c.links[crossing.a] = append(c.links[crossing.a], clusterLink{crossing.b, cost})
+		}
+	}
+
+	// the cheapest paths within the cluster to each entrance cell are found with one search per entrance cell
+	for to := range c.links {
+		costs := h.clusterCosts(c, to, false)
+		for from := range c.links {
+			if cost := costs[h.localIndex(c, from)]; from != to && !math.IsInf(cost, 1) {
+				c.links[from] = append(c.links[from], clusterLink{to, cost})
+			}
+		}
+	}
+}
+
+// localIndex returns the index of the Cell within the cluster.
+func (h *HierarchicalGrid) localIndex(c *cluster, cell *Cell) int32 {
+	return int32((cell.Y-c.minY)*(c.maxX-c.minX+1) + cell.X - c.minX)
+}
+
+// clusterCosts returns the quantized costs of the cheapest paths within the cluster from the Cell to each Cell of the
+// cluster (forward) or from each Cell to the passed one, indexed by localIndex. Cells, which can't be reached, cost
+// +Inf. Like with costsToGoals, the costs of the cells the paths start on aren't included.
+func (h *HierarchicalGrid) clusterCosts(c *cluster, cell *Cell, forward bool) []float64 {
+
+	m := h.grid
+	settings := &h.settings
+	costs := make([]float64, (c.maxX-c.minX+1)*(c.maxY-c.minY+1))
+	for i := range costs {
+		costs[i] = math.Inf(1)
+	}
+	closed := make([]bool, len(costs))
+
+	open := openList{}
+	costs[h.localIndex(c, cell)] = 0
+	open.push(openItem{h.localIndex(c, cell), 0})
+
+	width := int32(c.maxX - c.minX + 1)
+	for len(open) > 0 {
+
+		item := open.pop()
+		if closed[item.cell] {
+			continue
+		}
+		closed[item.cell] = true
+		current := m.Get(c.minX+int(item.cell%width), c.minY+int(item.cell/width))
+
+		for _, offset := range neighborOffsets {
+
+			if !settings.diagonals && offset[0] != 0 && offset[1] != 0 {
+				continue
+			}
+			x, y := current.X+offset[0], current.Y+offset[1]
+			if x < c.minX || x > c.maxX || y < c.minY || y > c.maxY {
+				continue
+			}
+			neighbor := m.Get(x, y)
+			index := h.localIndex(c, neighbor)
+			if closed[index] {
+				continue
+			}
+
+			from, to := current, neighbor
+			if !forward {
+				// the search goes backwards: the neighbor has to be able to move to the current cell
+				from, to = neighbor, current
+				if !settings.assumeWalkable(neighbor) {
+					continue
+				}
+			}
+			if !m.canMove(from, to, settings) {
+				continue
+			}
+
+			cost := costs[item.cell] + settings.quantize(m.moveCost(from, to, settings))
+			if cost < costs[index] {
+				costs[index] = cost
+				open.push(openItem{index, cost})
+			}
+		}
+	}
+
+	return costs
+}
+
+// setCorridor adds the cells of the cluster to the corridor or removes them.
+func (h *HierarchicalGrid) setCorridor(c *cluster, value bool) {
+	for y := c.minY; y <= c.maxY; y++ {
+		for x := c.minX; x <= c.maxX; x++ {
+			h.corridor[y*h.grid.Width()+x] = value
+		}
+	}
+}
+
+// GetPath returns a Path from the start to the end Cell, following the movement rules of the HierarchicalGrid. If the
+// start or end isn't walkable, nil is returned; if no path can be found, the Path is empty.
+func (h *HierarchicalGrid) GetPath(start, end *Cell) *Path {
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.revision != h.grid.Revision() {
+		h.build()
+	}
+
+	m := h.grid
+	settings := h.settings
+	settings.start, settings.end = start, end
+	if !settings.endpointsWalkable() {
+		return nil
+	}
+
+	path := &Path{StepHeight: int(settings.MaxStepHeight)}
+	route, _ := h.route(start, end)
+	if route == nil {
+		return path
+	}
+
+	// each step of the route either stays within a cluster or crosses into the next one
+	path.Cells = append(path.Cells, start)
+	for i := 1; i < len(route); i++ {
+
+		from, to := route[i-1], route[i]
+		c := h.clusterOf(from)
+		if c != h.clusterOf(to) {
+			path.Cells = append(path.Cells, to)
+			continue
+		}
+
+		h.setCorridor(c, true)
+		refined := settings
+		refined.start, refined.end, refined.corridor = from, to, h.corridor
+		refined.RequireOptimal, refined.WaypointsOnly = true, false
+		segment := m.findPath(&refined, nil)
+		h.setCorridor(c, false)
+
+		if segment == nil || len(segment.Cells) == 0 {
+			return &Path{StepHeight: path.StepHeight}
+		}
+		path.Cells = append(path.Cells, segment.Cells[1:]...)
+	}
+
+	m.finishCells(path, &settings)
+	return path
+}
+
+// route returns the cells of the abstract graph the cheapest path from the start to the end passes, including the
+// start and the end, and its quantized cost without the cost of the start, or nil if there is none.
+func (h *HierarchicalGrid) route(start, end *Cell) ([]*Cell, float64) {
+
+	settings := h.settings
+	settings.start, settings.end = start, end
+	startCluster, endCluster := h.clusterOf(start), h.clusterOf(end)
+
+	// the start and the end are connected to the entrance cells of their clusters (and to each other, if they share
+	// one) for this request only
+	fromStart := h.clusterCosts(startCluster, start, true)
+	toEnd := h.clusterCosts(endCluster, end, false)
+
+	links := func(cell *Cell) []clusterLink {
+		c := h.clusterOf(cell)
+		links := c.links[cell]
+		if cell == start {
+			links = append([]clusterLink{}, links...)
+			for to := range c.links {
+				if cost := fromStart[h.localIndex(c, to)]; !math.IsInf(cost, 1) {
+					links = append(links, clusterLink{to, cost})
+				}
+			}
+			if c == endCluster && !math.IsInf(fromStart[h.localIndex(c, end)], 1) {
+				links = append(links, clusterLink{end, fromStart[h.localIndex(c, end)]})
+			}
+		} else if c == endCluster {
+			if cost := toEnd[h.localIndex(c, cell)]; !math.IsInf(cost, 1) {
+				links = append(append([]clusterLink{}, links...), clusterLink{end, cost})
+			}
+		}
+		return links
+	}
+
+	// the abstract graph is searched with AStar, using the buffers of the searches on the Grid
+	m := h.grid
+	heuristic := m.newHeuristic(&settings)
+	buffer := m.searchBuffer()
+	defer m.searchBuffers.Put(buffer)
+
+	startIndex := m.cellIndex(start)
+	buffer.reach(startIndex, 0, -1)
+	buffer.open.push(openItem{startIndex, heuristic.estimate(start)})
+
+	for len(buffer.open) > 0 {
+
+		index := buffer.open.pop().cell
+		if buffer.closed[index] == buffer.generation {
+			continue
+		}
+		buffer.closed[index] = buffer.generation
+		cell := m.cellAt(index)
+
+		if cell == end {
+			route := []*Cell{}
+			for i := index; i >= 0; i = buffer.parents[i] {
+				route = append(route, m.cellAt(i))
+			}
+			reverse(route)
+			return route, buffer.costs[index]
+		}
+
+		for _, link := range links(cell) {
+			linkIndex := m.cellIndex(link.to)
+			cost := buffer.costs[index] + link.cost
+			if buffer.closed[linkIndex] == buffer.generation ||
+				(buffer.reached[linkIndex] == buffer.generation && buffer.costs[linkIndex] <= cost) {
+				continue
+			}
+			buffer.reach(linkIndex, cost, index)
+			buffer.open.push(openItem{linkIndex, cost + heuristic.estimate(link.to)})
+		}
+	}
+
+	return nil, math.Inf(1)
+}
+
+// EstimateCost returns the cost of the path GetPath would return like SearchStats.Cost, without refining it into
+// cells, so it only searches the abstract graph and the clusters of the start and the end. It's +Inf if the start or
+// end isn't walkable or there is no path.
+func (h *HierarchicalGrid) EstimateCost(start, end *Cell) float64 {
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.revision != h.grid.Revision() {
+		h.build()
+	}
+
+	settings := h.settings
+	settings.start, settings.end = start, end
+	if !settings.endpointsWalkable() {
+		return math.Inf(1)
+	}
+
+	_, cost := h.route(start, end)
+	return settings.unquantize(cost + settings.quantize(h.grid.cellCost(start, &settings)))
+}
+
+// estimateClusterSize is the cluster size of the HierarchicalGrid used by Grid.EstimateTravelCost.
+const estimateClusterSize = 16
+
+// EstimateTravelCost quickly estimates the cost of traveling from a to b with the default settings (see
+// NewDefaultPathSettings) without producing a path, e.g. for AI decisions like "is the mine close enough?". It answers
+// from a HierarchicalGrid, which is built on the first call and rebuilt after changes of the Grid, so the estimate is
+// the cost of its path, slightly above the cost of the cheapest one. It's +Inf if a or b isn't walkable or there is no
+// path. For other settings, use HierarchicalGrid.EstimateCost.
+func (m *Grid) EstimateTravelCost(a, b *Cell) float64 {
+
+	m.estimatesOnce.Do(func() {
+		m.estimates = m.NewHierarchicalGrid(estimateClusterSize, *NewDefaultPathSettings(nil, nil))
+	})
+	return m.estimates.EstimateCost(a, b)
+}
+
+// Clusters returns the amount of clusters and the amount of entrance cells of the abstract graph.
+func (h *HierarchicalGrid) Clusters() (clusters, entrances int) {
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, c := range h.clusters {
+		entrances += len(c.links)
+	}
+	return len(h.clusters), entrances
+}
@@ -0,0 +1,37 @@
+package paths
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestHierarchicalReachability checks, that a HierarchicalGrid finds a path on random grids with walls and heights
+// exactly if UniformCost finds one, with and without diagonal movement.
+func TestHierarchicalReachability(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+	for run := 0; run < 200; run++ {
+
+		grid := NewGrid(10, 10)
+		for _, cell := range grid.AllCells() {
+			cell.Walkable = random.Intn(4) > 0
+			cell.Cost = 1 + float64(random.Intn(3))
+			cell.HeightLevel = random.Intn(3)
+		}
+		start, end := grid.Get(random.Intn(10), random.Intn(10)), grid.Get(random.Intn(10), random.Intn(10))
+		start.Walkable, end.Walkable = true, true
+
+		settings := *NewDefaultPathSettings(start, end)
+		settings.diagonals = run%2 == 0
+		settings.MaxStepHeight, settings.MaxDropHeight = 1, 1
+		settings.Algorithm = UniformCost
+		cheapest := grid.GetPathFromSettings(settings)
+
+		path := grid.NewHierarchicalGrid(4, settings).GetPath(start, end)
+		found, expected := path != nil && len(path.Cells) > 0, cheapest != nil && len(cheapest.Cells) > 0
+		if found != expected {
+			t.Fatalf("run %d (diagonals: %v): found a path: %v, UniformCost found one: %v", run, settings.diagonals,
+				found, expected)
+		}
+	}
+}
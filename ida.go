@@ -0,0 +1,175 @@
+package paths
+
+import "math"
+
+// idaFrame is a Cell on the path of an IterativeDeepening search with the cost of the path up to it and the index of
+// its next neighbor to check in neighborOffsets.
+type idaFrame struct {
+	cell *Cell
+	cost float64
+	next int
+}
+
+// iterativeDeepening runs an IterativeDeepening search and writes the resulting path into the Path. Returns if a path
+// has been found.
+func (m *Grid) iterativeDeepening(path *Path, settings *PathSettings, observer *searchObserver, stats *SearchStats) bool {
+
+	// without an end to reach, every path below the ever growing threshold would be tried before giving up
+	if !m.goalReachable(settings) {
+		return false
+	}
+
+	heuristic := m.newHeuristic(settings)
+	start := settings.start
+	startCost := settings.quantize(m.cellCost(start, settings))
+	threshold := startCost + heuristic.estimate(start)
+
+	// the only memory needed is the current path
+	stack := []idaFrame{}
+	for {
+
+		stack = append(stack[:0], idaFrame{start, startCost, 0})
+		nextThreshold := math.Inf(1)
+
+		for len(stack) > 0 {
+
+			top := &stack[len(stack)-1]
+			if top.next == 0 {
+				// the cells beyond the threshold are left for the next iteration
+				if estimate := top.cost + heuristic.estimate(top.cell); estimate > threshold {
+					nextThreshold = math.Min(nextThreshold, estimate)
+					stack = stack[:len(stack)-1]
+					continue
+				}
+				if m.isGoal(top.cell, settings) {
+					m.finishDeepening(path, stack, settings, observer)
+					stats.Cost = settings.unquantize(top.cost)
+					return true
+				}
+				stats.Expanded++
+				if observer != nil && observer.expanded != nil {
+					observer.expanded(top.cell)
+				}
+			}
+
+			if top.next >= len(neighborOffsets) {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			offset := neighborOffsets[top.next]
+			top.next++
+
+			if !settings.diagonals && offset[0] != 0 && offset[1] != 0 {
+				continue
+			}
+			cell := top.cell
+			neighbor := m.Get(cell.X+offset[0], cell.Y+offset[1])
+			if neighbor == nil || !m.canMove(cell, neighbor, settings) {
+				continue
+			}
+			if settings.corridor != nil && !settings.corridor[m.cellIndex(neighbor)] {
+				continue
+			}
+			if onStack(stack, neighbor) {
+				continue
+			}
+
+			stats.Pushed++
+			stack = append(stack, idaFrame{neighbor, top.cost + settings.quantize(m.moveCost(cell, neighbor, settings)), 0})
+		}
+
+		// if no cell has been left for the next iteration, the whole reachable area has been searched
+		if math.IsInf(nextThreshold, 1) {
+			return false
+		}
+		threshold = nextThreshold
+	}
+}
+
+// goalReachable returns if the end (or a Goal) of the search can be reached from its start. The reached cells are
+// marked in a bitset, which is swept forwards and backwards, spreading the marks to the neighbors, until no more cells
+// are reached. So it needs one bit per Cell and no other memory, but a sweep over the whole Grid per turn of the
+// winding paths.
+func (m *Grid) goalReachable(settings *PathSettings) bool {
+
+	size := int32(m.Width() * m.Height())
+	reached := make([]uint64, (size+63)/64)
+	isReached := func(index int32) bool {
+		return reached[index/64]&(uint64(1)<<uint(index%64)) != 0
+	}
+
+	if m.isGoal(settings.start, settings) {
+		return true
+	}
+	start := m.cellIndex(settings.start)
+	reached[start/64] |= uint64(1) << uint(start%64)
+
+	for changed, step := true, int32(1); changed; step = -step {
+
+		changed = false
+		index, end := int32(0), size
+		if step < 0 {
+			index, end = size-1, -1
+		}
+		for ; index != end; index += step {
+
+			if !isReached(index) {
+				continue
+			}
+			cell := m.cellAt(index)
+			for _, offset := range neighborOffsets {
+				if !settings.diagonals && offset[0] != 0 && offset[1] != 0 {
+					continue
+				}
+				neighbor := m.Get(cell.X+offset[0], cell.Y+offset[1])
+				if neighbor == nil || !m.canMove(cell, neighbor, settings) {
+					continue
+				}
+				neighborIndex := m.cellIndex(neighbor)
+				if isReached(neighborIndex) || settings.corridor != nil && !settings.corridor[neighborIndex] {
+					continue
+				}
+				if m.isGoal(neighbor, settings) {
+					return true
+				}
+				reached[neighborIndex/64] |= uint64(1) << uint(neighborIndex%64)
+				changed = true
+			}
+		}
+	}
+
+	return false
+}
+
+// onStack returns if the Cell is on the current path of an IterativeDeepening search, which mustn't visit it twice.
+func onStack(stack []idaFrame, cell *Cell) bool {
+	for i := range stack {
+		if stack[i].cell == cell {
+			return true
+		}
+	}
+	return false
+}
+
+// finishDeepening writes the path on the stack into the Path. Observers waiting for the path in a searchBuffer get
+// it there, which allocates the buffer.
+func (m *Grid) finishDeepening(path *Path, stack []idaFrame, settings *PathSettings, observer *searchObserver) {
+
+	if observer != nil && observer.reached != nil {
+		buffer := m.searchBuffer()
+		defer m.searchBuffers.Put(buffer)
+		parent := int32(-1)
+		for _, frame := range stack {
+			index := m.cellIndex(frame.cell)
+			buffer.reach(index, frame.cost, parent)
+			parent = index
+		}
+		observer.reached(buffer, parent)
+		return
+	}
+
+	for _, frame := range stack {
+		path.Cells = append(path.Cells, frame.cell)
+	}
+	m.finishCells(path, settings)
+}
@@ -0,0 +1,214 @@
+package paths
+
+import (
+	"math"
+	"sort"
+)
+
+// An InspectedCell is the state of a Cell in an InspectableSearch.
+type InspectedCell struct {
+	Cell *Cell
+	// Parent is the Cell the cheapest way found so far comes from. It's nil for the start.
+	Parent *Cell
+	// Cost is the cost of the cheapest way found so far from the start to the Cell like SearchStats.Cost.
+	Cost float64
+	// Estimate is the estimated remaining cost to the end Cell, which is 0 for UniformCost and searches for a Goal.
+	Estimate float64
+	// Priority is the sum of Cost and Estimate, by which the open cells are ordered.
+	Priority float64
+}
+
+// An InspectableSearch runs a search one expansion at a time, so its progress can be shown between the steps, e.g. in
+// teaching tools or a debugger: each Step expands the open Cell with the lowest priority, and Open and Closed return the
+// open and the closed set at that moment. It always finds the cheapest path like with PathSettings.RequireOptimal.
+// AStar and UniformCost are stepped as they are; all other algorithms are shown as AStar. WaypointsOnly is respected
+// for the resulting Path. Changes of the Grid during the search aren't noticed. An InspectableSearch must not be used
+// by multiple goroutines at once.
+type InspectableSearch struct {
+	grid      *Grid
+	settings  PathSettings
+	heuristic heuristic
+	// costs and parents contain the quantized cost of the cheapest way found to each cell and the cell it comes from,
+	// indexed like the cells in a search. opened marks the cells in the open set, closed the expanded ones.
+	costs          []float64
+	parents        []int32
+	opened, closed []bool
+	open           openList
+	// expanded contains the indices of the closed cells in the order they were expanded in.
+	expanded []int32
+	path     *Path
+	done     bool
+}
+
+// NewInspectableSearch returns an InspectableSearch for a path from the start to the end Cell (or Goal) of the
+// settings, whose open set only contains the start. No Cell is expanded until Step is called.
+func (m *Grid) NewInspectableSearch(settings PathSettings) *InspectableSearch {
+
+	settings.RequireOptimal = true
+	if settings.Algorithm != UniformCost {
+		settings.Algorithm = AStar
+	}
+
+	size := m.Width() * m.Height()
+	s := &InspectableSearch{
+		grid:      m,
+		settings:  settings,
+		heuristic: m.newHeuristic(&settings),
+		costs:     make([]float64, size),
+		parents:   make([]int32, size),
+		opened:    make([]bool, size),
+		closed:    make([]bool, size),
+	}
+	for i := range s.costs {
+		s.costs[i] = math.Inf(1)
+		s.parents[i] = -1
+	}
+
+	if !settings.endpointsWalkable() {
+		s.done = true
+		return s
+	}
+
+	start := m.cellIndex(settings.start)
+	s.costs[start] = settings.quantize(m.cellCost(settings.start, &settings))
+	s.push(start)
+	return s
+}
+
+// Step expands the open Cell with the lowest priority and returns it, or returns nil if the search is Done. If the Cell
+// is the end Cell (or fulfills the Goal), the search is Done with the Path to it, otherwise its neighbors are added to
+// the open set or updated, if a cheaper way to them has been found. If the open set runs empty, the search is Done
+// without a path.
+func (s *InspectableSearch) Step() *Cell {
+
+	if s.done {
+		return nil
+	}
+
+	// the list may contain outdated items of cells, which have been reached cheaper or expanded since
+	for len(s.open) > 0 && (!s.opened[s.open[0].cell] || s.open[0].cost != s.priority(s.open[0].cell)) {
+		s.open.pop()
+	}
+	if len(s.open) == 0 {
+		s.done = true
+		return nil
+	}
+
+	m, settings := s.grid, &s.settings
+	index := s.open.pop().cell
+	s.opened[index] = false
+	s.closed[index] = true
+	s.expanded = append(s.expanded, index)
+	cell := m.cellAt(index)
+
+	if m.isGoal(cell, settings) {
+		s.path = &Path{StepHeight: int(settings.MaxStepHeight)}
+		for i := index; i >= 0; i = s.parents[i] {
+			s.path.Cells = append(s.path.Cells, m.cellAt(i))
+		}
+		reverse(s.path.Cells)
+		m.finishCells(s.path, settings)
+		s.done = true
+		return cell
+	}
+
+	for _, neighbor := range m.neighbors(cell, settings.diagonals) {
+
+		neighborIndex := m.cellIndex(neighbor)
+		if s.closed[neighborIndex] || !m.canMove(cell, neighbor, settings) {
+			continue
+		}
+		cost := s.costs[index] + settings.quantize(m.moveCost(cell, neighbor, settings))
+		if cost >= s.costs[neighborIndex] {
+			continue
+		}
+		s.costs[neighborIndex] = cost
+		s.parents[neighborIndex] = index
+		s.push(neighborIndex)
+	}
+
+	return cell
+}
+
+// Done returns if the search has finished, either with the cheapest path or without one.
+func (s *InspectableSearch) Done() bool {
+	return s.done
+}
+
+// Path returns the cheapest path once the search is Done. If it hasn't finished yet or there is no path, nil is
+// returned.
+func (s *InspectableSearch) Path() *Path {
+
+	if s.path == nil {
+		return nil
+	}
+	return copyPath(s.path)
+}
+
+// Open returns the open set: the cells waiting to be expanded, ordered by their priority like Step expands them.
+func (s *InspectableSearch) Open() []InspectedCell {
+
+	open := make([]InspectedCell, 0, len(s.open))
+	seen := make(map[int32]bool, len(s.open))
+	for _, item := range s.open {
+		if s.opened[item.cell] && !seen[item.cell] {
+			seen[item.cell] = true
+			open = append(open, s.inspect(item.cell))
+		}
+	}
+	sort.SliceStable(open, func(i, j int) bool {
+		return open[i].Priority < open[j].Priority
+	})
+	return open
+}
+
+// Closed returns the closed set: the expanded cells in the order they were expanded in.
+func (s *InspectableSearch) Closed() []InspectedCell {
+
+	closed := make([]InspectedCell, len(s.expanded))
+	for i, index := range s.expanded {
+		closed[i] = s.inspect(index)
+	}
+	return closed
+}
+
+// Inspect returns the state of the Cell and if it has been reached yet, i.e. if it's in the open or the closed set.
+func (s *InspectableSearch) Inspect(cell *Cell) (InspectedCell, bool) {
+
+	if s.grid.Get(cell.X, cell.Y) != cell {
+		return InspectedCell{}, false
+	}
+	index := s.grid.cellIndex(cell)
+	if !s.opened[index] && !s.closed[index] {
+		return InspectedCell{}, false
+	}
+	return s.inspect(index), true
+}
+
+// inspect returns the state of the reached Cell with the index.
+func (s *InspectableSearch) inspect(index int32) InspectedCell {
+
+	m := s.grid
+	cell := m.cellAt(index)
+	inspected := InspectedCell{
+		Cell:     cell,
+		Cost:     s.settings.unquantize(s.costs[index]),
+		Estimate: s.settings.unquantize(s.heuristic.estimate(cell)),
+		Priority: s.settings.unquantize(s.priority(index)),
+	}
+	if parent := s.parents[index]; parent >= 0 {
+		inspected.Parent = m.cellAt(parent)
+	}
+	return inspected
+}
+
+// priority returns the priority of the Cell with the index in the open set.
+func (s *InspectableSearch) priority(index int32) float64 {
+	return s.costs[index] + s.heuristic.estimate(s.grid.cellAt(index))
+}
+
+// push adds the Cell with the index to the open set with its current priority.
+func (s *InspectableSearch) push(index int32) {
+	s.opened[index] = true
+	s.open.push(openItem{index, s.priority(index)})
+}
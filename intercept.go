@@ -0,0 +1,37 @@
+package paths
+
+// PlanIntercept searches a path for the pursuer to cut off a target, which follows the passed Path, e.g. for guards
+// catching thieves or predators hunting prey. Instead of chasing the tail of the target, the earliest Cell on the
+// remaining path of the target (starting at its CurrentIndex) is chosen, which the pursuer can reach before or at the
+// same time as the target. The time needed for a move is its cost divided by the speed; the move costs of both are
+// computed with the settings, whose start and end are ignored. If the pursuer can't reach any Cell of the target path
+// in time, nil is returned.
+func (m *Grid) PlanIntercept(pursuer *Cell, targetPath *Path, pursuerSpeed, targetSpeed float64, settings PathSettings) *Path {
+
+	if !pursuer.Walkable || pursuerSpeed <= 0 || targetSpeed <= 0 || targetPath == nil {
+		return nil
+	}
+
+	pursuerCosts := m.costsFromStart(pursuer, &settings)
+
+	targetCost := 0.0
+	for i := targetPath.CurrentIndex; i >= 0 && i < len(targetPath.Cells); i++ {
+
+		cell := targetPath.Cells[i]
+		if i > targetPath.CurrentIndex {
+			targetCost += m.moveCost(targetPath.Cells[i-1], cell, &settings)
+		}
+
+		pursuerCost, reachable := pursuerCosts[cell]
+		if !reachable || pursuerCost/pursuerSpeed > targetCost/targetSpeed {
+			continue
+		}
+
+		settings.start, settings.end = pursuer, cell
+		settings.Goal = nil
+		settings.RequireOptimal = true
+		return m.findPath(&settings, nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,322 @@
+package paths
+
+// Jump point search (see JumpPointSearch) skips the cells of open, uniform areas: from each checked cell, the search
+// moves on in a straight line until it reaches a cell where the path may have to turn, a jump point, and only this cell
+// is added to the cells to check. A cell is uniform, if all of its neighbors are either regular (walkable, as high as
+// the cell and as expensive to enter as the cell itself) or walls (not walkable or outside of the Grid). Cells with
+// other neighbors, e.g. slopes, expensive terrain or occupied cells, are treated as jump points and checked like in
+// AStar, so all movement rules keep working.
+
+// jumpsAllowed returns if the search can use jump point search with the passed settings. It's only supported for the
+// default diagonal movement, as the pruning of the neighbors relies on it, and for searches ending at the end Cell, as
+// the jumps are stored for all searches with the same settings.
+func (m *Grid) jumpsAllowed(settings *PathSettings) bool {
+	return settings.Algorithm == JumpPointSearch && settings.diagonals && settings.wallBlocksDiagonals &&
+		settings.Directions == 0 && settings.corridor == nil && settings.end != nil && settings.Goal == nil &&
+		settings.StopWithinRange <= 0
+}
+
+// prepareJumps prepares the buffer for a jump point search with the settings. What the previous search has found out
+// about the cells is kept, if it used the same settings on the same revision of the Grid, so repeated searches (e.g.
+// once per frame) don't need to check the cells again. This isn't possible with Knowledge or MaxOccupancy, whose
+// changes don't change the revision.
+func (m *Grid) prepareJumps(settings *PathSettings, buffer *searchBuffer) {
+
+	key := ""
+	if settings.Knowledge == nil && settings.MaxOccupancy == 0 {
+		// only the settings used to check the cells matter
+		keySettings := *settings
+		keySettings.start, keySettings.end, keySettings.Goal, keySettings.OnSearchComplete = nil, nil, nil, nil
+		key = requestKey(m, &keySettings)
+	}
+
+	if len(buffer.jumpCells) != len(buffer.costs) {
+		buffer.jumpCells = make([]jumpCell, len(buffer.costs))
+		buffer.jumpGeneration = 0
+	} else if key != "" && key == buffer.jumpKey {
+		return
+	}
+
+	buffer.jumpKey = key
+	buffer.jumpGeneration++
+	if buffer.jumpGeneration == 0 {
+		// after an overflow, old stamps could be mistaken as current ones
+		for i := range buffer.jumpCells {
+			buffer.jumpCells[i] = jumpCell{}
+		}
+		buffer.jumpGeneration = 1
+	}
+}
+
+// neighborKind is the classification of a neighbor of a cell for jump point search.
+type neighborKind uint8
+
+const (
+	regularNeighbor neighborKind = iota
+	wallNeighbor
+	irregularNeighbor
+)
+
+// jumpCell is what jump point search knows about a cell during a search.
+type jumpCell struct {
+	// terrainGeneration and uniformGeneration are the jump generations (see searchBuffer.jumpGeneration), in which
+	// the terrain (kind and cost) and uniform have been determined.
+	terrainGeneration, uniformGeneration uint32
+	// kind is wallNeighbor for cells which aren't walkable, irregularNeighbor for cells blocked for other reasons and
+	// regularNeighbor for all others.
+	kind neighborKind
+	// cost and diagonal are the costs of a straight and a diagonal move onto the cell.
+	cost, diagonal float64
+	uniform        bool
+	// jumps contains the result of straightJump for each of the straight directions (in the order of neighborOffsets):
+	// the amount of steps plus one, negative if there's no jump point. Zero means unknown.
+	jumps [4]int32
+	// open contains a bit for each regular neighbor of a uniform cell, in the order of neighborOffsets.
+	open uint8
+}
+
+// terrain returns what the search knows about the Cell, with its kind and cost determined.
+func (m *Grid) terrain(cell *Cell, settings *PathSettings, buffer *searchBuffer) *jumpCell {
+
+	info := &buffer.jumpCells[m.cellIndex(cell)]
+	if info.terrainGeneration == buffer.jumpGeneration {
+		return info
+	}
+	info.terrainGeneration = buffer.jumpGeneration
+	info.jumps = [4]int32{}
+
+	// a step from the cell onto itself only checks the rules, which depend on the cell moved to
+	switch {
+	case !settings.assumeWalkable(cell):
+		info.kind = wallNeighbor
+	case checkStep(m, m, cell, cell, settings) != NotBlocked || cell.EnterCost > 0 || cell.ExitCost > 0 || m.hasEdges(cell):
+		// the costs of moves through cells with an EnterCost, ExitCost or edge costs depend on the direction
+		info.kind = irregularNeighbor
+	default:
+		info.kind = regularNeighbor
+		info.cost = m.moveCost(cell, cell, settings)
+		info.diagonal = m.moveCost(&Cell{X: cell.X + 1, Y: cell.Y + 1}, cell, settings)
+	}
+
+	return info
+}
+
+// classify returns the kind of the neighbor of the Cell at the offset: walls are not walkable or outside of the Grid,
+// regular neighbors are as high as the Cell and as expensive to enter and all others are irregular.
+func (m *Grid) classify(cell *Cell, dx, dy int, settings *PathSettings, buffer *searchBuffer) neighborKind {
+
+	neighbor := m.Get(cell.X+dx, cell.Y+dy)
+	if neighbor == nil {
+		return wallNeighbor
+	}
+
+	info := m.terrain(neighbor, settings, buffer)
+	if info.kind != regularNeighbor {
+		return info.kind
+	}
+	if neighbor.TotalHeight() != cell.TotalHeight() || info.cost != m.terrain(cell, settings, buffer).cost {
+		return irregularNeighbor
+	}
+	return regularNeighbor
+}
+
+// uniform returns if all neighbors of the Cell are regular or walls, so it can be skipped by jumps. Additionally, a
+// diagonal move mustn't cost more than two straight ones; otherwise the cheapest paths avoid diagonal moves, which
+// jump point search relies on.
+func (m *Grid) uniform(cell *Cell, settings *PathSettings, buffer *searchBuffer) bool {
+
+	info := &buffer.jumpCells[m.cellIndex(cell)]
+	if info.uniformGeneration == buffer.jumpGeneration {
+		return info.uniform
+	}
+	info.uniformGeneration = buffer.jumpGeneration
+	info.uniform = false
+	info.open = 0
+
+	for i, offset := range neighborOffsets {
+		switch m.classify(cell, offset[0], offset[1], settings, buffer) {
+		case irregularNeighbor:
+			return false
+		case regularNeighbor:
+			info.open |= 1 << uint(i)
+		}
+	}
+
+	terrain := m.terrain(cell, settings, buffer)
+	info.uniform = terrain.diagonal <= 2*terrain.cost
+	return info.uniform
+}
+
+// open returns if the neighbor of the uniform Cell at the offset is regular.
+func (m *Grid) open(cell *Cell, dx, dy int, buffer *searchBuffer) bool {
+	return buffer.jumpCells[m.cellIndex(cell)].open&neighborBits[dy+1][dx+1] != 0
+}
+
+// neighborBits contains the bit of each neighbor in jumpCell.open, indexed by the y and x offset plus one.
+var neighborBits = [3][3]uint8{
+	{1 << 4, 1 << 2, 1 << 5},
+	{1 << 0, 0, 1 << 1},
+	{1 << 6, 1 << 3, 1 << 7},
+}
+
+// pruned returns if the search can skip the neighbor at the offset of the uniform Cell, which has been reached moving
+// in the direction (px, py): only the natural neighbors, which continue the move, and the forced neighbors, which
+// can't be reached around a wall in another way, are kept.
+func (m *Grid) pruned(cell *Cell, px, py, dx, dy int, settings *PathSettings, buffer *searchBuffer) bool {
+
+	open := func(x, y int) bool {
+		return m.open(cell, x, y, buffer)
+	}
+
+	if px != 0 && py != 0 {
+		switch {
+		case dx == px && dy == py:
+			return !open(0, py) && !open(px, 0)
+		case dx == 0 && dy == py:
+			return false
+		case dx == px && dy == 0:
+			return false
+		case dx == -px && dy == py:
+			return open(-px, 0) || !open(0, py)
+		case dx == px && dy == -py:
+			return open(0, -py) || !open(px, 0)
+		}
+		return true
+	}
+
+	// moving straight, the perpendicular direction is (qx, qy)
+	qx, qy := py, px
+	switch {
+	case dx == px && dy == py:
+		return false
+	case dx == px+qx && dy == py+qy:
+		return !open(px, py) || open(qx, qy)
+	case dx == px-qx && dy == py-qy:
+		return !open(px, py) || open(-qx, -qy)
+	}
+	return true
+}
+
+// jump moves on from the Cell, which has been reached moving in the direction (dx, dy) with the passed cost, and
+// returns the next jump point and the cost of reaching it, or nil if the moves lead into a dead end. If the Cell itself
+// is a jump point, it's returned.
+func (m *Grid) jump(cell *Cell, dx, dy int, cost float64, settings *PathSettings, buffer *searchBuffer) (*Cell, float64) {
+
+	// all cells skipped by a jump are as expensive as the cell, as they are regular neighbors of each other
+	if dx == 0 || dy == 0 {
+		steps, found := m.straightJump(cell, dx, dy, settings, buffer)
+		if !found {
+			return nil, 0
+		}
+		moveCost := settings.quantize(m.terrain(cell, settings, buffer).cost)
+		for i := 0; i < steps; i++ {
+			cost += moveCost
+		}
+		return m.Get(cell.X+steps*dx, cell.Y+steps*dy), cost
+	}
+
+	moveCost := settings.quantize(m.terrain(cell, settings, buffer).diagonal)
+	for {
+
+		if cell == settings.end || !m.uniform(cell, settings, buffer) {
+			return cell, cost
+		}
+
+		open := func(x, y int) bool {
+			return m.open(cell, x, y, buffer)
+		}
+
+		if (open(-dx, dy) && !open(-dx, 0)) || (open(dx, -dy) && !open(0, -dy)) {
+			return cell, cost
+		}
+		// a straight move leading to a jump point makes this cell a jump point, as the path may turn here
+		if open(dx, 0) {
+			if _, found := m.straightJump(m.Get(cell.X+dx, cell.Y), dx, 0, settings, buffer); found {
+				return cell, cost
+			}
+		}
+		if open(0, dy) {
+			if _, found := m.straightJump(m.Get(cell.X, cell.Y+dy), 0, dy, settings, buffer); found {
+				return cell, cost
+			}
+		}
+
+		// a diagonal move is blocked, if both cells it moves past are walls
+		if !open(dx, dy) || (!open(dx, 0) && !open(0, dy)) {
+			return nil, 0
+		}
+		cell = m.Get(cell.X+dx, cell.Y+dy)
+		cost += moveCost
+	}
+}
+
+// straightJump moves on from the Cell in the straight direction (dx, dy) and returns the amount of steps to the next
+// jump point (0 if the Cell is one) and if there is one at all. The jumps found are stored in the buffer, so each
+// line of cells is only checked once.
+func (m *Grid) straightJump(cell *Cell, dx, dy int, settings *PathSettings, buffer *searchBuffer) (int, bool) {
+
+	direction := straightDirection(dx, dy)
+
+	// the end of the search is a jump point as well; it isn't stored, as it differs between the searches
+	endSteps := -1
+	if end := settings.end; (dx == 0 && end.X == cell.X && sign(end.Y-cell.Y) != -dy) ||
+		(dy == 0 && end.Y == cell.Y && sign(end.X-cell.X) != -dx) {
+		endSteps = abs(end.X-cell.X) + abs(end.Y-cell.Y)
+	}
+
+	steps, found := 0, false
+	for current := cell; ; steps++ {
+
+		if jumps := m.terrain(current, settings, buffer).jumps[direction]; jumps != 0 {
+			// the rest of the line is known already
+			if jumps > 0 {
+				steps, found = steps+int(jumps)-1, true
+			} else {
+				steps += int(-jumps) - 1
+			}
+			break
+		}
+
+		if !m.uniform(current, settings, buffer) {
+			found = true
+			break
+		}
+		qx, qy := dy, dx
+		if (m.open(current, dx+qx, dy+qy, buffer) && !m.open(current, qx, qy, buffer)) ||
+			(m.open(current, dx-qx, dy-qy, buffer) && !m.open(current, -qx, -qy, buffer)) {
+			found = true
+			break
+		}
+		if !m.open(current, dx, dy, buffer) {
+			break
+		}
+		current = m.Get(current.X+dx, current.Y+dy)
+	}
+
+	// the result is stored for all cells of the line, the first one being the farthest from the jump point
+	for i := 0; i < steps; i++ {
+		jumps := int32(steps - i + 1)
+		if !found {
+			jumps = -jumps
+		}
+		buffer.jumpCells[m.cellIndex(cell)+int32(i*(dy*m.Width()+dx))].jumps[direction] = jumps
+	}
+
+	if endSteps >= 0 && endSteps <= steps {
+		return endSteps, true
+	}
+	return steps, found
+}
+
+// straightDirection returns the index of the straight direction in neighborOffsets.
+func straightDirection(dx, dy int) int {
+	switch {
+	case dx < 0:
+		return 0
+	case dx > 0:
+		return 1
+	case dy < 0:
+		return 2
+	}
+	return 3
+}
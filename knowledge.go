@@ -0,0 +1,167 @@
+package paths
+
+import "math"
+
+// A KnowledgeLayer is what a single agent knows about a Grid, e.g. for fog of war: which cells it has seen and how they
+// looked like back then. Searches with the layer set in PathSettings.Knowledge use the remembered walkability and cost
+// instead of the actual ones, so agents don't know about closed doors they haven't seen yet. Cells the agent hasn't
+// seen are unknown; they are handled as defined by PathSettings.UnknownCells.
+type KnowledgeLayer struct {
+	// UnknownCost is the cost assumed for unknown cells with OptimisticUnknown. Higher costs make agents prefer known
+	// routes over exploring.
+	UnknownCost float64
+	grid        *Grid
+	remembered  map[*Cell]rememberedCell
+	// changes is increased on every change of the remembered cells. minCost is the lowest cost of a Cell in a search
+	// with the layer, which is valid as long as minCostKnown is set and minCostKey is current, see
+	// Grid.settingsMinCost.
+	changes      uint64
+	minCost      float64
+	minCostKey   knowledgeMinCostKey
+	minCostKnown bool
+}
+
+// rememberedCell is the state of a cell, when it has been seen the last time.
+type rememberedCell struct {
+	walkable bool
+	cost     float64
+}
+
+// NewKnowledgeLayer returns a KnowledgeLayer for the Grid, in which no Cell is known yet. UnknownCost is 1.
+func NewKnowledgeLayer(grid *Grid) *KnowledgeLayer {
+	return &KnowledgeLayer{UnknownCost: 1, grid: grid, remembered: make(map[*Cell]rememberedCell)}
+}
+
+// Reveal marks the cells as known and remembers their current walkability and cost. Call it with the cells in the field
+// of view of the agent whenever it changes.
+func (k *KnowledgeLayer) Reveal(cells ...*Cell) {
+	for _, cell := range cells {
+		if cell != nil {
+			k.remembered[cell] = rememberedCell{walkable: cell.Walkable, cost: cell.Cost}
+		}
+	}
+	k.changes++
+}
+
+// RevealRadius reveals all cells within the radius around the center Cell, see Reveal.
+func (k *KnowledgeLayer) RevealRadius(center *Cell, radius float64) {
+
+	r := int(math.Ceil(radius))
+	for y := center.Y - r; y <= center.Y+r; y++ {
+		for x := center.X - r; x <= center.X+r; x++ {
+			if math.Hypot(float64(x-center.X), float64(y-center.Y)) <= radius {
+				k.Reveal(k.grid.Get(x, y))
+			}
+		}
+	}
+}
+
+// Forget marks the cells as unknown again.
+func (k *KnowledgeLayer) Forget(cells ...*Cell) {
+	for _, cell := range cells {
+		delete(k.remembered, cell)
+	}
+	k.changes++
+}
+
+// Known returns if the Cell has been revealed.
+func (k *KnowledgeLayer) Known(cell *Cell) bool {
+	_, known := k.remembered[cell]
+	return known
+}
+
+// Remembered returns the walkability and cost of the Cell, when it has been seen the last time. If the Cell is unknown,
+// known is false.
+func (k *KnowledgeLayer) Remembered(cell *Cell) (walkable bool, cost float64, known bool) {
+	remembered, known := k.remembered[cell]
+	return remembered.walkable, remembered.cost, known
+}
+
+// KnownCells returns all known cells.
+func (k *KnowledgeLayer) KnownCells() []*Cell {
+
+	cells := []*Cell{}
+	for _, cell := range k.grid.AllCells() {
+		if k.Known(cell) {
+			cells = append(cells, cell)
+		}
+	}
+
+	return cells
+}
+
+// isUnknown returns if the Cell is unknown in a search with the passed settings: either it's marked as Unknown or it
+// hasn't been revealed in the KnowledgeLayer of the settings.
+func (settings *PathSettings) isUnknown(cell *Cell) bool {
+	if settings.Knowledge != nil {
+		return !settings.Knowledge.Known(cell)
+	}
+	return cell.Unknown
+}
+
+// assumeWalkable returns if the Cell is walkable as far as a search with the passed settings knows. Without a
+// KnowledgeLayer, it's just Cell.Walkable.
+func (settings *PathSettings) assumeWalkable(cell *Cell) bool {
+
+	if settings.Knowledge == nil {
+		return cell.Walkable
+	}
+
+	walkable, _, known := settings.Knowledge.Remembered(cell)
+	if !known {
+		// unknown cells are expected to be walkable; PessimisticUnknown is checked separately
+		return true
+	}
+	return walkable
+}
+
+// assumedCost returns the cost of the Cell as far as a search with the passed settings knows.
+func (settings *PathSettings) assumedCost(cell *Cell) float64 {
+
+	if settings.Knowledge == nil {
+		return cell.Cost
+	}
+
+	_, cost, known := settings.Knowledge.Remembered(cell)
+	if !known {
+		return settings.Knowledge.UnknownCost
+	}
+	return cost
+}
+
+// NearestFrontier searches the nearest frontier of the explored area, which is the standard target of auto-explore
+// features: a known, walkable Cell next to an unknown one. The search starts at the passed Cell and only walks on known
+// cells; the movement rules are taken from the settings, their start and end are ignored. The returned Path leads to
+// the frontier, whose Cell is the last one of the Path. If the reachable area has been explored completely, nil is
+// returned.
+func (m *Grid) NearestFrontier(from *Cell, knowledge *KnowledgeLayer, settings PathSettings) *Path {
+
+	settings.start, settings.end = from, nil
+	settings.Knowledge = knowledge
+	settings.UnknownCells = PessimisticUnknown
+	settings.RequireOptimal = true
+	settings.Goal = func(cell *Cell) bool {
+		return m.isFrontier(cell, knowledge, settings.diagonals)
+	}
+
+	path := &Path{}
+	if !m.search(path, &settings, nil) || path.Partial {
+		return nil
+	}
+	return path
+}
+
+// isFrontier returns if the Cell is known and walkable and at least one of its neighbors is unknown.
+func (m *Grid) isFrontier(cell *Cell, knowledge *KnowledgeLayer, diagonals bool) bool {
+
+	if walkable, _, known := knowledge.Remembered(cell); !known || !walkable {
+		return false
+	}
+
+	for _, neighbor := range m.neighbors(cell, diagonals) {
+		if !knowledge.Known(neighbor) {
+			return true
+		}
+	}
+	return false
+}
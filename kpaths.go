@@ -0,0 +1,186 @@
+package paths
+
+import "math"
+
+// A kPath is a path found by GetKPaths with the indices of its cells and its quantized cost.
+type kPath struct {
+	cells []int32
+	cost  float64
+}
+
+// GetKPaths returns up to k distinct paths from the start to the dest Cell ordered by their cost, cheapest first, e.g.
+// to show alternative routes to the player (Yen's algorithm). The paths don't visit a Cell twice; each one differs from
+// the cheaper ones in at least one move, so alternatives may share most of their cells. Finding each path takes a
+// search per Cell of the path before, so it's meant for moderate distances.
+//
+// The paths move between neighboring cells like with UniformCost, regardless of the Algorithm of the settings; their
+// start, end, Goal and StopWithinRange are ignored. If the start or dest isn't walkable, nil is returned; if there is
+// no path or k is zero or less, the slice is empty.
+func (m *Grid) GetKPaths(start, dest *Cell, k int, settings PathSettings) []*Path {
+
+	settings.start, settings.end = start, dest
+	settings.Goal, settings.StopWithinRange = nil, 0
+	if !settings.endpointsWalkable() {
+		return nil
+	}
+	if k <= 0 {
+		return []*Path{}
+	}
+
+	size := m.Width() * m.Height()
+	blockedCells := make([]bool, size)
+	blockedMoves := make(map[[2]int32]bool)
+
+	found := []kPath{}
+	startCost := settings.quantize(m.cellCost(start, &settings))
+	if first, exists := m.kPathRoute(&settings, m.cellIndex(start), startCost, blockedCells, blockedMoves); exists {
+		found = append(found, first)
+	}
+	candidates := []kPath{}
+
+	for len(found) > 0 && len(found) < k {
+
+		// each Cell of the last path found except the end is the spur Cell of a detour: the path up to it (the root)
+		// continues along a route avoiding the moves of the found paths with the same root and the cells of the root
+		previous := found[len(found)-1]
+		rootCost := startCost
+		for i := 0; i < len(previous.cells)-1; i++ {
+
+			spur := previous.cells[i]
+			root := previous.cells[:i+1]
+			if i > 0 {
+				rootCost += settings.quantize(m.moveCost(m.cellAt(previous.cells[i-1]), m.cellAt(spur), &settings))
+			}
+
+			for _, path := range found {
+				if len(path.cells) > i+1 && sameCells(path.cells[:i+1], root) {
+					blockedMoves[[2]int32{path.cells[i], path.cells[i+1]}] = true
+				}
+			}
+			for _, index := range root[:i] {
+				blockedCells[index] = true
+			}
+
+			if detour, exists := m.kPathRoute(&settings, spur, rootCost, blockedCells, blockedMoves); exists {
+				candidate := kPath{append(append([]int32{}, root[:i]...), detour.cells...), detour.cost}
+				if !containsKPath(found, candidate) && !containsKPath(candidates, candidate) {
+					candidates = append(candidates, candidate)
+				}
+			}
+
+			for _, index := range root[:i] {
+				blockedCells[index] = false
+			}
+			for move := range blockedMoves {
+				delete(blockedMoves, move)
+			}
+		}
+
+		if len(candidates) == 0 {
+			break
+		}
+
+		// the cheapest candidate is the next path; the earlier one wins ties, so the order is deterministic
+		cheapest := 0
+		for i, candidate := range candidates {
+			if candidate.cost < candidates[cheapest].cost {
+				cheapest = i
+			}
+		}
+		found = append(found, candidates[cheapest])
+		candidates = append(candidates[:cheapest], candidates[cheapest+1:]...)
+	}
+
+	result := make([]*Path, 0, len(found))
+	for _, route := range found {
+		path := &Path{StepHeight: int(settings.MaxStepHeight)}
+		for _, index := range route.cells {
+			path.Cells = append(path.Cells, m.cellAt(index))
+		}
+		m.finishCells(path, &settings)
+		result = append(result, path)
+	}
+	return result
+}
+
+// kPathRoute returns the cheapest route from the Cell with the index "from", which has been reached at the quantized
+// cost, to the end Cell of the settings, which doesn't enter the blocked cells or make the blocked moves. Returns false
+// if there is none.
+func (m *Grid) kPathRoute(settings *PathSettings, from int32, cost float64, blockedCells []bool, blockedMoves map[[2]int32]bool) (kPath, bool) {
+
+	size := m.Width() * m.Height()
+	costs := make([]float64, size)
+	parents := make([]int32, size)
+	closed := make([]bool, size)
+	for i := range costs {
+		costs[i] = math.Inf(1)
+	}
+
+	end := m.cellIndex(settings.end)
+	costs[from] = cost
+	parents[from] = -1
+	open := openList{{from, cost}}
+
+	for len(open) > 0 {
+
+		item := open.pop()
+		if closed[item.cell] {
+			continue
+		}
+		closed[item.cell] = true
+
+		if item.cell == end {
+			route := kPath{cost: costs[end]}
+			for index := end; index != -1; index = parents[index] {
+				route.cells = append(route.cells, index)
+			}
+			for i, j := 0, len(route.cells)-1; i < j; i, j = i+1, j-1 {
+				route.cells[i], route.cells[j] = route.cells[j], route.cells[i]
+			}
+			return route, true
+		}
+
+		current := m.cellAt(item.cell)
+		for _, neighbor := range m.neighbors(current, settings.diagonals) {
+
+			index := m.cellIndex(neighbor)
+			if closed[index] || blockedCells[index] || blockedMoves[[2]int32{item.cell, index}] ||
+				!m.canMove(current, neighbor, settings) {
+				continue
+			}
+
+			cost := costs[item.cell] + settings.quantize(m.moveCost(current, neighbor, settings))
+			if cost < costs[index] {
+				costs[index] = cost
+				parents[index] = item.cell
+				open.push(openItem{index, cost})
+			}
+		}
+	}
+
+	return kPath{}, false
+}
+
+// sameCells returns if both slices contain the same cell indices.
+func sameCells(a, b []int32) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// containsKPath returns if one of the paths has the same cells as the passed one.
+func containsKPath(paths []kPath, path kPath) bool {
+	for _, other := range paths {
+		if sameCells(other.cells, path.cells) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package paths
+
+import "math"
+
+// Landmarks are precomputed costs of the cheapest paths from and to a few landmark cells, which give AStar a much
+// better estimate of the remaining cost than the distance on static maps with many queries (ALT: A*, landmarks and the
+// triangle inequality). A path from a Cell to the end can't be cheaper than the difference of their paths to or from
+// any landmark, so the search heads around obstacles and through cheap terrain instead of following the straight line.
+// Use them with PathSettings.Landmarks.
+//
+// Computing them takes two searches over the whole Grid per landmark. They are only valid for the movement rules they
+// have been computed with; searches with other rules may get too high estimates and miss cheaper paths. Once the Grid
+// is changed, they are ignored, see Outdated.
+type Landmarks struct {
+	grid     *Grid
+	settings PathSettings
+	cells    []*Cell
+	// from and to contain the quantized costs of the cheapest paths from and to each landmark, indexed like the cells
+	// in a search. Unreachable cells have a cost of +Inf.
+	from, to [][]float64
+	revision uint64
+}
+
+// NewLandmarks chooses up to count landmarks on the walkable cells of the Grid and computes their costs for the
+// movement rules of the settings. Their start, end, Goal and StopWithinRange are ignored. The landmarks are spread as
+// far from each other as possible, as landmarks behind the end, seen from the start, give the best estimates.
+func (m *Grid) NewLandmarks(count int, settings PathSettings) *Landmarks {
+
+	settings.start, settings.end = nil, nil
+	settings.Goal, settings.StopWithinRange = nil, 0
+	settings.goals, settings.starts, settings.corridor = nil, nil, nil
+	settings.GoalBounds, settings.Landmarks = nil, nil
+
+	l := &Landmarks{grid: m, settings: settings, revision: m.Revision()}
+
+	// the first landmark is the Cell farthest from the first walkable one, each following one the Cell farthest from
+	// all landmarks chosen before
+	var nearest []float64
+	for _, cell := range m.AllCells() {
+		if settings.assumeWalkable(cell) {
+			nearest = m.landmarkCosts(m.cellIndex(cell), false, &settings)
+			break
+		}
+	}
+	for len(l.cells) < count && nearest != nil {
+
+		landmark := int32(-1)
+		for index, cost := range nearest {
+			if cost > 0 && !math.IsInf(cost, 1) && (landmark < 0 || cost > nearest[landmark]) {
+				landmark = int32(index)
+			}
+		}
+		if landmark < 0 {
+			break
+		}
+
+		for index, cost := range l.add(landmark) {
+			nearest[index] = math.Min(nearest[index], cost)
+		}
+	}
+
+	return l
+}
+
+// landmarksAt computes the costs of the passed landmarks like NewLandmarks does, which is used to restore the
+// Landmarks of a Replay.
+func (m *Grid) landmarksAt(cells []*Cell, settings PathSettings) *Landmarks {
+
+	settings.start, settings.end = nil, nil
+	settings.Goal, settings.StopWithinRange = nil, 0
+	settings.goals, settings.starts, settings.corridor = nil, nil, nil
+	settings.GoalBounds, settings.Landmarks = nil, nil
+
+	l := &Landmarks{grid: m, settings: settings, revision: m.Revision()}
+	for _, cell := range cells {
+		l.add(m.cellIndex(cell))
+	}
+	return l
+}
+
+// add computes the costs from and to the landmark with the index and returns the costs from it.
+func (l *Landmarks) add(landmark int32) []float64 {
+
+	from := l.grid.landmarkCosts(landmark, false, &l.settings)
+	l.cells = append(l.cells, l.grid.cellAt(landmark))
+	l.from = append(l.from, from)
+	l.to = append(l.to, l.grid.landmarkCosts(landmark, true, &l.settings))
+	return from
+}
+
+// landmarkCosts returns the quantized costs of the cheapest paths from the Cell with the index to all cells, or from
+// all cells to it if reverse is true.
+func (m *Grid) landmarkCosts(source int32, reverse bool, settings *PathSettings) []float64 {
+
+	costs := make([]float64, m.Width()*m.Height())
+	for i := range costs {
+		costs[i] = math.Inf(1)
+	}
+	closed := make([]bool, len(costs))
+	costs[source] = 0
+
+	open := openList{{source, 0}}
+	for len(open) > 0 {
+
+		index := open.pop().cell
+		if closed[index] {
+			continue
+		}
+		closed[index] = true
+		cell := m.cellAt(index)
+
+		for _, neighbor := range m.neighbors(cell, settings.diagonals) {
+
+			from, to := cell, neighbor
+			if reverse {
+				from, to = neighbor, cell
+			}
+			neighborIndex := m.cellIndex(neighbor)
+			if closed[neighborIndex] || !m.canMove(from, to, settings) {
+				continue
+			}
+			cost := costs[index] + settings.quantize(m.moveCost(from, to, settings))
+			if cost < costs[neighborIndex] {
+				costs[neighborIndex] = cost
+				open.push(openItem{neighborIndex, cost})
+			}
+		}
+	}
+
+	return costs
+}
+
+// Cells returns the landmarks.
+func (l *Landmarks) Cells() []*Cell {
+	return append([]*Cell{}, l.cells...)
+}
+
+// Settings returns the movement rules the Landmarks have been computed with.
+func (l *Landmarks) Settings() PathSettings {
+	return l.settings
+}
+
+// Outdated returns if the Grid has been changed since the Landmarks have been computed. Outdated Landmarks are ignored
+// by searches.
+func (l *Landmarks) Outdated() bool {
+	return l.revision != l.grid.Revision()
+}
+
+// usable returns if the Landmarks can estimate the remaining costs of the search of the Grid with the settings.
+func (l *Landmarks) usable(m *Grid, settings *PathSettings) bool {
+	return l.grid == m && !l.Outdated() && settings.StopWithinRange <= 0
+}
+
+// estimate returns the lowest quantized cost a path from the Cell to the end can have according to the landmarks.
+func (l *Landmarks) estimate(cell, end *Cell) float64 {
+
+	index, endIndex := l.grid.cellIndex(cell), l.grid.cellIndex(end)
+	estimate := 0.0
+	for i := range l.cells {
+		// the path from the landmark to the end can't be cheaper than the one via the Cell, and the path from the Cell
+		// to the landmark can't be cheaper than the one via the end
+		if from, fromEnd := l.from[i][index], l.from[i][endIndex]; !math.IsInf(from, 1) && !math.IsInf(fromEnd, 1) {
+			estimate = math.Max(estimate, fromEnd-from)
+		}
+		if to, toEnd := l.to[i][index], l.to[i][endIndex]; !math.IsInf(to, 1) && !math.IsInf(toEnd, 1) {
+			estimate = math.Max(estimate, to-toEnd)
+		}
+	}
+	return estimate
+}
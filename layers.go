@@ -0,0 +1,199 @@
+package paths
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// A CostLayer modifies the costs of cells without changing them, e.g. for seasons or the time of day: a "winter" layer
+// could make mountain passes more expensive and frozen lakes cheaper. Layers are stored on the Grid (see
+// Grid.CostLayer) and used by searches, whose PathSettings.CostLayers contain their names. For each Cell, the cost is
+// first multiplied with the multiplier of the layer and then the additive modifier is added.
+type CostLayer struct {
+	grid      *Grid
+	modifiers map[*Cell]costModifier
+}
+
+// costModifier is the modification of the cost of a single cell by a CostLayer.
+type costModifier struct {
+	add, factor float64
+}
+
+// CostLayer returns the cost layer with the passed name. If the Grid doesn't contain a layer with this name yet, an
+// empty one is created.
+func (m *Grid) CostLayer(name string) *CostLayer {
+
+	if m.costLayers == nil {
+		m.costLayers = make(map[string]*CostLayer)
+	}
+
+	layer, exists := m.costLayers[name]
+	if !exists {
+		layer = &CostLayer{grid: m, modifiers: make(map[*Cell]costModifier)}
+		m.costLayers[name] = layer
+	}
+
+	return layer
+}
+
+// RemoveCostLayer removes the cost layer with the passed name from the Grid.
+func (m *Grid) RemoveCostLayer(name string) {
+	if _, exists := m.costLayers[name]; exists {
+		delete(m.costLayers, name)
+		m.MarkChanged()
+	}
+}
+
+// CostLayers returns the names of all cost layers of the Grid in alphabetical order.
+func (m *Grid) CostLayers() []string {
+
+	names := make([]string, 0, len(m.costLayers))
+	for name := range m.costLayers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// SetAdditive sets the cost, which is added to the cost of the Cell.
+func (l *CostLayer) SetAdditive(cell *Cell, cost float64) {
+	modifier := l.modifier(cell)
+	modifier.add = cost
+	l.setModifier(cell, modifier)
+}
+
+// SetMultiplier sets the factor, which the cost of the Cell is multiplied with.
+func (l *CostLayer) SetMultiplier(cell *Cell, factor float64) {
+	modifier := l.modifier(cell)
+	modifier.factor = factor
+	l.setModifier(cell, modifier)
+}
+
+// SetAdditiveRune sets the additive modifier of all cells with the passed rune, see SetAdditive.
+func (l *CostLayer) SetAdditiveRune(char rune, cost float64) {
+	for _, cell := range l.grid.CellsByRune(char) {
+		l.SetAdditive(cell, cost)
+	}
+}
+
+// SetMultiplierRune sets the multiplier of all cells with the passed rune, see SetMultiplier.
+func (l *CostLayer) SetMultiplierRune(char rune, factor float64) {
+	for _, cell := range l.grid.CellsByRune(char) {
+		l.SetMultiplier(cell, factor)
+	}
+}
+
+// Modifier returns the additive modifier and the multiplier of the Cell. Cells without modifiers return 0 and 1.
+func (l *CostLayer) Modifier(cell *Cell) (add, factor float64) {
+	modifier := l.modifier(cell)
+	return modifier.add, modifier.factor
+}
+
+// Reset removes all modifiers of the layer.
+func (l *CostLayer) Reset() {
+	l.modifiers = make(map[*Cell]costModifier)
+	l.grid.MarkChanged()
+}
+
+// Apply returns the passed cost of the Cell modified by the layer.
+func (l *CostLayer) Apply(cell *Cell, cost float64) float64 {
+	if modifier, exists := l.modifiers[cell]; exists {
+		return cost*modifier.factor + modifier.add
+	}
+	return cost
+}
+
+func (l *CostLayer) modifier(cell *Cell) costModifier {
+	if modifier, exists := l.modifiers[cell]; exists {
+		return modifier
+	}
+	return costModifier{factor: 1}
+}
+
+func (l *CostLayer) setModifier(cell *Cell, modifier costModifier) {
+	l.modifiers[cell] = modifier
+	l.grid.MarkChanged()
+}
+
+// cellCost returns the cost of entering the Cell in a search with the passed settings, i.e. the Cost of the Cell (as
+// far as the search knows, see PathSettings.Knowledge) modified by the cost layers of the settings plus the risk
+// surcharge (see PathSettings.RiskAversion). Negative costs are treated as zero, NaN as +Inf.
+func (m *Grid) cellCost(cell *Cell, settings *PathSettings) float64 {
+
+	if len(settings.CostLayers) == 0 && settings.RiskAversion == 0 && settings.Knowledge == nil {
+		return cell.pathCost()
+	}
+
+	cost := settings.assumedCost(cell)
+	for _, name := range settings.CostLayers {
+		if layer, exists := m.costLayers[name]; exists {
+			cost = layer.Apply(cell, cost)
+		}
+	}
+
+	if cell.CostVariance > 0 {
+		cost += settings.RiskAversion * math.Sqrt(cell.CostVariance)
+	}
+
+	if cost < 0 {
+		return 0
+	}
+	if math.IsNaN(cost) {
+		return math.Inf(1)
+	}
+	return cost
+}
+
+// settingsMinCost returns the lowest cost of entering a Cell with the cost layers and the knowledge of the settings,
+// not including the risk surcharge. Finding it needs to check every Cell, so it's cached until the Grid (including its
+// cost layers) or the KnowledgeLayer is changed.
+func (m *Grid) settingsMinCost(settings *PathSettings) float64 {
+
+	if len(settings.CostLayers) == 0 && settings.Knowledge == nil {
+		return m.MinCost()
+	}
+
+	m.minCostsLock.Lock()
+	defer m.minCostsLock.Unlock()
+
+	layers := strings.Join(settings.CostLayers, "\x00")
+	knowledge := settings.Knowledge
+	var key knowledgeMinCostKey
+	if knowledge != nil {
+		key = knowledgeMinCostKey{m.Revision(), knowledge.changes, layers, knowledge.UnknownCost}
+		if knowledge.minCostKnown && knowledge.minCostKey == key {
+			return knowledge.minCost
+		}
+	} else {
+		if m.layerMinCostsRevision != m.Revision() || m.layerMinCosts == nil {
+			m.layerMinCosts = make(map[string]float64)
+			m.layerMinCostsRevision = m.Revision()
+		}
+		if minCost, known := m.layerMinCosts[layers]; known {
+			return minCost
+		}
+	}
+
+	plain := PathSettings{CostLayers: settings.CostLayers, Knowledge: knowledge}
+	minCost := math.Inf(1)
+	for _, cell := range m.AllCells() {
+		minCost = math.Min(minCost, m.cellCost(cell, &plain))
+	}
+
+	if knowledge != nil {
+		knowledge.minCost, knowledge.minCostKey, knowledge.minCostKnown = minCost, key, true
+	} else {
+		m.layerMinCosts[layers] = minCost
+	}
+	return minCost
+}
+
+// knowledgeMinCostKey is what the lowest cost of a Cell for a search with a KnowledgeLayer depends on: the revision of
+// the Grid, the changes of the KnowledgeLayer, the cost layers (their joined names) and the cost of unknown cells.
+type knowledgeMinCostKey struct {
+	revision, changes uint64
+	layers            string
+	unknownCost       float64
+}
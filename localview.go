@@ -0,0 +1,100 @@
+package paths
+
+// A LocalView is a small copy of a region of a Grid, see Grid.LocalView. Expensive computations for a single agent,
+// like local avoidance or tactical analysis, can run on the small Grid instead of the whole map. Changes of the copy
+// don't affect the original Grid and vice versa.
+type LocalView struct {
+	// Grid is the copy of the region.
+	Grid *Grid
+	// OffsetX and OffsetY are the position of the top left Cell of the copy in the original Grid.
+	OffsetX, OffsetY int
+	source           *Grid
+}
+
+// LocalView copies all cells within the radius (measured like with Chebyshev) around the center Cell into a new Grid.
+// The region is cut off at the borders of the Grid. Categories, rune layers, cost layers and the sea level are copied
+// as well.
+func (m *Grid) LocalView(center *Cell, radius int) *LocalView {
+
+	minX, minY := maxInt(center.X-radius, 0), maxInt(center.Y-radius, 0)
+	maxX, maxY := minInt(center.X+radius, m.Width()-1), minInt(center.Y+radius, m.Height()-1)
+
+	view := &LocalView{
+		Grid:    NewGrid(maxInt(maxX-minX+1, 0), maxInt(maxY-minY+1, 0)),
+		OffsetX: minX,
+		OffsetY: minY,
+		source:  m,
+	}
+
+	for _, cell := range view.Grid.AllCells() {
+		x, y := cell.X, cell.Y
+		*cell = *m.Get(x+minX, y+minY)
+		cell.X, cell.Y = x, y
+	}
+
+	if m.categories != nil {
+		view.Grid.categories = make(map[string]map[rune]bool, len(m.categories))
+	}
+	for name, runes := range m.categories {
+		view.Grid.categories[name] = make(map[rune]bool, len(runes))
+		for r := range runes {
+			view.Grid.categories[name][r] = true
+		}
+	}
+	for name, layer := range m.runeLayers {
+		localLayer := view.Grid.RuneLayer(name)
+		for _, cell := range view.Grid.AllCells() {
+			localLayer.runes[cell.Y][cell.X] = layer.Get(view.ToGlobal(cell))
+		}
+	}
+	for name, layer := range m.costLayers {
+		localLayer := view.Grid.CostLayer(name)
+		for cell, modifier := range layer.modifiers {
+			if local := view.ToLocal(cell); local != nil {
+				localLayer.modifiers[local] = modifier
+			}
+		}
+	}
+	view.Grid.seaLevel = m.seaLevel
+
+	return view
+}
+
+// ToLocal returns the Cell of the copy at the position of the passed Cell of the original Grid. If the position is
+// outside of the copied region, nil is returned.
+func (v *LocalView) ToLocal(cell *Cell) *Cell {
+	if cell == nil || v.source.Get(cell.X, cell.Y) != cell {
+		return nil
+	}
+	return v.Grid.Get(cell.X-v.OffsetX, cell.Y-v.OffsetY)
+}
+
+// ToGlobal returns the Cell of the original Grid at the position of the passed Cell of the copy.
+func (v *LocalView) ToGlobal(cell *Cell) *Cell {
+	if cell == nil || v.Grid.Get(cell.X, cell.Y) != cell {
+		return nil
+	}
+	return v.source.Get(cell.X+v.OffsetX, cell.Y+v.OffsetY)
+}
+
+// ToGlobalPath returns a copy of the Path found on the copy, which uses the cells of the original Grid.
+func (v *LocalView) ToGlobalPath(path *Path) *Path {
+
+	if path == nil {
+		return nil
+	}
+
+	global := *path
+	global.Cells = make([]*Cell, len(path.Cells))
+	for i, cell := range path.Cells {
+		global.Cells[i] = v.ToGlobal(cell)
+	}
+	return &global
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,140 @@
+package paths
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A MapReport is the result of ValidateMap.
+type MapReport struct {
+	// Routes contains the route from each spawn point to each objective.
+	Routes []MapRoute
+	// Failures contains the routes, which are unreachable or too expensive.
+	Failures []MapRoute
+}
+
+// A MapRoute is the connection between a spawn point and an objective checked by ValidateMap.
+type MapRoute struct {
+	Spawn, Objective *Cell
+	// Reachable is true, if the objective can be reached from the spawn point.
+	Reachable bool
+	// Cost is the cost of the cheapest path, without the cost of the spawn point.
+	Cost float64
+	// Visualisation shows the problem of failed routes, see ValidateMap. It's nil for routes without problems.
+	Visualisation []string
+}
+
+// OK returns if all routes are fine.
+func (r *MapReport) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// String returns a description of all failures including their visualisations.
+func (r *MapReport) String() string {
+
+	if r.OK() {
+		return fmt.Sprintf("all %d routes ok", len(r.Routes))
+	}
+
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "%d of %d routes failed\n", len(r.Failures), len(r.Routes))
+	for _, failure := range r.Failures {
+		if failure.Reachable {
+			fmt.Fprintf(&builder, "%v -> %v: cost %g too high\n", Point{failure.Spawn.X, failure.Spawn.Y}, Point{failure.Objective.X, failure.Objective.Y}, failure.Cost)
+		} else {
+			fmt.Fprintf(&builder, "%v -> %v: unreachable\n", Point{failure.Spawn.X, failure.Spawn.Y}, Point{failure.Objective.X, failure.Objective.Y})
+		}
+		for _, line := range failure.Visualisation {
+			builder.WriteString(line)
+			builder.WriteString("\n")
+		}
+	}
+
+	return builder.String()
+}
+
+// ValidateMap checks, that every spawn point can reach every objective with the passed settings, whose start and end
+// are ignored, e.g. in automated quality checks of generated maps. If maxCost is greater than 0, the cheapest path
+// must not cost more than it. Failed routes are visualised with the runes of the Grid: the spawn point is 'S', the
+// objective 'O'. For unreachable objectives, '+' marks the cells reachable from the spawn point and '-' the cells the
+// objective can be reached from, so the gap between both areas shows where the connection is missing. For too
+// expensive routes, the cheapest path is marked with '*'.
+func ValidateMap(grid *Grid, spawnPoints, objectives []*Cell, settings PathSettings, maxCost float64) *MapReport {
+
+	report := &MapReport{Routes: []MapRoute{}, Failures: []MapRoute{}}
+
+	for _, objective := range objectives {
+
+		toObjective := grid.costsToGoals([]*Cell{objective}, &settings)
+
+		for _, spawn := range spawnPoints {
+
+			cost, reachable := toObjective[spawn]
+			reachable = reachable && spawn.Walkable && objective.Walkable
+			route := MapRoute{Spawn: spawn, Objective: objective, Reachable: reachable, Cost: cost}
+
+			if !reachable {
+				fromSpawn := grid.costsFromStart(spawn, &settings)
+				route.Visualisation = grid.visualiseRoute(spawn, objective, func(cell *Cell) rune {
+					if _, exists := fromSpawn[cell]; exists && spawn.Walkable {
+						return '+'
+					}
+					if _, exists := toObjective[cell]; exists && objective.Walkable {
+						return '-'
+					}
+					return 0
+				})
+			} else if maxCost > 0 && cost > maxCost {
+				pathSettings := settings
+				pathSettings.start, pathSettings.end = spawn, objective
+				pathSettings.Goal = nil
+				pathSettings.RequireOptimal = true
+				onPath := make(map[*Cell]bool)
+				if path := grid.findPath(&pathSettings, nil); path != nil {
+					for _, cell := range path.Cells {
+						onPath[cell] = true
+					}
+				}
+				route.Visualisation = grid.visualiseRoute(spawn, objective, func(cell *Cell) rune {
+					if onPath[cell] {
+						return '*'
+					}
+					return 0
+				})
+			}
+
+			report.Routes = append(report.Routes, route)
+			if route.Visualisation != nil {
+				report.Failures = append(report.Failures, route)
+			}
+		}
+	}
+
+	return report
+}
+
+// visualiseRoute returns the runes of the Grid with the start marked as 'S', the end as 'O' and all cells the marker
+// returns a rune other than 0 for with this rune.
+func (m *Grid) visualiseRoute(start, end *Cell, marker func(cell *Cell) rune) []string {
+
+	lines := make([]string, 0, m.Height())
+	for y := 0; y < m.Height(); y++ {
+		line := make([]rune, 0, m.Width())
+		for x := 0; x < m.Width(); x++ {
+			cell := m.Get(x, y)
+			switch {
+			case cell == start:
+				line = append(line, 'S')
+			case cell == end:
+				line = append(line, 'O')
+			case marker(cell) != 0:
+				line = append(line, marker(cell))
+			default:
+				line = append(line, cell.Rune)
+			}
+		}
+		lines = append(lines, string(line))
+	}
+
+	return lines
+}
@@ -0,0 +1,139 @@
+package paths
+
+import "math"
+
+// A Falloff defines how the penalty of a SoftObstacle decreases with the distance to its center.
+type Falloff int
+
+const (
+	// LinearFalloff decreases the penalty linearly to zero at the radius.
+	LinearFalloff Falloff = iota
+	// SmoothFalloff decreases the penalty quadratically, so it drops quickly near the center and fades out smoothly.
+	SmoothFalloff
+	// ConstantFalloff applies the full penalty to all cells within the radius.
+	ConstantFalloff
+)
+
+// factor returns the share of the penalty applied at the distance from the center.
+func (falloff Falloff) factor(distance, radius float64) float64 {
+
+	if distance > radius {
+		return 0
+	}
+	if radius <= 0 {
+		return 1
+	}
+
+	switch falloff {
+	case SmoothFalloff:
+		return (1 - distance/radius) * (1 - distance/radius)
+	case ConstantFalloff:
+		return 1
+	}
+	return 1 - distance/radius
+}
+
+// A SoftObstacle is a point hazard, e.g. a fire, an enemy or a moving vehicle, which doesn't block the cells around it,
+// but makes them more expensive, so agents give it a wide berth if there's room to and still pass it if there isn't.
+// Its penalty is added to the additive modifiers (see CostLayer.SetAdditive) of the cells within its radius, decreasing
+// with the distance to its center as defined by its Falloff. The penalties of overlapping obstacles add up. When it's
+// moved or changed, only the difference to the penalty applied before is added to each Cell, so modifiers set by other
+// obstacles or by hand on the same layer are kept. Create it with CostLayer.AddSoftObstacle.
+type SoftObstacle struct {
+	layer   *CostLayer
+	center  *Cell
+	radius  float64
+	penalty float64
+	falloff Falloff
+	// applied contains the penalty added to each cell.
+	applied map[*Cell]float64
+}
+
+// AddSoftObstacle adds a SoftObstacle at the center Cell, which adds the penalty to the cells of the layer within the
+// radius (measured like with Euclidean).
+func (l *CostLayer) AddSoftObstacle(center *Cell, radius, penalty float64, falloff Falloff) *SoftObstacle {
+
+	obstacle := &SoftObstacle{layer: l, radius: radius, penalty: penalty, falloff: falloff}
+	obstacle.update(center, radius, penalty)
+	return obstacle
+}
+
+// Center returns the Cell the SoftObstacle is centered on, or nil if it has been removed.
+func (o *SoftObstacle) Center() *Cell {
+	return o.center
+}
+
+// MoveTo moves the SoftObstacle to the center Cell.
+func (o *SoftObstacle) MoveTo(center *Cell) {
+	o.update(center, o.radius, o.penalty)
+}
+
+// SetRadius changes the radius of the SoftObstacle.
+func (o *SoftObstacle) SetRadius(radius float64) {
+	o.update(o.center, radius, o.penalty)
+}
+
+// SetPenalty changes the penalty of the SoftObstacle at its center.
+func (o *SoftObstacle) SetPenalty(penalty float64) {
+	o.update(o.center, o.radius, penalty)
+}
+
+// Remove removes the penalties of the SoftObstacle from its layer. Removed obstacles can be placed again with MoveTo.
+func (o *SoftObstacle) Remove() {
+	o.update(nil, o.radius, o.penalty)
+}
+
+// update moves the SoftObstacle to the center (nil removes it) with the radius and penalty and changes the modifiers
+// of the cells, whose penalty has changed.
+func (o *SoftObstacle) update(center *Cell, radius, penalty float64) {
+
+	applied := make(map[*Cell]float64)
+	if center != nil {
+		grid := o.layer.grid
+		reach := int(math.Ceil(radius))
+		for y := center.Y - reach; y <= center.Y+reach; y++ {
+			for x := center.X - reach; x <= center.X+reach; x++ {
+				cell := grid.Get(x, y)
+				if cell == nil {
+					continue
+				}
+				if amount := penalty * o.falloff.factor(Euclidean.Distance(cell, center), radius); amount != 0 {
+					applied[cell] = amount
+				}
+			}
+		}
+	}
+
+	changed := false
+	for cell, amount := range o.applied {
+		if applied[cell] != amount {
+			o.layer.addPenalty(cell, applied[cell]-amount)
+			changed = true
+		}
+	}
+	for cell, amount := range applied {
+		if _, exists := o.applied[cell]; !exists {
+			o.layer.addPenalty(cell, amount)
+			changed = true
+		}
+	}
+
+	o.center, o.radius, o.penalty, o.applied = center, radius, penalty, applied
+	if changed {
+		o.layer.grid.MarkChanged()
+	}
+}
+
+// addPenalty adds the amount to the additive modifier of the Cell without marking the Grid as changed. Modifiers,
+// which don't modify the cost anymore, are removed, so the rounding errors of adding and removing penalties don't
+// accumulate.
+func (l *CostLayer) addPenalty(cell *Cell, amount float64) {
+
+	modifier := l.modifier(cell)
+	modifier.add += amount
+	if math.Abs(modifier.add) < 1e-9 && modifier.factor == 1 {
+		delete(l.modifiers, cell)
+		return
+	}
+	l.modifiers[cell] = modifier
+}
@@ -0,0 +1,59 @@
+package paths
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestRequireOptimal compares the paths found with RequireOptimal to the ones of UniformCost, which always finds a
+// cheapest path, on random grids with varying costs. Settings trading path quality for speed are set as well, as they
+// must be ignored.
+func TestRequireOptimal(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+	configurations := []struct {
+		name      string
+		configure func(settings *PathSettings)
+	}{
+		{"astar", func(settings *PathSettings) {}},
+		{"weighted", func(settings *PathSettings) { settings.HeuristicWeight = 3 }},
+		{"manhattan", func(settings *PathSettings) { settings.Heuristic = ManhattanHeuristic }},
+		{"jump point search", func(settings *PathSettings) { settings.Algorithm = JumpPointSearch }},
+		{"fringe", func(settings *PathSettings) { settings.Algorithm = FringeSearch }},
+	}
+
+	for run := 0; run < 300; run++ {
+
+		grid := NewGrid(16, 16)
+		for _, cell := range grid.AllCells() {
+			cell.Walkable = random.Intn(5) > 0
+			cell.Cost = 1 + random.Float64()*4
+		}
+		start, end := grid.Get(random.Intn(16), random.Intn(16)), grid.Get(random.Intn(16), random.Intn(16))
+		start.Walkable, end.Walkable = true, true
+
+		settings := *NewDefaultPathSettings(start, end)
+		settings.diagonals = run%2 == 0
+		settings.RequireOptimal = true
+		settings.Algorithm = UniformCost
+		var cheapest SearchStats
+		settings.OnSearchComplete = func(stats SearchStats) { cheapest = stats }
+		grid.GetPathFromSettings(settings)
+
+		for _, configuration := range configurations {
+
+			optimal := settings
+			optimal.Algorithm = AStar
+			configuration.configure(&optimal)
+			var found SearchStats
+			optimal.OnSearchComplete = func(stats SearchStats) { found = stats }
+			grid.GetPathFromSettings(optimal)
+
+			if found.Found != cheapest.Found || math.Abs(found.Cost-cheapest.Cost) > 1e-9 {
+				t.Fatalf("run %d, %s: found a path costing %v (found: %v) instead of %v (found: %v)", run,
+					configuration.name, found.Cost, found.Found, cheapest.Cost, cheapest.Found)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package paths
+
+import "math"
+
+// A MergeMode defines how an attribute of a Cell is merged with the one of another Cell, see OverlayPolicy.
+type MergeMode int
+
+const (
+	// MergeReplace takes the value of the other Cell.
+	MergeReplace MergeMode = iota
+	// MergeKeep keeps the value of the Cell.
+	MergeKeep
+	// MergeMin takes the lower value. For walkability, a Cell is only walkable if both are ("prefer blocked").
+	MergeMin
+	// MergeMax takes the higher value. For walkability, a Cell is walkable if one of both is ("prefer walkable").
+	MergeMax
+	// MergeSum adds both values. For walkability, it works like MergeMax.
+	MergeSum
+)
+
+// An OverlayPolicy defines how conflicts are handled by Grid.Overlay. The zero value replaces everything.
+type OverlayPolicy struct {
+	// Walkable, Height, Cost and Rune define how each of the attributes is merged. Heights are compared by their
+	// TotalHeight; the HeightLevel and Elevation of the chosen Cell are taken, or both are added with MergeSum. Runes can
+	// only be replaced or kept; all other modes keep them.
+	Walkable, Height, Cost, Rune MergeMode
+	// Transparent contains runes of the other Grid, whose cells aren't merged at all, e.g. the empty space around a
+	// building.
+	Transparent []rune
+}
+
+// Overlay merges the cells of the other Grid into this one, e.g. to stamp prefabs or buildings into a terrain. The
+// top left Cell of the other Grid is placed at the offset; cells outside of this Grid are ignored. Conflicts are
+// handled as defined by the policy. Clearance, CostVariance, BlockProbability, Unknown, EnterCost and ExitCost are
+// taken from the other Grid, the Occupancy is kept. The amount of merged cells is returned.
+func (m *Grid) Overlay(other *Grid, offset Point, policy OverlayPolicy) int {
+
+	transparent := make(map[rune]bool, len(policy.Transparent))
+	for _, r := range policy.Transparent {
+		transparent[r] = true
+	}
+
+	merged := 0
+
+	for _, source := range other.AllCells() {
+
+		target := m.Get(source.X+offset.X, source.Y+offset.Y)
+		if target == nil || transparent[source.Rune] {
+			continue
+		}
+		target = m.Stage(target)
+
+		target.Walkable = mergeBool(target.Walkable, source.Walkable, policy.Walkable)
+		target.Cost = mergeFloat(target.Cost, source.Cost, policy.Cost)
+
+		switch policy.Height {
+		case MergeReplace:
+			target.HeightLevel, target.Elevation = source.HeightLevel, source.Elevation
+		case MergeMin:
+			if source.TotalHeight() < target.TotalHeight() {
+				target.HeightLevel, target.Elevation = source.HeightLevel, source.Elevation
+			}
+		case MergeMax:
+			if source.TotalHeight() > target.TotalHeight() {
+				target.HeightLevel, target.Elevation = source.HeightLevel, source.Elevation
+			}
+		case MergeSum:
+			target.HeightLevel += source.HeightLevel
+			target.Elevation += source.Elevation
+		}
+
+		if policy.Rune == MergeReplace {
+			target.Rune = source.Rune
+		}
+
+		target.Clearance = source.Clearance
+		target.CostVariance = source.CostVariance
+		target.BlockProbability = source.BlockProbability
+		target.Unknown = source.Unknown
+		target.EnterCost, target.ExitCost = source.EnterCost, source.ExitCost
+
+		merged++
+	}
+
+	if merged > 0 {
+		m.markCellsChanged()
+	}
+
+	return merged
+}
+
+// mergeFloat merges two values as defined by the MergeMode.
+func mergeFloat(value, other float64, mode MergeMode) float64 {
+	switch mode {
+	case MergeKeep:
+		return value
+	case MergeMin:
+		return math.Min(value, other)
+	case MergeMax:
+		return math.Max(value, other)
+	case MergeSum:
+		return value + other
+	}
+	return other
+}
+
+// mergeBool merges two flags as defined by the MergeMode.
+func mergeBool(value, other bool, mode MergeMode) bool {
+	switch mode {
+	case MergeKeep:
+		return value
+	case MergeMin:
+		return value && other
+	case MergeMax, MergeSum:
+		return value || other
+	}
+	return other
+}
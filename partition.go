@@ -0,0 +1,166 @@
+package paths
+
+import "math"
+
+// A Partition assigns the walkable cells of a Grid to the seed they can be reached from the cheapest, e.g. for the
+// territories of factions, the catchment areas of shops or to check if spawn points are balanced. Create one with
+// Grid.PartitionBySeeds.
+type Partition struct {
+	grid  *Grid
+	seeds []*Cell
+	// regions contains the index of the seed of each cell or -1 if no seed reaches it and costs the cost of the path
+	// from the seed, both indexed like the cells in a search.
+	regions []int
+	costs   []float64
+}
+
+// PartitionBySeeds assigns every Cell to the seed with the cheapest path to it. The cost of a seed itself isn't
+// counted. If multiple seeds are equally close, the Cell belongs to the one passed first. Cells, which no seed can
+// reach, and seeds, which aren't walkable, don't belong to any region. The movement rules are taken from the settings;
+// their start and end are ignored.
+func (m *Grid) PartitionBySeeds(seeds []*Cell, settings PathSettings) *Partition {
+
+	size := m.Width() * m.Height()
+	p := &Partition{
+		grid:    m,
+		seeds:   seeds,
+		regions: make([]int, size),
+		costs:   make([]float64, size),
+	}
+	for i := range p.regions {
+		p.regions[i] = -1
+		p.costs[i] = math.Inf(1)
+	}
+
+	// all seeds spread at once, so each cell is reached by the closest one first
+	open := openList{}
+	for i, seed := range seeds {
+		if seed == nil || !seed.Walkable {
+			continue
+		}
+		if index := m.cellIndex(seed); p.regions[index] == -1 {
+			p.regions[index] = i
+			p.costs[index] = 0
+			open.push(openItem{index, 0})
+		}
+	}
+
+	closed := make([]bool, size)
+	for len(open) > 0 {
+
+		item := open.pop()
+		if closed[item.cell] {
+			continue
+		}
+		closed[item.cell] = true
+		current := m.cellAt(item.cell)
+
+		for _, neighbor := range m.neighbors(current, settings.diagonals) {
+
+			index := m.cellIndex(neighbor)
+			if closed[index] || !m.canMove(current, neighbor, &settings) {
+				continue
+			}
+
+			cost := p.costs[item.cell] + settings.quantize(m.moveCost(current, neighbor, &settings))
+			region := p.regions[item.cell]
+			if cost < p.costs[index] || (cost == p.costs[index] && region < p.regions[index]) {
+				p.costs[index] = cost
+				p.regions[index] = region
+				open.push(openItem{index, cost})
+			}
+		}
+	}
+
+	if settings.FixedPoint {
+		for i, cost := range p.costs {
+			p.costs[i] = settings.unquantize(cost)
+		}
+	}
+
+	return p
+}
+
+// Seeds returns the seeds of the Partition in the order they have been passed.
+func (p *Partition) Seeds() []*Cell {
+	return p.seeds
+}
+
+// Region returns the index of the seed the Cell belongs to, or -1 if it doesn't belong to any.
+func (p *Partition) Region(cell *Cell) int {
+	return p.regions[p.grid.cellIndex(cell)]
+}
+
+// Seed returns the seed the Cell belongs to, or nil if it doesn't belong to any.
+func (p *Partition) Seed(cell *Cell) *Cell {
+
+	region := p.Region(cell)
+	if region == -1 {
+		return nil
+	}
+	return p.seeds[region]
+}
+
+// Cost returns the cost of the cheapest path from the seed of the Cell to the Cell, without the cost of the seed. If
+// the Cell doesn't belong to any seed, false is returned.
+func (p *Partition) Cost(cell *Cell) (float64, bool) {
+
+	index := p.grid.cellIndex(cell)
+	if p.regions[index] == -1 {
+		return math.Inf(1), false
+	}
+	return p.costs[index], true
+}
+
+// Cells returns the cells belonging to the seed with the index, including the seed itself.
+func (p *Partition) Cells(region int) []*Cell {
+
+	cells := []*Cell{}
+	for i, r := range p.regions {
+		if r == region && region != -1 {
+			cells = append(cells, p.grid.cellAt(int32(i)))
+		}
+	}
+	return cells
+}
+
+// Sizes returns the amount of cells belonging to each seed, in the order of the seeds, e.g. to compare the territories
+// of spawn points.
+func (p *Partition) Sizes() []int {
+
+	sizes := make([]int, len(p.seeds))
+	for _, region := range p.regions {
+		if region != -1 {
+			sizes[region]++
+		}
+	}
+	return sizes
+}
+
+// Regions exports the Partition as the index of the seed of each Cell, indexed by [y][x]. Cells without a seed have the
+// index -1.
+func (p *Partition) Regions() [][]int {
+
+	regions := make([][]int, p.grid.Height())
+	for y := range regions {
+		regions[y] = make([]int, p.grid.Width())
+		copy(regions[y], p.regions[y*p.grid.Width():])
+	}
+	return regions
+}
+
+// Border returns if the Cell belongs to a seed and has a neighbor belonging to another one, e.g. to draw the borders of
+// territories.
+func (p *Partition) Border(cell *Cell) bool {
+
+	region := p.Region(cell)
+	if region == -1 {
+		return false
+	}
+	for _, neighbor := range p.grid.neighbors(cell, true) {
+		if other := p.Region(neighbor); other != -1 && other != region {
+			return true
+		}
+	}
+	return false
+}
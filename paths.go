@@ -12,27 +12,124 @@ import (
 	"math"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // A Cell represents a point on a Grid map. It has an X and Y value for the position, a Cost, which influences which Cells are
 // ideal for paths, Walkable, which indicates if the tile can be walked on or should be avoided, a Rune, which indicates
 // which rune character the Cell is represented by, and a HeightLevel (default: 0), which represents the height of this cell.
+// Elevation (default: 0) is added on top of the HeightLevel and allows heights between the levels, e.g. for terrains
+// generated from noise or digital elevation models. Use TotalHeight to get the combined height. Occupancy counts the
+// agents currently standing on the cell; it is maintained by the user and limited by PathSettings.MaxOccupancy.
+// Clearance (default: 0, unlimited) is the free height above the Cell, e.g. in tunnels; agents taller than it (see
+// PathSettings.AgentHeight) can't walk on the Cell. CostVariance (default: 0) is the variance of the Cost, for cells whose
+// cost is unreliable, e.g. a ford whose depth depends on the weather; see PathSettings.RiskAversion. BlockProbability
+// (default: 0) is the probability, that the Cell is blocked although it's walkable, e.g. from sensor data, and Unknown
+// marks cells nothing is known about; see PathSettings.MinTraversalProbability and PathSettings.UnknownCells.
+// EnterCost and ExitCost (default: 0) are added to the Cost of the moves onto and off the Cell, e.g. for climbing out of
+// a trench or pulling free of mud, which can't be modeled by the cost of entering each Cell alone.
+//
+// A Cost of zero is valid and makes the Cell free to walk over. Negative costs can't be handled by the pathfinding; they
+// are treated as zero. A Cost of +Inf or NaN (e.g. from a broken cost formula) makes the Cell unwalkable, the same
+// applies to its EnterCost and ExitCost for the moves onto and off it. Use Grid.ValidateCosts to detect them.
 type Cell struct {
 	X, Y, HeightLevel int
+	Elevation         float64
 	Cost              float64
 	Walkable          bool
 	Rune              rune
+	Occupancy         int
+	Clearance         float64
+	CostVariance      float64
+	BlockProbability  float64
+	Unknown           bool
+	EnterCost         float64
+	ExitCost          float64
 }
 
 func (cell Cell) String() string {
 	return fmt.Sprintf("X:%d Y:%d Height:%d Cost:%f Walkable:%t Rune:%s(%d)", cell.X, cell.Y, cell.HeightLevel, cell.Cost, cell.Walkable, string(cell.Rune), int(cell.Rune))
 }
 
+// TotalHeight returns the height of the cell, which is the HeightLevel plus the Elevation.
+func (cell *Cell) TotalHeight() float64 {
+	return float64(cell.HeightLevel) + cell.Elevation
+}
+
+// transitionCost returns the EnterCost of "to" plus the ExitCost of "from". Negative costs are treated as zero, NaN as
+// +Inf.
+func transitionCost(from, to *Cell) float64 {
+	return searchCost(from.ExitCost) + searchCost(to.EnterCost)
+}
+
+// pathCost returns the cost used by the pathfinding for entering this cell. Negative costs are treated as zero, NaN as
+// +Inf.
+func (cell *Cell) pathCost() float64 {
+	return searchCost(cell.Cost)
+}
+
+// searchCost returns the cost as it's used by the pathfinding: negative costs are treated as zero and NaN as +Inf, so
+// it can't break the comparisons of the search.
+func searchCost(cost float64) float64 {
+
+	if cost < 0 {
+		return 0
+	}
+	if math.IsNaN(cost) {
+		return math.Inf(1)
+	}
+	return cost
+}
+
+// A Point is a position on a Grid.
+type Point struct {
+	X, Y int
+}
+
 // Grid represents a "map" composed of individual Cells at each point in the map.
 // Data is a 2D array of Cells.
 // CellWidth and CellHeight indicate the size of Cells for Cell Position <-> World Position translation.
 type Grid struct {
-	Data [][]*Cell
+	// revision is increased on every change of the Grid, see Revision. It's accessed atomically, so it has to stay the
+	// first field to be 64-bit aligned on 32-bit platforms.
+	revision uint64
+	Data     [][]*Cell
+	// minCost is the result of MinCost, which is valid as long as minCostRevision equals the revision. It's guarded by
+	// minCostsLock, as searches in read transactions may store it concurrently.
+	minCost         float64
+	minCostRevision uint64
+	minCostKnown    bool
+	// layerMinCosts contains the lowest cost of a Cell with each combination of cost layers (by their joined names),
+	// which is valid as long as layerMinCostsRevision equals the revision. minCostsLock guards it, the minCost and the
+	// lowest costs cached by the knowledge layers, see settingsMinCost.
+	layerMinCosts         map[string]float64
+	layerMinCostsRevision uint64
+	minCostsLock          sync.Mutex
+	// costLayers contains the cost layers by name, see CostLayer.
+	costLayers map[string]*CostLayer
+	// seaLevel is the height of the water surface, see SetSeaLevel.
+	seaLevel float64
+	// categories contains the runes of each category, see Categorize.
+	categories map[string]map[rune]bool
+	// runeLayers contains the rune layers by name, see RuneLayer.
+	runeLayers map[string]*RuneLayer
+	// staged contains the staged copies of the cells in staging mode, see SetStaging.
+	staged map[*Cell]*Cell
+	// slowQueries is the log of slow searches, see SetSlowQueryLog.
+	slowQueries *SlowQueryLog
+	// lock is held for reading by ReadTx and for writing by Commit.
+	lock sync.RWMutex
+	// searchBuffers contains the memory used by searches, so they don't need to allocate it again and again.
+	searchBuffers sync.Pool
+	// estimates is the HierarchicalGrid answering EstimateTravelCost, which is created once on the first call.
+	estimates     *HierarchicalGrid
+	estimatesOnce sync.Once
+	// edgeCosts contains the costs of the moves set by SetEdgeCost, edgeCells the amount of these moves each Cell (by
+	// its index) is part of.
+	edgeCosts map[edge]float64
+	edgeCells map[int32]int
 }
 
 // NewGrid returns a new Grid of (gridWidth x gridHeight) size.
@@ -104,6 +201,26 @@ func NewGridFromRuneArrays(arrays [][]rune) *Grid {
 
 }
 
+// NewGridFromFunc creates a Grid of (gridWidth x gridHeight) size, whose Cells are returned by the generator, e.g. for
+// procedural worlds defining their terrain with noise or formulas. The generator is called once for each position;
+// the X and Y of the returned Cell are overwritten with the position.
+func NewGridFromFunc(gridWidth, gridHeight int, generator func(x, y int) Cell) *Grid {
+
+	m := &Grid{}
+
+	for y := 0; y < gridHeight; y++ {
+		m.Data = append(m.Data, make([]*Cell, 0, gridWidth))
+		for x := 0; x < gridWidth; x++ {
+			cell := generator(x, y)
+			cell.X, cell.Y = x, y
+			m.Data[y] = append(m.Data[y], &cell)
+		}
+	}
+
+	return m
+
+}
+
 // AddHeightMap adds a height to the grid via a key-value map. All runes, the map contains, do have an assigned height.
 // This height is applied to ALL cells with this rune. After the execution of this method, letters aren't bound to the height;
 // they are no pointers. If you change a letter, the height will stay the same.
@@ -113,12 +230,26 @@ func (m *Grid) AddHeightMap(profile map[rune]int) {
 	//loop trough all cells
 	for _, cell := range m.AllCells() {
 		//check if the map contains the rune of the cell
-		heightLevel, exists := profile[cell.Rune]
+		heightLevel, exists := profile[m.pending(cell).Rune]
 		if exists {
-			cell.HeightLevel = heightLevel
+			m.Stage(cell).HeightLevel = heightLevel
 		}
 	}
 
+	m.markCellsChanged()
+
+}
+
+// Revision returns the revision of the Grid, which is increased each time the Grid is changed by one of its methods.
+// Cells changed directly (e.g. grid.Get(1, 2).Cost = 5) can't be noticed by the Grid; call MarkChanged after such edits.
+// The revision allows to detect, if results calculated earlier are still up-to-date.
+func (m *Grid) Revision() uint64 {
+	return atomic.LoadUint64(&m.revision)
+}
+
+// MarkChanged increases the revision of the Grid. It has to be called after Cells have been changed directly.
+func (m *Grid) MarkChanged() {
+	atomic.AddUint64(&m.revision, 1)
 }
 
 // DataToString returns a string, used to easily identify the Grid map.
@@ -234,6 +365,31 @@ func (m *Grid) GetAverageHeight() float64 {
 
 }
 
+// HeightAt returns the height at any position of the Grid by bilinear interpolation between the TotalHeight of the
+// four surrounding cells. Cell positions are the integer coordinates, so HeightAt(2, 3) is the height of the cell at
+// X:2 Y:3, while HeightAt(2.5, 3) is halfway between this cell and its right neighbor. Positions outside the Grid
+// are clamped to the border.
+func (m *Grid) HeightAt(x, y float64) float64 {
+
+	x = math.Max(0, math.Min(float64(m.Width()-1), x))
+	y = math.Max(0, math.Min(float64(m.Height()-1), y))
+
+	x0, y0 := int(x), int(y)
+	x1, y1 := x0+1, y0+1
+	if x1 >= m.Width() {
+		x1 = x0
+	}
+	if y1 >= m.Height() {
+		y1 = y0
+	}
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	top := m.Get(x0, y0).TotalHeight()*(1-fx) + m.Get(x1, y0).TotalHeight()*fx
+	bottom := m.Get(x0, y1).TotalHeight()*(1-fx) + m.Get(x1, y1).TotalHeight()*fx
+
+	return top*(1-fy) + bottom*fy
+}
+
 // GetMaxHeight returns the maximum height of the whole Grid
 func (m *Grid) GetMaxHeight() int {
 
@@ -373,256 +529,1038 @@ func (m *Grid) SetWalkable(char rune, walkable bool) {
 
 		for x := 0; x < m.Width(); x++ {
 			cell := m.Get(x, y)
-			if cell.Rune == char {
-				cell.Walkable = walkable
+			if m.pending(cell).Rune == char {
+				m.Stage(cell).Walkable = walkable
 			}
 		}
 
 	}
 
+	m.markCellsChanged()
+
 }
 
 // SetHeightLevel sets the height level for all cells in the Grid with the specified rune.
 func (m *Grid) SetHeightLevel(char rune, heightLevel int) {
 
 	for _, cell := range m.AllCells() {
-		if cell.Rune == char {
-			cell.HeightLevel = heightLevel
+		if m.pending(cell).Rune == char {
+			m.Stage(cell).HeightLevel = heightLevel
 		}
 	}
 
+	m.markCellsChanged()
+
 }
 
 // SetCost sets the movement cost across all cells in the Grid with the specified rune.
 func (m *Grid) SetCost(char rune, cost float64) {
 
+	previousMinCost, known := m.cachedMinCost()
+	changed := false
+
 	for y := 0; y < m.Height(); y++ {
 
 		for x := 0; x < m.Width(); x++ {
 			cell := m.Get(x, y)
-			if cell.Rune == char {
-				cell.Cost = cost
+			if m.pending(cell).Rune == char {
+				m.Stage(cell).Cost = cost
+				changed = true
 			}
 		}
 
 	}
 
+	m.markCellsChanged()
+
+	// if the new cost is the new minimum, there is no need to check all the cells again
+	if known && changed && cost <= previousMinCost {
+		m.updateMinCost(cost)
+	}
+
+}
+
+// ScaleCosts multiplies the cost of all cells in the Grid with the passed factor.
+func (m *Grid) ScaleCosts(factor float64) {
+
+	previousMinCost, known := m.cachedMinCost()
+
+	for _, cell := range m.AllCells() {
+		m.Stage(cell).Cost *= factor
+	}
+
+	m.markCellsChanged()
+
+	if known && factor >= 0 {
+		m.updateMinCost(previousMinCost * factor)
+	}
+
+}
+
+// NormalizeCosts linearly maps the costs of all cells in the Grid to the range from min to max: The cheapest cell
+// ends up with a cost of min, the most expensive one with a cost of max. If all cells share the same cost, they all
+// get a cost of min.
+func (m *Grid) NormalizeCosts(min, max float64) {
+
+	cells := m.AllCells()
+	if len(cells) == 0 {
+		return
+	}
+
+	lowest, highest := m.pending(cells[0]).Cost, m.pending(cells[0]).Cost
+	for _, cell := range cells {
+		lowest = math.Min(lowest, m.pending(cell).Cost)
+		highest = math.Max(highest, m.pending(cell).Cost)
+	}
+
+	for _, cell := range cells {
+		cell = m.Stage(cell)
+		if highest == lowest {
+			cell.Cost = min
+		} else {
+			cell.Cost = min + (cell.Cost-lowest)/(highest-lowest)*(max-min)
+		}
+	}
+
+	m.markCellsChanged()
+	m.updateMinCost(math.Min(min, max))
+
+}
+
+// ClampCosts limits the costs of all cells in the Grid to the range from min to max. Costs outside of this range
+// are set to the nearest bound, all other costs stay untouched.
+func (m *Grid) ClampCosts(min, max float64) {
+
+	previousMinCost, known := m.cachedMinCost()
+
+	for _, cell := range m.AllCells() {
+		cell = m.Stage(cell)
+		cell.Cost = math.Max(min, math.Min(max, cell.Cost))
+	}
+
+	m.markCellsChanged()
+
+	if known {
+		m.updateMinCost(math.Max(min, math.Min(max, previousMinCost)))
+	}
+
+}
+
+// MinCost returns the lowest cost of all cells in the Grid, with negative costs counting as zero. Heuristics estimating
+// the remaining cost of a path have to multiply the amount of remaining moves with this cost; otherwise they may
+// overestimate on grids with costs below one and the pathfinding loses its optimality.
+//
+// The value is updated by the cost methods of the Grid (SetCost, ScaleCosts, ...) without checking all cells again
+// where possible. After other changes (including MarkChanged), it is recalculated the next time it is requested.
+func (m *Grid) MinCost() float64 {
+
+	if minCost, known := m.cachedMinCost(); known {
+		return minCost
+	}
+
+	minCost := math.Inf(1)
+	for _, cell := range m.AllCells() {
+		minCost = math.Min(minCost, cell.pathCost())
+	}
+	if math.IsInf(minCost, 1) {
+		minCost = 0
+	}
+
+	m.setMinCost(minCost)
+	return minCost
+}
+
+// cachedMinCost returns the last result of MinCost, if the Grid hasn't been changed since.
+func (m *Grid) cachedMinCost() (float64, bool) {
+
+	m.minCostsLock.Lock()
+	defer m.minCostsLock.Unlock()
+	return m.minCost, m.minCostKnown && m.minCostRevision == m.Revision()
+}
+
+// setMinCost stores the lowest cost of the Grid in its current revision. Negative costs count as zero.
+func (m *Grid) setMinCost(minCost float64) {
+
+	m.minCostsLock.Lock()
+	defer m.minCostsLock.Unlock()
+	m.minCost = math.Max(0, minCost)
+	m.minCostRevision = m.Revision()
+	m.minCostKnown = true
+}
+
+// updateMinCost is called by the methods changing costs with the new lowest cost. In staging mode, the cells don't
+// change until Commit is called, so the new cost isn't stored.
+func (m *Grid) updateMinCost(minCost float64) {
+	if m.staged == nil {
+		m.setMinCost(minCost)
+	}
+}
+
+// ErrInvalidCost is returned by Grid.ValidateCosts if cells with a negative or NaN cost are found.
+var ErrInvalidCost = errors.New("invalid cell cost")
+
+// ValidateCosts checks the costs of all cells in the Grid. Costs have to be zero or positive, otherwise an error wrapping
+// ErrInvalidCost is returned, which contains the amount of invalid cells and the first of them. The pathfinding treats
+// negative costs as zero, so this can be used to reject imported data before the paths turn out different than expected.
+// ClampCosts can be used to fix negative costs. The EnterCost and ExitCost of the cells are checked as well.
+func (m *Grid) ValidateCosts() error {
+
+	invalidCost := func(cost float64) bool {
+		return cost < 0 || math.IsNaN(cost)
+	}
+	var invalid []*Cell
+	for _, cell := range m.AllCells() {
+		if invalidCost(cell.Cost) || invalidCost(cell.EnterCost) || invalidCost(cell.ExitCost) {
+			invalid = append(invalid, cell)
+		}
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("%w: %d cells have a negative or NaN cost, the first one is at X:%d Y:%d (cost: %f)",
+			ErrInvalidCost, len(invalid), invalid[0].X, invalid[0].Y, invalid[0].Cost)
+	}
+	return nil
 }
 
 // GetPathFromCells returns a Path, from the starting Cell to the destination Cell. diagonals controls whether moving diagonally
 // is acceptable when creating the Path. wallsBlockDiagonals indicates whether to allow diagonal movement "through" walls that are
 // positioned diagonally. If stepHeight and/or dropHeight are negative, they will not be used in the calculation -> infinite drop and/or step height
+//
+// Deprecated: Use GetPathFromSettings, which supports all settings. NewPositionalPathSettings converts the arguments
+// of this function into PathSettings.
 func (m *Grid) GetPathFromCells(start, dest *Cell, stepHeight, dropHeight int, diagonals, wallsBlockDiagonals bool) *Path {
+	return m.findPath(NewPositionalPathSettings(start, dest, stepHeight, dropHeight, diagonals, wallsBlockDiagonals), nil)
+}
 
-	openNodes := minHeap{}
-	heap.Push(&openNodes, &Node{Cell: dest, Cost: dest.Cost})
+// searchObserver gets notified about the progress of a search. All of its functions are optional.
+type searchObserver struct {
+	// expanded is called for each cell, whose neighbors are checked by the search.
+	expanded func(cell *Cell)
+	// reached is called instead of writing the path, when the search ends at the cell with the passed index.
+	reached func(buffer *searchBuffer, index int32)
+}
 
-	checkedNodes := make([]*Cell, 0)
+// findPath searches a Path as described by the passed settings. This is where all the GetPath functions end up.
+// The observer is optional and can be nil. If the start or end isn't walkable, nil is returned.
+func (m *Grid) findPath(settings *PathSettings, observer *searchObserver) *Path {
 
-	hasBeenAdded := func(cell *Cell) bool {
+	path := &Path{}
+	m.search(path, settings, observer)
 
-		for _, c := range checkedNodes {
-			if cell == c {
-				return true
+	if !settings.endpointsWalkable() {
+		return nil
+	}
+	return path
+}
+
+// FindPathInto works like GetPathFromSettings, but instead of creating a new Path, the passed one is reset and filled.
+// All the memory needed by the search is reused as well, so if the same Path is passed again and again, searching
+// doesn't allocate any memory once the buffers have grown big enough. Returns if a path (complete or partial) has
+// been found.
+func (m *Grid) FindPathInto(dst *Path, settings *PathSettings) bool {
+	return m.search(dst, settings, nil)
+}
+
+// search runs the actual search and writes the resulting path into the passed Path. Returns if a path has been found.
+func (m *Grid) search(path *Path, settings *PathSettings, observer *searchObserver) (found bool) {
+
+	path.Cells = path.Cells[:0]
+	path.CurrentIndex = 0
+	path.StepHeight = int(settings.MaxStepHeight)
+	path.Partial = false
+	path.Postures = path.Postures[:0]
+
+	stats := SearchStats{}
+	// recorded searches are replays of slow queries themselves, so they aren't checked again
+	slowQueries := m.slowQueries
+	if observer != nil && observer.expanded != nil {
+		slowQueries = nil
+	}
+	if settings.OnSearchComplete != nil || slowQueries != nil {
+		began := time.Now()
+		defer func() {
+			stats.Duration = time.Since(began)
+			stats.Found = found && !path.Partial
+			stats.Partial = path.Partial
+			stats.PathLength = len(path.Cells)
+			if settings.OnSearchComplete != nil {
+				settings.OnSearchComplete(stats)
 			}
-		}
+			if slowQueries != nil {
+				slowQueries.check(m, settings, stats)
+			}
+		}()
+	}
+
+	if !settings.endpointsWalkable() {
 		return false
+	}
 
+	// iterative deepening doesn't need the memory of the buffer
+	if settings.Algorithm == IterativeDeepening {
+		return m.iterativeDeepening(path, settings, observer, &stats)
 	}
 
-	// returns if the cell is valid as next cell of the path.
-	// NOTE: "from" and "to" are not in direction of the final path. If you're looking at the whole path at the end, "to"
-	// will come before "from" because the path is constructed backwards (from end to start)
-	isCellValid := func(from, to *Node) bool {
+	buffer := m.searchBuffer()
+	defer m.searchBuffers.Put(buffer)
 
-		//check if the cell is walkable
-		if !to.Cell.Walkable {
+	if settings.Algorithm == FringeSearch {
+		return m.fringe(path, buffer, settings, observer, &stats)
+	}
+
+	if custom, _ := settings.Algorithm.custom(); custom != nil {
+		index, cost := m.customSearch(custom, buffer, settings, observer, &stats)
+		if index < 0 {
 			return false
 		}
-		//that the cell is not added a second time to the heap
-		if hasBeenAdded(to.Cell) {
-			return false
+		m.finishSearch(path, buffer, index, settings, observer)
+		stats.Cost = settings.unquantize(cost)
+		return true
+	}
+
+	state := m.newSearchState(buffer, settings, observer)
+	_, found = state.expand(path, &stats, -1)
+	return found
+
+}
+
+// searchState is the state of a search between the expansions of its cells, so it can be resumed, see Search.
+type searchState struct {
+	grid     *Grid
+	buffer   *searchBuffer
+	settings *PathSettings
+	observer *searchObserver
+	// heuristic estimates the remaining costs, jumps enables jump point search, anyAngle ThetaStar and bounded the
+	// GoalBounds of the settings.
+	heuristic                heuristic
+	jumps, anyAngle, bounded bool
+	reachedCells             int
+	// closest is the expanded cell closest to the destination, which is used if the memory limit is reached.
+	closest         int32
+	closestDistance float64
+}
+
+// newSearchState prepares a search with the buffer and adds the starts to the cells to check.
+func (m *Grid) newSearchState(buffer *searchBuffer, settings *PathSettings, observer *searchObserver) searchState {
+
+	s := searchState{
+		grid:            m,
+		buffer:          buffer,
+		settings:        settings,
+		observer:        observer,
+		heuristic:       m.newHeuristic(settings),
+		jumps:           m.jumpsAllowed(settings),
+		anyAngle:        settings.Algorithm == ThetaStar,
+		closestDistance: math.Inf(1),
+	}
+	if s.jumps {
+		m.prepareJumps(settings, buffer)
+	}
+	// the boxes only cover single moves, which jumps and straightened lines skip
+	s.bounded = settings.GoalBounds != nil && !s.jumps && !s.anyAngle && settings.GoalBounds.usable(m, settings)
+
+	start := settings.start
+	startIndex := m.cellIndex(start)
+	startCost := settings.quantize(m.cellCost(start, settings))
+	buffer.reach(startIndex, startCost, -1)
+	buffer.open.push(openItem{startIndex, startCost + s.heuristic.estimate(start)})
+	s.reachedCells = 1
+	s.closest = startIndex
+
+	// a search from multiple starts begins at all of them at once
+	for _, other := range settings.starts {
+		index := m.cellIndex(other)
+		if buffer.reached[index] == buffer.generation || !settings.assumeWalkable(other) {
+			continue
 		}
-		heightDifference := from.Cell.HeightLevel - to.Cell.HeightLevel
-		//check if the step height is not exceeded
-		if heightDifference > 0 && heightDifference > stepHeight {
-			return false
+		cost := settings.quantize(m.cellCost(other, settings))
+		buffer.reach(index, cost, -1)
+		buffer.open.push(openItem{index, cost + s.heuristic.estimate(other)})
+		s.reachedCells++
+	}
+
+	return s
+}
+
+// expand expands up to limit cells, or all cells needed if limit is negative, and counts them in the stats. Returns if
+// the search has ended and if a path (complete or partial) has been written into the Path then.
+func (s *searchState) expand(path *Path, stats *SearchStats, limit int) (done, found bool) {
+
+	m, buffer, settings, observer := s.grid, s.buffer, s.settings, s.observer
+	heuristic, jumps, anyAngle, bounded := &s.heuristic, s.jumps, s.anyAngle, s.bounded
+	dest := settings.end
+
+	for expanded := 0; len(buffer.open) > 0; {
+
+		if limit >= 0 && expanded >= limit {
+			return false, false
 		}
-		//check if the drop height is not exceeded
-		if heightDifference < 0 && int(math.Abs(float64(heightDifference))) > dropHeight {
-			return false
+
+		item := buffer.open.pop()
+		index := item.cell
+		cell := m.cellAt(index)
+
+		if settings.RequireOptimal {
+			// a cell may be pushed multiple times, if a cheaper way to it has been found later on. Only the first
+			// (cheapest) one counts.
+			if buffer.closed[index] == buffer.generation {
+				continue
+			}
+			buffer.closed[index] = buffer.generation
+		}
+
+		// If we've reached the destination, we just have to walk up the parents, which leads us back to the start.
+		if m.isGoal(cell, settings) {
+			m.finishSearch(path, buffer, index, settings, observer)
+			stats.Cost = settings.unquantize(buffer.costs[index])
+			return true, true
+		}
+
+		if dest != nil {
+			if distance := math.Hypot(float64(dest.X-cell.X), float64(dest.Y-cell.Y)); distance < s.closestDistance {
+				s.closest, s.closestDistance = index, distance
+			}
+		}
+
+		expanded++
+		stats.Expanded++
+		if observer != nil && observer.expanded != nil {
+			observer.expanded(cell)
+		}
+
+		// with jump point search, uniform cells only need to check the neighbors the path can continue to
+		pruning := false
+		px, py := 0, 0
+		if parent := buffer.parents[index]; jumps && parent >= 0 && m.uniform(cell, settings, buffer) {
+			pruning = true
+			px, py = sign(cell.X-m.cellAt(parent).X), sign(cell.Y-m.cellAt(parent).Y)
+		}
+
+		// Otherwise, we add the current cell's neighbors to the list of cells to check.
+		for direction, offset := range neighborOffsets {
+
+			if !settings.diagonals && offset[0] != 0 && offset[1] != 0 {
+				continue
+			}
+			if pruning && m.pruned(cell, px, py, offset[0], offset[1], settings, buffer) {
+				continue
+			}
+			if bounded && settings.GoalBounds.prunes(index, direction, dest) {
+				continue
+			}
+			neighbor := m.Get(cell.X+offset[0], cell.Y+offset[1])
+			if neighbor == nil || !m.canMove(cell, neighbor, settings) {
+				continue
+			}
+			cost := buffer.costs[index] + settings.quantize(m.moveCost(cell, neighbor, settings))
+			if jumps {
+				if neighbor, cost = m.jump(neighbor, offset[0], offset[1], cost, settings, buffer); neighbor == nil {
+					continue
+				}
+			}
+			parent := index
+			if anyAngle {
+				parent, cost = m.straightenParent(index, neighbor, cost, settings, buffer)
+			}
+
+			neighborIndex := m.cellIndex(neighbor)
+			if settings.corridor != nil && !settings.corridor[neighborIndex] {
+				continue
+			}
+
+			reached := buffer.reached[neighborIndex] == buffer.generation
+			if settings.RequireOptimal {
+				// only cheaper ways to a cell are worth checking again
+				if buffer.closed[neighborIndex] == buffer.generation || (reached && buffer.costs[neighborIndex] <= cost) {
+					continue
+				}
+			} else if reached {
+				// the first way to a cell is taken, which is fast but not necessarily the cheapest one
+				continue
+			}
+
+			if !reached {
+				if settings.MemoryLimit > 0 && s.reachedCells >= settings.MemoryLimit {
+					// no more cells can be tracked, so the search ends here with the best path found so far
+					m.finishSearch(path, buffer, s.closest, settings, observer)
+					path.Partial = true
+					stats.Cost = settings.unquantize(buffer.costs[s.closest])
+					return true, true
+				}
+				s.reachedCells++
+			}
+
+			buffer.reach(neighborIndex, cost, parent)
+			buffer.open.push(openItem{neighborIndex, cost + heuristic.estimate(neighbor)})
+			stats.Pushed++
 		}
 
-		return true
 	}
 
-	areDiagonalsValid := func(node *Node, diagonal1, diagonal2 *Cell) bool {
+	return true, false
 
-		//check if both of the diagonals are not walkable
-		if wallsBlockDiagonals {
-			if (diagonal2.Walkable == false) && (diagonal1.Walkable == false) {
-				return false
+}
+
+// finishSearch writes the path to the cell with the passed index into the Path, or hands it to the observer.
+func (m *Grid) finishSearch(path *Path, buffer *searchBuffer, index int32, settings *PathSettings, observer *searchObserver) {
+	if observer != nil && observer.reached != nil {
+		observer.reached(buffer, index)
+		return
+	}
+	m.writePath(path, buffer, index, settings)
+}
+
+// writePath writes the cells from the start of the search to the cell with the passed index into the Path.
+func (m *Grid) writePath(path *Path, buffer *searchBuffer, index int32, settings *PathSettings) {
+
+	for i := index; i >= 0; i = buffer.parents[i] {
+		cell := m.cellAt(i)
+		path.Cells = append(path.Cells, cell)
+		if parent := buffer.parents[i]; parent >= 0 && settings.restoresJumps() {
+			for line := newLineWalk(cell, m.cellAt(parent)); line.next(); {
+				path.Cells = append(path.Cells, m.Get(line.x, line.y))
 			}
 		}
+	}
 
-		//check if both of the diagonals are too high to step on
-		heightDifference1 := diagonal1.HeightLevel - node.Cell.HeightLevel
-		heightDifference2 := diagonal2.HeightLevel - node.Cell.HeightLevel
+	// the cells have been collected backwards
+	for i, j := 0, len(path.Cells)-1; i < j; i, j = i+1, j-1 {
+		path.Cells[i], path.Cells[j] = path.Cells[j], path.Cells[i]
+	}
 
-		if (heightDifference1 > stepHeight) && (heightDifference2 > stepHeight) {
-			return false
+	m.finishCells(path, settings)
+}
+
+// restoresJumps returns if the cells between a Cell of a found path and its parent have to be restored: the cells
+// skipped by jumps (see JumpPointSearch) lie on the straight line to the parent, while the straight lines of ThetaStar
+// are returned as they are.
+func (settings *PathSettings) restoresJumps() bool {
+	return settings.Algorithm != ThetaStar
+}
+
+// A lineWalk steps along the straight (horizontal, vertical or diagonal) line from one Cell to another, e.g. to restore
+// the cells skipped by a jump.
+type lineWalk struct {
+	x, y, toX, toY int
+}
+
+// newLineWalk returns a lineWalk standing on the Cell "from".
+func newLineWalk(from, to *Cell) lineWalk {
+	return lineWalk{from.X, from.Y, to.X, to.Y}
+}
+
+// next moves to the next position of the line. It returns false once the Cell "to" has been reached, which isn't part
+// of the walk.
+func (l *lineWalk) next() bool {
+	l.x, l.y = l.x+sign(l.toX-l.x), l.y+sign(l.toY-l.y)
+	return l.x != l.toX || l.y != l.toY
+}
+
+// finishCells reduces the cells of the Path to its waypoints and adds the postures, if the settings ask for it.
+func (m *Grid) finishCells(path *Path, settings *PathSettings) {
+
+	if settings.WaypointsOnly && len(path.Cells) > 2 {
+		// the waypoints are moved to the front; previous keeps the cell before the current one, which may have been
+		// overwritten already
+		waypoints, previous := 1, path.Cells[0]
+		for i := 1; i < len(path.Cells)-1; i++ {
+			cell := path.Cells[i]
+			if m.isWaypoint(previous, cell, path.Cells[i+1], settings) {
+				path.Cells[waypoints] = cell
+				waypoints++
+			}
+			previous = cell
 		}
+		path.Cells[waypoints] = path.Cells[len(path.Cells)-1]
+		path.Cells = path.Cells[:waypoints+1]
+	}
+
+	if len(settings.Postures) > 0 {
+		for _, cell := range path.Cells {
+			path.Postures = append(path.Postures, settings.postureName(cell.Clearance))
+		}
+	}
+}
+
+// isWaypoint returns if the path changes its direction or posture at the Cell, see PathSettings.WaypointsOnly.
+func (m *Grid) isWaypoint(previous, cell, next *Cell, settings *PathSettings) bool {
 
+	if cell.X-previous.X != next.X-cell.X || cell.Y-previous.Y != next.Y-cell.Y {
 		return true
 	}
+	return len(settings.Postures) > 0 && settings.postureName(cell.Clearance) != settings.postureName(previous.Clearance)
+}
 
-	path := &Path{StepHeight: stepHeight}
+// cellIndex returns the index of the cell in the buffers of a search.
+func (m *Grid) cellIndex(cell *Cell) int32 {
+	return int32(cell.Y*m.Width() + cell.X)
+}
 
-	if !start.Walkable || !dest.Walkable {
-		return nil
+// cellAt returns the cell with the passed index in the buffers of a search.
+func (m *Grid) cellAt(index int32) *Cell {
+	return m.Data[int(index)/m.Width()][int(index)%m.Width()]
+}
+
+// searchBuffer returns an unused searchBuffer, which is big enough for the Grid.
+func (m *Grid) searchBuffer() *searchBuffer {
+
+	buffer, _ := m.searchBuffers.Get().(*searchBuffer)
+	if buffer == nil {
+		buffer = &searchBuffer{}
 	}
 
-	for {
+	size := m.Width() * m.Height()
+	if len(buffer.costs) != size {
+		*buffer = searchBuffer{
+			reached: make([]uint32, size),
+			closed:  make([]uint32, size),
+			costs:   make([]float64, size),
+			parents: make([]int32, size),
+			open:    buffer.open,
+		}
+	}
 
-		// If the list of openNodes (nodes to check) is at 0, then we've checked all Nodes, and so the function can quit.
-		if len(openNodes) == 0 {
-			break
+	buffer.open = buffer.open[:0]
+	buffer.generation++
+	if buffer.generation == 0 {
+		// after an overflow, old stamps could be mistaken as current ones
+		for i := range buffer.reached {
+			buffer.reached[i], buffer.closed[i] = 0, 0
 		}
+		buffer.generation = 1
+	}
 
-		node := heap.Pop(&openNodes).(*Node)
+	return buffer
+}
 
-		// If we've reached the start, then we've constructed our Path going from the destination to the start; we just have
-		// to loop through each Node and go up, adding it and its parents recursively to the path.
-		if node.Cell == start {
+// A searchBuffer contains the memory used by a search, which is reused by the following searches on the same Grid.
+// Instead of clearing all the data after each search, each search has its own generation. Cells marked with an older
+// generation are treated as not reached (or closed).
+type searchBuffer struct {
+	generation uint32
+	// reached and closed contain the generation, in which each cell has been reached or closed.
+	reached, closed []uint32
+	// costs and parents contain the cost of the cheapest way to each cell and the index of the cell before.
+	costs   []float64
+	parents []int32
+	open    openList
+	// jumpCells contains what jump point search knows about each cell. It's only allocated for jump point search.
+	// Entries are valid, if they have been stamped with the jumpGeneration, which only changes if the Grid or the
+	// settings change, see Grid.prepareJumps. jumpKey identifies the Grid and settings.
+	jumpCells      []jumpCell
+	jumpGeneration uint32
+	jumpKey        string
+	// fringeNext and fringePrevious link the cells in the fringe of a FringeSearch. They are only allocated for it.
+	fringeNext, fringePrevious []int32
+}
 
-			var t = node
-			for true {
-				path.Cells = append(path.Cells, t.Cell)
-				t = t.Parent
-				if t == nil {
-					break
-				}
-			}
+// reach marks the cell with the passed index as reached with the passed cost and parent.
+func (buffer *searchBuffer) reach(index int32, cost float64, parent int32) {
+	buffer.reached[index] = buffer.generation
+	buffer.costs[index] = cost
+	buffer.parents[index] = parent
+}
+
+// An openItem is a cell in the openList.
+type openItem struct {
+	cell int32
+	// cost is the priority of the cell: its cost plus the estimated remaining cost, see AStar.
+	cost float64
+}
 
+// openList is a min-heap of cells to check. It works like container/heap, but without converting the items to
+// interfaces, which would need memory allocations.
+type openList []openItem
+
+func (l *openList) push(item openItem) {
+
+	*l = append(*l, item)
+
+	h := *l
+	for j := len(h) - 1; j > 0; {
+		i := (j - 1) / 2 // parent
+		if i == j || !(h[j].cost < h[i].cost) {
 			break
 		}
+		h[i], h[j] = h[j], h[i]
+		j = i
+	}
+}
 
-		// Otherwise, we add the current node's neighbors to the list of cells to check, and list of cells that have already been
-		// checked (so we don't get nodes being checked multiple times).
-		if node.Cell.X > 0 {
-			c := m.Get(node.Cell.X-1, node.Cell.Y)
-			n := &Node{c, node, c.Cost + node.Cost}
-			if isCellValid(node, n) {
-				heap.Push(&openNodes, n)
-				checkedNodes = append(checkedNodes, n.Cell)
-			}
+func (l *openList) pop() openItem {
+
+	h := *l
+	n := len(h) - 1
+	h[0], h[n] = h[n], h[0]
+
+	for i := 0; ; {
+		j1 := 2*i + 1
+		if j1 >= n || j1 < 0 {
+			break
 		}
-		if node.Cell.X < m.Width()-1 {
-			c := m.Get(node.Cell.X+1, node.Cell.Y)
-			n := &Node{c, node, c.Cost + node.Cost}
-			if isCellValid(node, n) {
-				heap.Push(&openNodes, n)
-				checkedNodes = append(checkedNodes, n.Cell)
-			}
+		j := j1 // left child
+		if j2 := j1 + 1; j2 < n && h[j2].cost < h[j1].cost {
+			j = j2 // right child
+		}
+		if !(h[j].cost < h[i].cost) {
+			break
+		}
+		h[i], h[j] = h[j], h[i]
+		i = j
+	}
+
+	item := h[n]
+	*l = h[:n]
+	return item
+}
+
+// diagonalCost is added to the cost of each diagonal move. Diagonal movement is slightly slower, so we should prioritize
+// straightaways if possible.
+const diagonalCost = .414
+
+// neighbors returns all cells next to the passed cell. Diagonal neighbors are only included if diagonals is true.
+func (m *Grid) neighbors(cell *Cell, diagonals bool) []*Cell {
+
+	neighbors := make([]*Cell, 0, 8)
+
+	for _, offset := range neighborOffsets {
+		if !diagonals && offset[0] != 0 && offset[1] != 0 {
+			continue
 		}
+		if n := m.Get(cell.X+offset[0], cell.Y+offset[1]); n != nil {
+			neighbors = append(neighbors, n)
+		}
+	}
+
+	return neighbors
+}
+
+// neighborOffsets contains the x and y offsets of all eight neighbors of a cell.
+var neighborOffsets = [8][2]int{
+	{-1, 0}, {1, 0}, {0, -1}, {0, 1},
+	{-1, -1}, {1, -1}, {-1, 1}, {1, 1},
+}
+
+// BlockReason describes, why a move from one cell to a neighboring one isn't possible.
+type BlockReason int
+
+const (
+	// NotBlocked means, that the move is possible.
+	NotBlocked BlockReason = iota
+	// BlockedNotWalkable means, that the cell moved to isn't walkable.
+	BlockedNotWalkable
+	// BlockedOccupied means, that the cell moved to has reached PathSettings.MaxOccupancy.
+	BlockedOccupied
+	// BlockedStepHeight means, that the cell moved to is too high to step up.
+	BlockedStepHeight
+	// BlockedDropHeight means, that the cell moved to is too low to drop down.
+	BlockedDropHeight
+	// BlockedWallCorner means, that a diagonal move would go "through" two walls.
+	BlockedWallCorner
+	// BlockedHeightCorner means, that a diagonal move would go past two cells, which are too high to step on.
+	BlockedHeightCorner
+	// BlockedDirection means, that the move goes in a direction, which isn't contained in PathSettings.Directions.
+	BlockedDirection
+	// BlockedCategory means, that the cell moved to belongs to one of PathSettings.BlockedCategories.
+	BlockedCategory
+	// BlockedClearance means, that the ceiling above the cell moved to is lower than PathSettings.AgentHeight.
+	BlockedClearance
+	// BlockedWater means, that the cell moved to is land or water, which can't be traversed with PathSettings.Water.
+	BlockedWater
+	// BlockedUncertain means, that the cell moved to is blocked too likely, see PathSettings.MinTraversalProbability.
+	BlockedUncertain
+	// BlockedUnknown means, that the cell moved to is unknown and PathSettings.UnknownCells is PessimisticUnknown.
+	BlockedUnknown
+	// BlockedEdge means, that the move has been blocked with Grid.SetEdgeCost.
+	BlockedEdge
+)
+
+func (reason BlockReason) String() string {
+	switch reason {
+	case NotBlocked:
+		return "not blocked"
+	case BlockedNotWalkable:
+		return "not walkable"
+	case BlockedOccupied:
+		return "occupied"
+	case BlockedStepHeight:
+		return "step too high"
+	case BlockedDropHeight:
+		return "drop too deep"
+	case BlockedWallCorner:
+		return "diagonal through walls"
+	case BlockedHeightCorner:
+		return "diagonal past high cells"
+	case BlockedDirection:
+		return "direction not allowed"
+	case BlockedCategory:
+		return "category blocked"
+	case BlockedClearance:
+		return "ceiling too low"
+	case BlockedWater:
+		return "water profile forbids it"
+	case BlockedUncertain:
+		return "probably blocked"
+	case BlockedUnknown:
+		return "unknown"
+	case BlockedEdge:
+		return "edge blocked"
+	}
+	return fmt.Sprintf("BlockReason(%d)", int(reason))
+}
 
-		if node.Cell.Y > 0 {
-			c := m.Get(node.Cell.X, node.Cell.Y-1)
-			n := &Node{c, node, c.Cost + node.Cost}
-			if isCellValid(node, n) {
-				heap.Push(&openNodes, n)
-				checkedNodes = append(checkedNodes, n.Cell)
+// canMove returns if a path can go from one cell to the neighboring cell "to" with the passed settings.
+func (m *Grid) canMove(from, to *Cell, settings *PathSettings) bool {
+	return m.checkMove(from, to, settings) == NotBlocked
+}
+
+// checkMove returns why a path can't go from one cell to the neighboring cell "to" with the passed settings, or
+// NotBlocked if it can.
+func (m *Grid) checkMove(from, to *Cell, settings *PathSettings) BlockReason {
+
+	if !settings.allowsDirection(to.X-from.X, to.Y-from.Y) {
+		return BlockedDirection
+	}
+	if m.edgeBlocked(from, to) {
+		return BlockedEdge
+	}
+	if reason := checkStep(m, m, from, to, settings); reason != NotBlocked {
+		return reason
+	}
+
+	//diagonal moves need to check the two cells they are moving past
+	if from.X != to.X && from.Y != to.Y {
+
+		diagonal1 := m.Get(from.X, to.Y)
+		diagonal2 := m.Get(to.X, from.Y)
+
+		//check if both of the diagonals are not walkable
+		if settings.wallBlocksDiagonals {
+			if !settings.assumeWalkable(diagonal1) && !settings.assumeWalkable(diagonal2) {
+				return BlockedWallCorner
 			}
 		}
-		if node.Cell.Y < m.Height()-1 {
-			c := m.Get(node.Cell.X, node.Cell.Y+1)
-			n := &Node{c, node, c.Cost + node.Cost}
-			if isCellValid(node, n) {
-				heap.Push(&openNodes, n)
-				checkedNodes = append(checkedNodes, n.Cell)
+
+		//check if both of the diagonals are too high to step on
+		if settings.MaxStepHeight >= 0 {
+			heightDifference1 := diagonal1.TotalHeight() - to.TotalHeight()
+			heightDifference2 := diagonal2.TotalHeight() - to.TotalHeight()
+
+			if (heightDifference1 > settings.MaxStepHeight) && (heightDifference2 > settings.MaxStepHeight) {
+				return BlockedHeightCorner
 			}
 		}
 
-		// Do the same thing for diagonals.
-		if diagonals {
+	}
 
-			diagonalCost := .414 // Diagonal movement is slightly slower, so we should prioritize straightaways if possible
+	return NotBlocked
+}
 
-			up := m.Get(node.Cell.X, node.Cell.Y-1)
-			down := m.Get(node.Cell.X, node.Cell.Y+1)
-			left := m.Get(node.Cell.X-1, node.Cell.Y)
-			right := m.Get(node.Cell.X+1, node.Cell.Y)
+// checkStep checks the rules of checkMove, which only depend on the two cells and not on their surroundings. This
+// allows to check moves between cells of different grids as well: from belongs to fromGrid, to belongs to toGrid.
+func checkStep(fromGrid, toGrid *Grid, from, to *Cell, settings *PathSettings) BlockReason {
 
-			if node.Cell.X > 0 && node.Cell.Y > 0 {
-				c := m.Get(node.Cell.X-1, node.Cell.Y-1)
-				n := &Node{c, node, c.Cost + node.Cost + diagonalCost}
-				if isCellValid(node, n) && areDiagonalsValid(node, left, up) {
-					heap.Push(&openNodes, n)
-					checkedNodes = append(checkedNodes, n.Cell)
-				}
+	//check if the cell is walkable
+	if !settings.assumeWalkable(to) {
+		return BlockedNotWalkable
+	}
+	//check if the cost of the move can be paid at all
+	if math.IsInf(toGrid.cellCost(to, settings)+transitionCost(from, to), 1) {
+		return BlockedNotWalkable
+	}
+	//check if the cell is known and walkable likely enough
+	if settings.isUnknown(to) {
+		if settings.UnknownCells == PessimisticUnknown {
+			return BlockedUnknown
+		}
+	} else if settings.MinTraversalProbability > 0 && 1-to.BlockProbability < settings.MinTraversalProbability {
+		return BlockedUncertain
+	}
+	//check if there is space left on the cell
+	if settings.MaxOccupancy > 0 && to.Occupancy >= settings.MaxOccupancy {
+		return BlockedOccupied
+	}
+	//check if the agent fits below the ceiling
+	if !fitsClearance(to.Clearance, settings) {
+		return BlockedClearance
+	}
+	if toGrid.blockedByCategory(to, settings) {
+		return BlockedCategory
+	}
+	if !toGrid.canTraverseWater(to, settings) {
+		return BlockedWater
+	}
+
+	// on water, the heights of the water surfaces are compared
+	heightDifference := toGrid.surfaceHeight(to, settings) - fromGrid.surfaceHeight(from, settings)
+	//check if the step height is not exceeded. Negative step heights are infinite.
+	if settings.MaxStepHeight >= 0 && heightDifference > settings.MaxStepHeight {
+		return BlockedStepHeight
+	}
+	//check if the drop height is not exceeded. Negative drop heights are infinite.
+	if settings.MaxDropHeight >= 0 && -heightDifference > settings.MaxDropHeight {
+		return BlockedDropHeight
+	}
+
+	return NotBlocked
+}
+
+// fitsClearance returns if an agent with the AgentHeight of the settings fits below a ceiling with the passed clearance,
+// either upright or in one of the postures. A clearance of zero is unlimited.
+func fitsClearance(clearance float64, settings *PathSettings) bool {
+	_, fits := settings.posture(clearance)
+	return fits
+}
+
+// moveCost returns the cost of moving from one cell to the neighboring cell "to". It doesn't check if the move is
+// possible at all, use canMove for that.
+func (m *Grid) moveCost(from, to *Cell, settings *PathSettings) float64 {
+
+	cost := moveCostBelow(from, to, m.enteringCost(from, to, settings), to.Clearance, settings)
+	if settings.Water != nil && settings.Water.WaterCostMultiplier > 0 && m.depth(to, settings) > 0 {
+		cost *= settings.Water.WaterCostMultiplier
+	}
+	return cost
+}
+
+// moveCostBelow works like moveCost, but with the cost of entering "to" (see enteringCost) and its clearance passed
+// separately.
+func moveCostBelow(from, to *Cell, cellCost, clearance float64, settings *PathSettings) float64 {
+
+	cost := cellCost
+	if from.X != to.X && from.Y != to.Y {
+		cost += diagonalCost
+	}
+
+	if posture, _ := settings.posture(clearance); posture >= 0 {
+		cost *= settings.Postures[posture].CostMultiplier
+	}
+	return cost
+}
+
+// costsToGoals returns the cost of the cheapest path from each cell to the nearest of the passed goals. The costs of
+// the cells the paths start on aren't included, the costs of the goals are. Cells, from which no goal can be reached,
+// aren't contained in the returned map.
+func (m *Grid) costsToGoals(goals []*Cell, settings *PathSettings) map[*Cell]float64 {
+	costs, _ := m.searchToGoals(goals, settings)
+	return costs
+}
+
+// searchToGoals works like costsToGoals, but additionally returns the next cell on the cheapest path from each cell to
+// the nearest goal. The goals don't have a next cell.
+func (m *Grid) searchToGoals(goals []*Cell, settings *PathSettings) (map[*Cell]float64, map[*Cell]*Cell) {
+
+	costs := make(map[*Cell]float64)
+	next := make(map[*Cell]*Cell)
+	openNodes := minHeap{}
+
+	for _, goal := range goals {
+		costs[goal] = 0
+		heap.Push(&openNodes, &Node{Cell: goal})
+	}
+
+	closed := make(map[*Cell]bool)
+
+	for len(openNodes) > 0 {
+
+		node := heap.Pop(&openNodes).(*Node)
+		if closed[node.Cell] {
+			continue
+		}
+		closed[node.Cell] = true
+
+		// the search goes backwards: each neighbor, which can move to the current cell, is checked
+		for _, neighbor := range m.neighbors(node.Cell, settings.diagonals) {
+
+			if closed[neighbor] || !settings.assumeWalkable(neighbor) || !m.canMove(neighbor, node.Cell, settings) {
+				continue
 			}
 
-			if node.Cell.X < m.Width()-1 && node.Cell.Y > 0 {
-				c := m.Get(node.Cell.X+1, node.Cell.Y-1)
-				n := &Node{c, node, c.Cost + node.Cost + diagonalCost}
-				if isCellValid(node, n) && areDiagonalsValid(node, right, up) {
-					heap.Push(&openNodes, n)
-					checkedNodes = append(checkedNodes, n.Cell)
-				}
+			cost := node.Cost + settings.quantize(m.moveCost(neighbor, node.Cell, settings))
+			if previousCost, reached := costs[neighbor]; reached && previousCost <= cost {
+				continue
 			}
 
-			if node.Cell.X > 0 && node.Cell.Y < m.Height()-1 {
-				c := m.Get(node.Cell.X-1, node.Cell.Y+1)
-				n := &Node{c, node, c.Cost + node.Cost + diagonalCost}
-				if isCellValid(node, n) && areDiagonalsValid(node, left, down) {
-					heap.Push(&openNodes, n)
-					checkedNodes = append(checkedNodes, n.Cell)
-				}
+			costs[neighbor] = cost
+			next[neighbor] = node.Cell
+			heap.Push(&openNodes, &Node{Cell: neighbor, Parent: node, Cost: cost})
+		}
+	}
+
+	if settings.FixedPoint {
+		for cell, cost := range costs {
+			costs[cell] = settings.unquantize(cost)
+		}
+	}
+
+	return costs, next
+}
+
+// costsFromStart is the forward counterpart of costsToGoals: it returns the cost of the cheapest path from the start
+// cell to each cell. Cells, which can't be reached, aren't contained in the returned map.
+func (m *Grid) costsFromStart(start *Cell, settings *PathSettings) map[*Cell]float64 {
+
+	costs := map[*Cell]float64{start: 0}
+	openNodes := minHeap{}
+	heap.Push(&openNodes, &Node{Cell: start})
+
+	closed := make(map[*Cell]bool)
+
+	for len(openNodes) > 0 {
+
+		node := heap.Pop(&openNodes).(*Node)
+		if closed[node.Cell] {
+			continue
+		}
+		closed[node.Cell] = true
+
+		for _, neighbor := range m.neighbors(node.Cell, settings.diagonals) {
+
+			if closed[neighbor] || !m.canMove(node.Cell, neighbor, settings) {
+				continue
 			}
 
-			if node.Cell.X < m.Width()-1 && node.Cell.Y < m.Height()-1 {
-				c := m.Get(node.Cell.X+1, node.Cell.Y+1)
-				n := &Node{c, node, c.Cost + node.Cost + diagonalCost}
-				if isCellValid(node, n) && areDiagonalsValid(node, right, down) {
-					heap.Push(&openNodes, n)
-					checkedNodes = append(checkedNodes, n.Cell)
-				}
+			cost := node.Cost + settings.quantize(m.moveCost(node.Cell, neighbor, settings))
+			if previousCost, reached := costs[neighbor]; reached && previousCost <= cost {
+				continue
 			}
 
+			costs[neighbor] = cost
+			heap.Push(&openNodes, &Node{Cell: neighbor, Parent: node, Cost: cost})
 		}
-
 	}
 
-	return path
+	if settings.FixedPoint {
+		for cell, cost := range costs {
+			costs[cell] = settings.unquantize(cost)
+		}
+	}
 
+	return costs
 }
 
 // GetPath returns a Path, from the starting cell's X and Y to the ending cell's X and Y. diagonals controls whether
 // moving diagonally is acceptable when creating the Path. wallsBlockDiagonals indicates whether to allow diagonal movement "through" walls
 // that are positioned diagonally. This is essentially just a smoother way to get a Path from GetPathFromCells().
+//
+// Deprecated: Use GetPathFromSettings, which supports all settings. NewPositionalPathSettings converts the arguments
+// of this function into PathSettings, once the positions have been turned into cells with Get.
 func (m *Grid) GetPath(startX, startY, endX, endY float64, stepHeight, dropHeight int, diagonals, wallsBlockDiagonals bool) *Path {
 
 	sc := m.Get(int(startX), int(startY))
 	ec := m.Get(int(endX), int(endY))
 
 	if sc != nil && ec != nil {
-		return m.GetPathFromCells(sc, ec, stepHeight, dropHeight, diagonals, wallsBlockDiagonals)
+		return m.findPath(NewPositionalPathSettings(sc, ec, stepHeight, dropHeight, diagonals, wallsBlockDiagonals), nil)
 	}
 	return nil
 }
 
 // GetPathFromSettings returns a Path, from the starting Cell to the ending Cell. The starting and ending Cells as well as
-// the other parameters (stepHeight, dropHeight, diagonals, wallsBlockDiagonals) are read from the passed PathSettings
+// the other parameters (MaxStepHeight, MaxDropHeight, diagonals, wallsBlockDiagonals) are read from the passed PathSettings
 // object.
 func (m *Grid) GetPathFromSettings(settings PathSettings) *Path {
-	return m.GetPathFromCells(
-		settings.start,
-		settings.end,
-		settings.stepHeight,
-		settings.dropHeight,
-		settings.diagonals,
-		settings.wallBlocksDiagonals,
-	)
+	return m.findPath(&settings, nil)
 }
 
 // DataAsStringArray returns a 2D array of runes for each Cell in the Grid. The first axis is the Y axis.
@@ -662,6 +1600,23 @@ func (m *Grid) DataAsRuneArrays() [][]rune {
 type Path struct {
 	Cells                    []*Cell
 	CurrentIndex, StepHeight int
+	// Partial is true if the search has been stopped before reaching the destination, e.g. because of
+	// PathSettings.MemoryLimit. The Path then leads to the cell closest to the destination found so far.
+	Partial bool
+	// Postures contains the name of the posture (see PathSettings.Postures) used on each Cell, or an empty string where
+	// the agent stands upright. It's only filled if the settings contain postures.
+	Postures []string
+}
+
+// CostVariance returns the variance of the total cost of the Path, i.e. the sum of the CostVariance of all the Cells,
+// assuming their costs are independent. Its square root is the standard deviation of the total cost.
+func (p *Path) CostVariance() float64 {
+
+	variance := 0.0
+	for _, cell := range p.Cells {
+		variance += cell.CostVariance
+	}
+	return variance
 }
 
 // TotalCost returns the total cost of the Path (i.e. is the sum of all the Cells in the Path).
@@ -800,35 +1755,299 @@ func (p *Path) IsAtEnd() bool {
 type PathSettings struct {
 	// start and end are the start and end Cells of the path.
 	start, end *Cell
-	// stepHeight is the maximum height difference between two Cells that can be stepped up or down.
-	stepHeight int
-	// dropHeight is the maximum height difference between two Cells that can be dropped down.
-	dropHeight int
+	// MaxStepHeight is the maximum height difference between two Cells that can be stepped up. The heights of the
+	// Cells are compared with Cell.TotalHeight, so steps between height levels are possible. A negative value
+	// allows infinite step heights.
+	MaxStepHeight float64
+	// MaxDropHeight is the maximum height difference between two Cells that can be dropped down. A negative value
+	// allows infinite drop heights.
+	MaxDropHeight float64
 	// diagonals setting defines whether diagonal movement is allowed.
 	diagonals bool
 	// If this parameter is set to true, diagonal movements will be able "trough" walls. If diagonals is disabled, this
 	// setting doesn't have any impact.
 	wallBlocksDiagonals bool
+	// Goal turns the destination into a region: the search ends at the first Cell the Goal returns true for (or at the
+	// end Cell), e.g. any Cell next to a chest or inside a room. Use NewGoalPathSettings to create settings without an
+	// end Cell.
+	Goal GoalFunc
+	// goals are the cells of a search for the nearest of them, see Grid.GetPathToNearest. The Goal accepts them, while
+	// the heuristic estimates the remaining cost to the closest one.
+	goals []*Cell
+	// starts are the cells of a search from the nearest of them, see Grid.GetPathFromNearest. The search begins at all
+	// of them besides the start.
+	starts []*Cell
+	// StopWithinRange ends the search at the first Cell within this distance of the end Cell, e.g. for ranged units
+	// moving to a firing position instead of the target itself. The end Cell doesn't need to be walkable then. Zero
+	// disables it.
+	StopWithinRange float64
+	// RangeMetric is the metric used for StopWithinRange.
+	RangeMetric DistanceMetric
+	// RequireLineOfSight additionally requires a line of sight (see Grid.LineOfSight) from the Cell to the end Cell
+	// for StopWithinRange.
+	RequireLineOfSight bool
+	// RequireOptimal guarantees, that the returned path is a cheapest path from start to end. Without it, the first way
+	// found to a cell is kept, which is faster but can lead to slightly more expensive paths on grids with varying
+	// costs. Every other setting, which trades path quality for speed, is ignored while RequireOptimal is set.
+	RequireOptimal bool
+	// MaxOccupancy is the maximum amount of agents, which can stand on one Cell at once. Cells whose Occupancy
+	// reached this limit are avoided, so choke points make agents queue up instead of stacking all of them on one
+	// Cell. Zero means unlimited.
+	MaxOccupancy int
+	// MemoryLimit is the maximum amount of cells a search keeps track of. On huge maps, searches for unreachable or
+	// far away destinations can consume a lot of memory. If the limit is reached, the search stops and returns the
+	// path to the checked cell closest to the destination, with Path.Partial set. Zero means unlimited.
+	MemoryLimit int
+	// AgentHeight is the height of the agent. Cells with a lower Clearance can't be walked on. In a Grid3D, the
+	// clearance is the distance to the next walkable Cell above, unless the Cell has a Clearance itself. Zero ignores
+	// the clearance.
+	AgentHeight float64
+	// RiskAversion trades the expected cost of the path against its worst case: the standard deviation of each Cell's
+	// cost (see Cell.CostVariance) multiplied with RiskAversion is added to its cost. 0 only minds the expected costs,
+	// bigger values avoid unreliable cells more and more.
+	RiskAversion float64
+	// Knowledge is the KnowledgeLayer of the agent. If it's set, the search uses the remembered walkability and costs of
+	// the cells instead of the actual ones, and cells which haven't been revealed are unknown (see UnknownCells).
+	Knowledge *KnowledgeLayer
+	// MinTraversalProbability is the minimum probability a Cell has to be walkable with, see Cell.BlockProbability.
+	// Zero ignores the probabilities.
+	MinTraversalProbability float64
+	// UnknownCells defines how cells marked as Unknown are handled.
+	UnknownCells UnknownPolicy
+	// CostLayers contains the names of the cost layers (see Grid.CostLayer), which modify the costs of the cells in
+	// this search, e.g. "winter" or "night". They are applied in the given order; unknown layers are ignored.
+	CostLayers []string
+	// Water defines how the agent moves on land and in water, see WaterProfile. Without it, the water is ignored.
+	Water *WaterProfile
+	// WaterLevel overrides the sea level of the Grid (see Grid.SetSeaLevel) for this search, e.g. for tides or floods,
+	// so one terrain Grid can be used for all water levels. Without a WaterProfile, cells below it aren't walkable.
+	// If it's nil, the sea level of the Grid is used.
+	WaterLevel *float64
+	// Postures contains the postures (e.g. crouching or crawling) the agent can take, if it doesn't fit below a
+	// ceiling upright. On each Cell, the cheapest posture the agent fits in is chosen; the chosen postures are reported
+	// in Path.Postures.
+	Postures []Posture
+	// MaxFallHeight is the maximum amount of levels agents can fall down in a Grid3D, when stepping off a ledge deeper
+	// than MaxDropHeight. Zero forbids falling, a negative value allows infinite falls.
+	MaxFallHeight float64
+	// FallCost is the additional cost per level fallen down in a Grid3D.
+	FallCost float64
+	// Directions restricts the directions moves can go in, e.g. CardinalDirections, or East|South for rivers which
+	// only flow downstream. Diagonal directions only have an impact if diagonal movement is enabled. Zero allows all
+	// directions. Vertical movement can be restricted with MaxStepHeight and MaxDropHeight instead.
+	Directions Direction
+	// BlockedCategories contains names of categories (see Grid.Categorize), whose cells are treated as not walkable,
+	// e.g. "water" for agents which can't swim. Unknown categories are ignored.
+	BlockedCategories []string
+	// RuneLayer is the name of the rune layer (see Grid.RuneLayer), whose runes are checked against the
+	// BlockedCategories. If it's empty or the Grid doesn't contain the layer, the Rune of the cells is used.
+	RuneLayer string
+	// WaypointsOnly makes the search return only the waypoints of the path instead of all of its cells: the start, the
+	// end and each Cell where the path changes its direction or posture. Moving in straight lines between them
+	// follows the path exactly, which is what most movement systems need, while long paths take much less memory.
+	WaypointsOnly bool
+	// FixedPoint makes the search round the cost of each move to thousandths and sum them up as integers, so the
+	// results are bit-identical on all architectures, e.g. for lockstep multiplayer. Summing up floating point numbers
+	// can't guarantee this, as rounding errors accumulate differently when the compiler fuses operations.
+	FixedPoint bool
+	// GoalBounds are precomputed bounding boxes, which let the search skip moves that can't lead to the end Cell on the
+	// cheapest path, see Grid.NewGoalBounds. They have to be computed with the same movement rules. Only AStar and
+	// UniformCost searches for a single end Cell use them, and they are ignored once they are outdated.
+	GoalBounds *GoalBounds
+	// Landmarks improve the estimate of AStar with precomputed costs from and to landmark cells, see Grid.NewLandmarks.
+	// They have to be computed with the same movement rules, and they are ignored once they are outdated or with
+	// StopWithinRange.
+	Landmarks *Landmarks
+	// corridor restricts the search to the cells whose index is true, see PathCache and HierarchicalGrid. nil allows
+	// all cells.
+	corridor []bool
+	// Algorithm is the order in which the search checks the cells. The default AStar heads towards the end Cell,
+	// UniformCost spreads evenly in all directions, JumpPointSearch skips open areas and ThetaStar finds paths at any
+	// angle. Algorithms registered with RegisterAlgorithm can be used as well.
+	Algorithm Algorithm
+	// Heuristic is the distance measure AStar estimates the remaining cost with. The DefaultHeuristic fits the
+	// movement of the settings.
+	Heuristic Heuristic
+	// HeuristicWeight inflates the estimated remaining cost of AStar (weighted A*), e.g. 1.5 for faster searches with
+	// slightly longer paths: the more the estimate is weighted, the more greedily the search heads towards the end
+	// Cell. It's ignored while RequireOptimal is set. Values of 1 or less don't change the estimate.
+	HeuristicWeight float64
+	// OnSearchComplete is called after each search with statistics about it, e.g. to adapt the amount of searches per
+	// frame to the measured durations. It's optional.
+	OnSearchComplete func(stats SearchStats)
+}
+
+// SearchStats contains statistics about a single search, see PathSettings.OnSearchComplete.
+type SearchStats struct {
+	// Expanded is the amount of cells whose neighbors have been checked.
+	Expanded int
+	// Pushed is the amount of cells added to the list of cells to check.
+	Pushed int
+	// Duration is the time the search took.
+	Duration time.Duration
+	// Found is true if a complete path has been found.
+	Found bool
+	// Partial is true if a partial path has been returned, see Path.Partial.
+	Partial bool
+	// PathLength is the amount of cells in the returned path.
+	PathLength int
+	// Cost is the cost of the returned path, including the costs of diagonal moves.
+	Cost float64
+}
+
+// NewGoalPathSettings works like NewDefaultPathSettings, but the path leads to the nearest Cell the goal returns true
+// for instead of a single end Cell. See GoalCells, GoalRect and GoalAdjacent.
+func NewGoalPathSettings(startCell *Cell, goal GoalFunc) *PathSettings {
+	settings := NewDefaultPathSettings(startCell, nil)
+	settings.Goal = goal
+	return settings
+}
+
+// NewPositionalPathSettings returns the PathSettings the positional arguments of GetPathFromCells stand for, so calls
+// of the deprecated functions can be migrated to GetPathFromSettings step by step: the result equals them, and further
+// settings can be adjusted afterwards. All other settings have their zero values, which differ from
+// NewDefaultPathSettings only in the step and drop heights, diagonals and wallsBlockDiagonals.
+func NewPositionalPathSettings(start, end *Cell, stepHeight, dropHeight int, diagonals, wallsBlockDiagonals bool) *PathSettings {
+	return &PathSettings{
+		start:               start,
+		end:                 end,
+		MaxStepHeight:       float64(stepHeight),
+		MaxDropHeight:       float64(dropHeight),
+		diagonals:           diagonals,
+		wallBlocksDiagonals: wallsBlockDiagonals,
+	}
 }
 
 // NewDefaultPathSettings returns a new PathSettings struct with default values.
 //
 // Default values:
-//   - StepHeight: 1
-//   - DropHeight: 1
+//   - MaxStepHeight: 1
+//   - MaxDropHeight: 1
 //   - Diagonals: true
 //   - WallBlocksDiagonals: true
+//   - StopWithinRange: 0 (disabled)
+//   - RangeMetric: Euclidean
+//   - RequireLineOfSight: false
+//   - RequireOptimal: false
+//   - MaxOccupancy: 0 (unlimited)
+//   - MemoryLimit: 0 (unlimited)
+//   - AgentHeight: 0 (clearance ignored)
+//   - RiskAversion: 0
+//   - Knowledge: nil (everything is known)
+//   - MinTraversalProbability: 0 (probabilities ignored)
+//   - UnknownCells: OptimisticUnknown
+//   - CostLayers: none
+//   - Water: nil (water ignored)
+//   - WaterLevel: nil (sea level of the Grid)
+//   - Postures: none
+//   - MaxFallHeight: 0 (no falling)
+//   - FallCost: 0
+//   - Directions: 0 (all directions)
+//   - RuneLayer: "" (Cell.Rune)
+//   - WaypointsOnly: false
+//   - FixedPoint: false
+//   - GoalBounds: nil
+//   - Landmarks: nil
+//   - Algorithm: AStar
+//   - Heuristic: DefaultHeuristic (octile with diagonals, manhattan without)
+//   - HeuristicWeight: 0 (no inflation)
 func NewDefaultPathSettings(startCell, endCell *Cell) *PathSettings {
 	return &PathSettings{
 		start:               startCell,
 		end:                 endCell,
-		stepHeight:          1,
-		dropHeight:          1,
+		MaxStepHeight:       1,
+		MaxDropHeight:       1,
 		diagonals:           true,
 		wallBlocksDiagonals: true,
 	}
 }
 
+// An UnknownPolicy defines how a search handles cells marked as Unknown.
+type UnknownPolicy int
+
+const (
+	// OptimisticUnknown treats unknown cells like known ones, so paths are planned through unmapped areas assuming
+	// their walkability and costs are as expected. BlockProbability is ignored for them. Cells unknown to a
+	// KnowledgeLayer are assumed to be walkable with its UnknownCost.
+	OptimisticUnknown UnknownPolicy = iota
+	// PessimisticUnknown treats unknown cells as blocked, so paths only lead through known space.
+	PessimisticUnknown
+)
+
+// A Posture is a way an agent can move, which needs less clearance than standing upright, but is slower.
+type Posture struct {
+	Name string `json:"name"`
+	// Height is the height of the agent in this posture, which is compared with the Clearance of the cells.
+	Height float64 `json:"height"`
+	// CostMultiplier is multiplied with the cost of each move in this posture, e.g. 2 for moving half as fast.
+	CostMultiplier float64 `json:"costMultiplier"`
+}
+
+// fixedPointScale is the amount of fixed point units per cost unit, see PathSettings.FixedPoint.
+const fixedPointScale = 1000
+
+// quantize converts a cost into the units the search sums up: with FixedPoint, it's rounded to an integer amount of
+// thousandths. These integers are represented exactly by float64, so their sums are exact.
+func (settings *PathSettings) quantize(cost float64) float64 {
+	if settings.FixedPoint {
+		return math.Round(cost * fixedPointScale)
+	}
+	return cost
+}
+
+// unquantize converts a sum of costs returned by quantize back into a cost.
+func (settings *PathSettings) unquantize(cost float64) float64 {
+	if settings.FixedPoint {
+		return cost / fixedPointScale
+	}
+	return cost
+}
+
+// posture returns the index of the cheapest posture an agent fits in below a ceiling with the passed clearance, or -1
+// if the agent fits in upright. If the agent doesn't fit at all, false is returned.
+func (settings *PathSettings) posture(clearance float64) (int, bool) {
+
+	if clearance <= 0 || settings.AgentHeight <= clearance {
+		return -1, true
+	}
+
+	best := -1
+	for i, posture := range settings.Postures {
+		if posture.Height <= clearance && (best < 0 || posture.CostMultiplier < settings.Postures[best].CostMultiplier) {
+			best = i
+		}
+	}
+
+	return best, best >= 0
+}
+
+// postureName returns the name of the posture used below a ceiling with the passed clearance, or an empty string if
+// the agent stands upright.
+func (settings *PathSettings) postureName(clearance float64) string {
+	if posture, _ := settings.posture(clearance); posture >= 0 {
+		return settings.Postures[posture].Name
+	}
+	return ""
+}
+
+// endpointsWalkable returns if the start and the end of the settings are walkable. If the search doesn't need to reach
+// the end, only the start is checked.
+func (settings *PathSettings) endpointsWalkable() bool {
+	if settings.end == nil {
+		return settings.Goal != nil && settings.assumeWalkable(settings.start)
+	}
+	if settings.StopWithinRange > 0 {
+		// the end is only a target, which doesn't need to be reached
+		return settings.assumeWalkable(settings.start)
+	}
+	return settings.assumeWalkable(settings.start) && settings.assumeWalkable(settings.end)
+}
+
+// allowsDirection returns if a move with the passed offset is allowed by Directions.
+func (settings *PathSettings) allowsDirection(dx, dy int) bool {
+	return settings.Directions == 0 || settings.Directions.Contains(directionOf(dx, dy))
+}
+
 // Node represents the node a path, it contains the cell it represents.
 // Also contains other information such as the parent and the cost.
 type Node struct {
@@ -837,6 +2056,21 @@ type Node struct {
 	Cost   float64
 }
 
+// cells returns the cells from the first node (the one without a parent) to this node.
+func (node *Node) cells() []*Cell {
+
+	cells := []*Cell{}
+	for t := node; t != nil; t = t.Parent {
+		cells = append(cells, t.Cell)
+	}
+
+	// the cells have been collected backwards
+	for i, j := 0, len(cells)-1; i < j; i, j = i+1, j-1 {
+		cells[i], cells[j] = cells[j], cells[i]
+	}
+	return cells
+}
+
 type minHeap []*Node
 
 func (mH minHeap) Len() int           { return len(mH) }
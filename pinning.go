@@ -0,0 +1,200 @@
+package paths
+
+import "math"
+
+// A PinnedRoute is a route through pinned waypoints, which can be edited by the user, e.g. in route-planning tools:
+// waypoints can be dragged, added and removed, and only the segments between the changed waypoint and its neighbors
+// are searched again, while all other waypoints and segments stay as they are. Each segment is the path the settings
+// lead to between two consecutive waypoints.
+//
+// Changes of the Grid aren't noticed until Refresh is called, see Outdated. A PinnedRoute must not be used by multiple
+// goroutines at once.
+type PinnedRoute struct {
+	grid     *Grid
+	settings PathSettings
+	// waypoints contains the pinned cells, segments the cells of the path from each waypoint to the next one and costs
+	// the cost of each segment like SearchStats.Cost, but without the cost of its first Cell.
+	waypoints []*Cell
+	segments  [][]*Cell
+	costs     []float64
+	revision  uint64
+}
+
+// NewPinnedRoute returns a PinnedRoute through the waypoints in their order, the first one being the start and the last
+// one the end of the route. The movement rules are taken from the settings; their start, end, Goal and
+// StopWithinRange are ignored. If fewer than two waypoints are passed or one of the segments can't be found, nil is
+// returned.
+func (m *Grid) NewPinnedRoute(waypoints []*Cell, settings PathSettings) *PinnedRoute {
+
+	if len(waypoints) < 2 {
+		return nil
+	}
+	settings.start, settings.end = nil, nil
+	settings.Goal, settings.StopWithinRange = nil, 0
+
+	r := &PinnedRoute{grid: m, settings: settings, waypoints: append([]*Cell{}, waypoints...)}
+	if !r.Refresh() {
+		return nil
+	}
+	return r
+}
+
+// Waypoints returns the pinned cells of the route in their order.
+func (r *PinnedRoute) Waypoints() []*Cell {
+	return append([]*Cell{}, r.waypoints...)
+}
+
+// Path returns the whole route through all waypoints.
+func (r *PinnedRoute) Path() *Path {
+
+	path := &Path{Cells: []*Cell{r.waypoints[0]}, StepHeight: int(r.settings.MaxStepHeight)}
+	for _, segment := range r.segments {
+		path.Cells = append(path.Cells, segment[1:]...)
+	}
+	r.grid.finishCells(path, &r.settings)
+	return path
+}
+
+// Cost returns the cost of the whole route like SearchStats.Cost.
+func (r *PinnedRoute) Cost() float64 {
+
+	cost := r.grid.cellCost(r.waypoints[0], &r.settings)
+	for _, segmentCost := range r.costs {
+		cost += segmentCost
+	}
+	return cost
+}
+
+// SegmentIndex returns the index of the segment containing the Cell, i.e. the index of the waypoint it follows, or -1
+// if the Cell isn't part of the route. Waypoints belong to the segment they start.
+func (r *PinnedRoute) SegmentIndex(cell *Cell) int {
+
+	for i, segment := range r.segments {
+		for _, other := range segment[:len(segment)-1] {
+			if other == cell {
+				return i
+			}
+		}
+	}
+	if cell == r.waypoints[len(r.waypoints)-1] {
+		return len(r.segments) - 1
+	}
+	return -1
+}
+
+// MoveWaypoint moves the waypoint with the index to the Cell "to", e.g. when the user drags it, and searches the
+// segments to and from it again. If one of them can't be found or the index is out of range, nothing is changed and
+// false is returned.
+func (r *PinnedRoute) MoveWaypoint(index int, to *Cell) bool {
+
+	if index < 0 || index >= len(r.waypoints) {
+		return false
+	}
+
+	previous := r.waypoints[index]
+	r.waypoints[index] = to
+	first, last := maxInt(index-1, 0), minInt(index, len(r.segments)-1)
+	if !r.resolve(first, last) {
+		r.waypoints[index] = previous
+		return false
+	}
+	return true
+}
+
+// InsertWaypoint pins the Cell "to" as a new waypoint after the waypoint with the index, e.g. when the user drags a
+// Cell of the segment following it (see SegmentIndex), and searches the segments to and from it. If one of them can't
+// be found or the index is out of range, nothing is changed and false is returned.
+func (r *PinnedRoute) InsertWaypoint(index int, to *Cell) bool {
+
+	if index < 0 || index >= len(r.segments) {
+		return false
+	}
+
+	r.waypoints = append(r.waypoints[:index+1], append([]*Cell{to}, r.waypoints[index+1:]...)...)
+	r.segments = append(r.segments[:index+1], append([][]*Cell{nil}, r.segments[index+1:]...)...)
+	r.costs = append(r.costs[:index+1], append([]float64{0}, r.costs[index+1:]...)...)
+	if !r.resolve(index, index+1) {
+		r.waypoints = append(r.waypoints[:index+1], r.waypoints[index+2:]...)
+		r.segments = append(r.segments[:index+1], r.segments[index+2:]...)
+		r.costs = append(r.costs[:index+1], r.costs[index+2:]...)
+		return false
+	}
+	return true
+}
+
+// RemoveWaypoint unpins the waypoint with the index and searches the segment between its neighbors. The start and the
+// end can't be removed. If the new segment can't be found or the index is out of range, nothing is changed and false
+// is returned.
+func (r *PinnedRoute) RemoveWaypoint(index int) bool {
+
+	if index <= 0 || index >= len(r.waypoints)-1 {
+		return false
+	}
+
+	waypoints := append([]*Cell{}, r.waypoints...)
+	segments := append([][]*Cell{}, r.segments...)
+	costs := append([]float64{}, r.costs...)
+
+	r.waypoints = append(r.waypoints[:index], r.waypoints[index+1:]...)
+	r.segments = append(r.segments[:index], r.segments[index+1:]...)
+	r.costs = append(r.costs[:index], r.costs[index+1:]...)
+	if !r.resolve(index-1, index-1) {
+		r.waypoints, r.segments, r.costs = waypoints, segments, costs
+		return false
+	}
+	return true
+}
+
+// Outdated returns if the Grid has been changed since the segments have been searched.
+func (r *PinnedRoute) Outdated() bool {
+	return r.revision != r.grid.Revision()
+}
+
+// Refresh searches all segments again, e.g. after changes of the Grid. If one of them can't be found, the segments
+// stay as they are and false is returned.
+func (r *PinnedRoute) Refresh() bool {
+
+	if r.segments == nil {
+		r.segments = make([][]*Cell, len(r.waypoints)-1)
+		r.costs = make([]float64, len(r.waypoints)-1)
+	}
+	if !r.resolve(0, len(r.segments)-1) {
+		return false
+	}
+	r.revision = r.grid.Revision()
+	return true
+}
+
+// resolve searches the segments from the index first to the index last (inclusive) again. Unless all of them have been
+// found, the segments stay as they are and false is returned.
+func (r *PinnedRoute) resolve(first, last int) bool {
+
+	m := r.grid
+	segments := make([][]*Cell, 0, last-first+1)
+	costs := make([]float64, 0, last-first+1)
+	for i := first; i <= last; i++ {
+
+		from, to := r.waypoints[i], r.waypoints[i+1]
+		settings := r.settings
+		settings.start, settings.end = from, to
+		settings.WaypointsOnly = false
+		cost := math.Inf(1)
+		settings.OnSearchComplete = func(stats SearchStats) {
+			cost = stats.Cost
+			if r.settings.OnSearchComplete != nil {
+				r.settings.OnSearchComplete(stats)
+			}
+		}
+
+		path := m.findPath(&settings, nil)
+		if path == nil || len(path.Cells) == 0 || path.Partial {
+			return false
+		}
+		segments = append(segments, path.Cells)
+		costs = append(costs, cost-m.cellCost(from, &r.settings))
+	}
+
+	copy(r.segments[first:], segments)
+	copy(r.costs[first:], costs)
+	return true
+}
@@ -0,0 +1,326 @@
+package paths
+
+import (
+	"container/heap"
+	"math"
+)
+
+// A PathPlanner keeps the path of an agent up-to-date while the agent walks it and the Grid changes, e.g. when doors
+// open or walls are built. Instead of searching the whole path again after each change, it keeps the state of its
+// search (D* Lite) and only repairs the part affected by the changed cells. The search runs backwards from the end
+// Cell, so the agent moving along the path doesn't invalidate what has been found out so far.
+//
+// The PathPlanner doesn't notice changes of the Grid by itself: pass the changed cells to Update. Searches for a Goal
+// or with StopWithinRange aren't supported; the path always leads to the end Cell. A PathPlanner must not be used by
+// multiple goroutines at once.
+type PathPlanner struct {
+	grid     *Grid
+	settings PathSettings
+	// last is the position of the agent when the keys have been adjusted the last time and adjustment is the sum of
+	// the heuristics between the positions so far (km in D* Lite).
+	last       *Cell
+	adjustment float64
+	heuristic  heuristic
+	// costs contains the cost from each cell to the end found so far (g in D* Lite) and lookahead the cost the cell
+	// should have according to the costs of its neighbors (rhs in D* Lite). Cells are inconsistent, if the two differ.
+	costs, lookahead []float64
+	// keys contains the current key of each cell in the queue. queued is false for cells which aren't in the queue.
+	keys   []plannerKey
+	queued []bool
+	queue  plannerQueue
+}
+
+// plannerKey is the priority of a cell in the queue of a PathPlanner.
+type plannerKey [2]float64
+
+// plannerTolerance is the relative difference, up to which the values of keys are treated as equal. The same cost
+// summed up in another order may be rounded differently, which would otherwise stop the search too early.
+const plannerTolerance = 1e-9
+
+// less returns if the key is checked before the other one.
+func (k plannerKey) less(other plannerKey) bool {
+
+	if !nearlyEqual(k[0], other[0]) {
+		return k[0] < other[0]
+	}
+	return !nearlyEqual(k[1], other[1]) && k[1] < other[1]
+}
+
+// nearlyEqual returns if the values differ by at most the plannerTolerance. Infinite values are only equal to
+// themselves.
+func nearlyEqual(a, b float64) bool {
+
+	if a == b {
+		return true
+	}
+	if math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return false
+	}
+	return math.Abs(a-b) <= plannerTolerance*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+// NewPathPlanner returns a PathPlanner for paths from the start to the end Cell of the settings. No search is done
+// until Path is called. The Landmarks of the settings are ignored, as they only estimate the costs towards the end, and
+// so is the HeuristicWeight: the keys of the repaired Cells must never overestimate the remaining cost.
+func (m *Grid) NewPathPlanner(settings PathSettings) *PathPlanner {
+
+	settings.Goal, settings.StopWithinRange, settings.Landmarks, settings.HeuristicWeight = nil, 0, nil, 0
+	p := &PathPlanner{grid: m, settings: settings}
+	p.Reset()
+	return p
+}
+
+// Reset drops the state of the search, so the next call of Path searches from scratch. It's needed after changes of
+// the Grid, whose cells aren't known, e.g. after loading another map.
+func (p *PathPlanner) Reset() {
+
+	size := p.grid.Width() * p.grid.Height()
+	p.costs = make([]float64, size)
+	p.lookahead = make([]float64, size)
+	for i := range p.costs {
+		p.costs[i], p.lookahead[i] = math.Inf(1), math.Inf(1)
+	}
+	p.keys = make([]plannerKey, size)
+	p.queued = make([]bool, size)
+	p.queue = p.queue[:0]
+	p.last = p.settings.start
+	p.adjustment = 0
+	p.heuristic = p.grid.newHeuristic(&p.settings)
+
+	if end := p.settings.end; end != nil {
+		index := p.grid.cellIndex(end)
+		p.lookahead[index] = 0
+		p.enqueue(index)
+	}
+}
+
+// Position returns the Cell the path starts on.
+func (p *PathPlanner) Position() *Cell {
+	return p.settings.start
+}
+
+// MoveTo moves the start of the path to the Cell, usually the next Cell of the path after the agent has reached it.
+func (p *PathPlanner) MoveTo(cell *Cell) {
+
+	if cell == p.settings.start {
+		return
+	}
+
+	p.settings.start = cell
+	if p.last != nil && p.heuristic.enabled {
+		p.adjustment += p.estimate(p.last, cell)
+	}
+	p.last = cell
+}
+
+// Update repairs the state of the search after the passed cells have been changed. Besides the cells themselves, the
+// moves from and past their neighbors are checked again, so changes of the walkability, costs and heights are handled.
+// Changes with a wider effect, e.g. of the clearance, need all affected cells to be passed.
+func (p *PathPlanner) Update(cells ...*Cell) {
+
+	// cheaper cells may make the heuristic overestimate, which requires a new search
+	if h := p.grid.newHeuristic(&p.settings); h.straight < p.heuristic.straight || h.diagonal < p.heuristic.diagonal {
+		p.Reset()
+		return
+	}
+
+	for _, cell := range cells {
+		for y := cell.Y - 1; y <= cell.Y+1; y++ {
+			for x := cell.X - 1; x <= cell.X+1; x++ {
+				if neighbor := p.grid.Get(x, y); neighbor != nil {
+					p.updateCell(p.grid.cellIndex(neighbor))
+				}
+			}
+		}
+	}
+}
+
+// Path returns the cheapest path from the current position to the end Cell. If there is none, the Path is empty. If
+// the start or end isn't walkable, nil is returned.
+func (p *PathPlanner) Path() *Path {
+
+	settings := &p.settings
+	if !settings.endpointsWalkable() {
+		return nil
+	}
+
+	p.search()
+
+	m := p.grid
+	path := &Path{StepHeight: int(settings.MaxStepHeight)}
+	index := m.cellIndex(settings.start)
+	if math.IsInf(p.costs[index], 1) {
+		return path
+	}
+
+	// the path follows the cheapest neighbors; it can't be longer than the amount of cells, unless the Grid has been
+	// changed without Update, which may lead it in circles without reaching the end
+	path.Cells = append(path.Cells, settings.start)
+	for cell := settings.start; cell != settings.end && len(path.Cells) <= len(p.costs); {
+		next, nextCost := (*Cell)(nil), math.Inf(1)
+		for _, offset := range neighborOffsets {
+			neighbor := p.neighbor(cell, offset)
+			if neighbor == nil || !m.canMove(cell, neighbor, settings) {
+				continue
+			}
+			cost := settings.quantize(m.moveCost(cell, neighbor, settings)) + p.costs[m.cellIndex(neighbor)]
+			if cost < nextCost {
+				next, nextCost = neighbor, cost
+			}
+		}
+		if next == nil {
+			return &Path{StepHeight: path.StepHeight}
+		}
+		path.Cells = append(path.Cells, next)
+		cell = next
+	}
+	if path.Cells[len(path.Cells)-1] != settings.end {
+		return &Path{StepHeight: path.StepHeight}
+	}
+
+	m.finishCells(path, settings)
+	return path
+}
+
+// Cost returns the cost of the path from the current position to the end Cell found by the last call of Path,
+// including the cost of the current position like SearchStats.Cost, or +Inf if there is none.
+func (p *PathPlanner) Cost() float64 {
+
+	settings := &p.settings
+	cost := p.costs[p.grid.cellIndex(settings.start)]
+	if math.IsInf(cost, 1) {
+		return cost
+	}
+	return settings.unquantize(cost + settings.quantize(p.grid.cellCost(settings.start, settings)))
+}
+
+// search checks the inconsistent cells until the cost of the current position is known.
+func (p *PathPlanner) search() {
+
+	m := p.grid
+	settings := &p.settings
+	start := m.cellIndex(settings.start)
+
+	for len(p.queue) > 0 {
+
+		// the queue may contain outdated items of cells, which have been removed or whose key has changed
+		item := p.queue[0]
+		index := item.index
+		if !p.queued[index] || item.key != p.keys[index] {
+			heap.Pop(&p.queue)
+			continue
+		}
+		if !item.key.less(p.key(start)) && p.lookahead[start] == p.costs[start] {
+			return
+		}
+
+		heap.Pop(&p.queue)
+		// the key may be outdated, since the position has moved
+		if current := p.key(index); item.key.less(current) {
+			p.keys[index] = current
+			heap.Push(&p.queue, plannerItem{index, current})
+			continue
+		}
+
+		p.queued[index] = false
+		cell := m.cellAt(index)
+		if p.costs[index] > p.lookahead[index] {
+			p.costs[index] = p.lookahead[index]
+		} else {
+			p.costs[index] = math.Inf(1)
+			p.updateCell(index)
+		}
+
+		// the cells moving onto this one depend on its cost
+		for _, offset := range neighborOffsets {
+			if neighbor := p.neighbor(cell, offset); neighbor != nil && m.canMove(neighbor, cell, settings) {
+				p.updateCell(m.cellIndex(neighbor))
+			}
+		}
+	}
+}
+
+// updateCell determines the lookahead of the cell with the index from the costs of its neighbors again and adds it to
+// the queue if it's inconsistent.
+func (p *PathPlanner) updateCell(index int32) {
+
+	m := p.grid
+	settings := &p.settings
+	cell := m.cellAt(index)
+
+	if cell != settings.end {
+		lookahead := math.Inf(1)
+		for _, offset := range neighborOffsets {
+			neighbor := p.neighbor(cell, offset)
+			if neighbor == nil || !m.canMove(cell, neighbor, settings) {
+				continue
+			}
+			cost := settings.quantize(m.moveCost(cell, neighbor, settings)) + p.costs[m.cellIndex(neighbor)]
+			lookahead = math.Min(lookahead, cost)
+		}
+		p.lookahead[index] = lookahead
+	}
+
+	if p.costs[index] != p.lookahead[index] {
+		p.enqueue(index)
+	} else {
+		// cells in the queue are skipped when they are removed from it
+		p.queued[index] = false
+	}
+}
+
+// enqueue adds the cell with the index to the queue with its current key or updates its key.
+func (p *PathPlanner) enqueue(index int32) {
+
+	// an item with an older key is left in the queue and skipped later
+	key := p.key(index)
+	if p.queued[index] && p.keys[index] == key {
+		return
+	}
+	p.keys[index] = key
+	p.queued[index] = true
+	heap.Push(&p.queue, plannerItem{index, key})
+}
+
+// neighbor returns the neighbor of the Cell at the offset, or nil if there is none or the settings don't allow moving
+// to it.
+func (p *PathPlanner) neighbor(cell *Cell, offset [2]int) *Cell {
+	if !p.settings.diagonals && offset[0] != 0 && offset[1] != 0 {
+		return nil
+	}
+	return p.grid.Get(cell.X+offset[0], cell.Y+offset[1])
+}
+
+// key returns the current key of the cell with the index.
+func (p *PathPlanner) key(index int32) plannerKey {
+
+	cost := math.Min(p.costs[index], p.lookahead[index])
+	return plannerKey{cost + p.estimate(p.settings.start, p.grid.cellAt(index)) + p.adjustment, cost}
+}
+
+// estimate returns the estimated cost between the two cells.
+func (p *PathPlanner) estimate(from, to *Cell) float64 {
+	h := p.heuristic
+	h.end = from
+	return h.estimate(to)
+}
+
+// plannerItem is a cell in the queue of a PathPlanner with its key at the time it has been added.
+type plannerItem struct {
+	index int32
+	key   plannerKey
+}
+
+// plannerQueue is the queue of a PathPlanner, which returns the item with the lowest key first.
+type plannerQueue []plannerItem
+
+func (q plannerQueue) Len() int            { return len(q) }
+func (q plannerQueue) Less(i, j int) bool  { return q[i].key.less(q[j].key) }
+func (q plannerQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *plannerQueue) Push(x interface{}) { *q = append(*q, x.(plannerItem)) }
+func (q *plannerQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,148 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRotationNotAllowed is returned when placing a Prefab with a Rotation it doesn't allow.
+var ErrRotationNotAllowed = errors.New("rotation not allowed for prefab")
+
+// ErrPrefabOutOfBounds is returned when a Prefab doesn't fit into the Grid at the passed position.
+var ErrPrefabOutOfBounds = errors.New("prefab out of bounds")
+
+// ErrPrefabCollision is returned when a Prefab overlaps cells it can't be placed on.
+var ErrPrefabCollision = errors.New("prefab collides with grid")
+
+// A Rotation is a clockwise rotation by a multiple of 90 degrees.
+type Rotation int
+
+const (
+	Rotate0 Rotation = iota
+	Rotate90
+	Rotate180
+	Rotate270
+)
+
+// A Prefab is a reusable piece of a map, e.g. a building or a room, which procedural generators can place into a Grid
+// with Grid.PlacePrefab.
+type Prefab struct {
+	// Grid contains the cells of the prefab.
+	Grid *Grid
+	// Anchor is the position of the Cell of the prefab, which is placed at the passed position, e.g. the door of a
+	// building. It's rotated with the prefab.
+	Anchor Point
+	// Rotations contains the allowed rotations. Empty allows all rotations.
+	Rotations []Rotation
+	// Policy defines how the cells of the prefab are merged into the Grid, see Grid.Overlay. Its transparent runes
+	// mark cells, which don't belong to the prefab, so they can't collide.
+	Policy OverlayPolicy
+	// Collides decides, if a Cell of the prefab can't be placed onto the Cell of the Grid. nil means, that prefabs can
+	// only be placed on walkable cells, so they don't overlap walls or other prefabs.
+	Collides func(target, prefabCell *Cell) bool
+}
+
+// Allows returns if the Prefab can be placed with the Rotation.
+func (p *Prefab) Allows(rotation Rotation) bool {
+
+	if len(p.Rotations) == 0 {
+		return rotation >= Rotate0 && rotation <= Rotate270
+	}
+
+	for _, allowed := range p.Rotations {
+		if allowed == rotation {
+			return true
+		}
+	}
+	return false
+}
+
+// Rotated returns a copy of the Grid of the Prefab rotated clockwise and the rotated Anchor.
+func (p *Prefab) Rotated(rotation Rotation) (*Grid, Point) {
+
+	width, height := p.Grid.Width(), p.Grid.Height()
+	if rotation == Rotate90 || rotation == Rotate270 {
+		width, height = height, width
+	}
+
+	rotate := func(x, y int) (int, int) {
+		switch rotation {
+		case Rotate90:
+			return p.Grid.Height() - 1 - y, x
+		case Rotate180:
+			return p.Grid.Width() - 1 - x, p.Grid.Height() - 1 - y
+		case Rotate270:
+			return y, p.Grid.Width() - 1 - x
+		}
+		return x, y
+	}
+
+	rotated := NewGrid(width, height)
+	for _, cell := range p.Grid.AllCells() {
+		x, y := rotate(cell.X, cell.Y)
+		*rotated.Get(x, y) = *cell
+		rotated.Get(x, y).X, rotated.Get(x, y).Y = x, y
+	}
+
+	anchorX, anchorY := rotate(p.Anchor.X, p.Anchor.Y)
+	return rotated, Point{anchorX, anchorY}
+}
+
+// CanPlacePrefab checks, if the Prefab can be placed with its Anchor at the passed position, see PlacePrefab. If not,
+// an error wrapping ErrRotationNotAllowed, ErrPrefabOutOfBounds or ErrPrefabCollision is returned.
+func (m *Grid) CanPlacePrefab(prefab *Prefab, at Point, rotation Rotation) error {
+	_, _, err := m.checkPrefab(prefab, at, rotation)
+	return err
+}
+
+// PlacePrefab places the Prefab rotated clockwise into the Grid, so its Anchor ends up at the passed position. The
+// cells are merged as defined by the Policy of the Prefab. If the Prefab can't be placed (see CanPlacePrefab), the
+// Grid isn't changed and the error is returned.
+func (m *Grid) PlacePrefab(prefab *Prefab, at Point, rotation Rotation) error {
+
+	rotated, offset, err := m.checkPrefab(prefab, at, rotation)
+	if err != nil {
+		return err
+	}
+
+	m.Overlay(rotated, offset, prefab.Policy)
+	return nil
+}
+
+// checkPrefab checks, if the Prefab can be placed, and returns its rotated Grid and the offset of its top left Cell.
+func (m *Grid) checkPrefab(prefab *Prefab, at Point, rotation Rotation) (*Grid, Point, error) {
+
+	if !prefab.Allows(rotation) {
+		return nil, Point{}, fmt.Errorf("%w: %d", ErrRotationNotAllowed, rotation)
+	}
+
+	rotated, anchor := prefab.Rotated(rotation)
+	offset := Point{at.X - anchor.X, at.Y - anchor.Y}
+
+	transparent := make(map[rune]bool, len(prefab.Policy.Transparent))
+	for _, r := range prefab.Policy.Transparent {
+		transparent[r] = true
+	}
+
+	for _, cell := range rotated.AllCells() {
+
+		if transparent[cell.Rune] {
+			continue
+		}
+
+		target := m.Get(cell.X+offset.X, cell.Y+offset.Y)
+		if target == nil {
+			return nil, Point{}, fmt.Errorf("%w: cell at %d, %d", ErrPrefabOutOfBounds, cell.X+offset.X, cell.Y+offset.Y)
+		}
+
+		collides := !target.Walkable
+		if prefab.Collides != nil {
+			collides = prefab.Collides(target, cell)
+		}
+		if collides {
+			return nil, Point{}, fmt.Errorf("%w: cell at %d, %d", ErrPrefabCollision, target.X, target.Y)
+		}
+	}
+
+	return rotated, offset, nil
+}
@@ -0,0 +1,263 @@
+package paths
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownPreset is returned when requesting a preset, which hasn't been registered.
+var ErrUnknownPreset = errors.New("unknown preset")
+
+// A Preset is the serializable form of the movement rules of PathSettings, e.g. "infantry", "cart" or "flying_scout",
+// see LoadPresets. Fields missing in the JSON keep the values of NewDefaultPathSettings. The Algorithm and the
+// Heuristic are referenced by their names (see Algorithm.String and Heuristic.String), so registered algorithms can be
+// used as well; empty names stand for AStar and DefaultHeuristic.
+type Preset struct {
+	MaxStepHeight           float64        `json:"maxStepHeight"`
+	MaxDropHeight           float64        `json:"maxDropHeight"`
+	Diagonals               bool           `json:"diagonals"`
+	WallBlocksDiagonals     bool           `json:"wallBlocksDiagonals"`
+	StopWithinRange         float64        `json:"stopWithinRange,omitempty"`
+	RangeMetric             DistanceMetric `json:"rangeMetric,omitempty"`
+	RequireLineOfSight      bool           `json:"requireLineOfSight,omitempty"`
+	RequireOptimal          bool           `json:"requireOptimal,omitempty"`
+	MaxOccupancy            int            `json:"maxOccupancy,omitempty"`
+	MemoryLimit             int            `json:"memoryLimit,omitempty"`
+	Directions              Direction      `json:"directions,omitempty"`
+	BlockedCategories       []string       `json:"blockedCategories,omitempty"`
+	AgentHeight             float64        `json:"agentHeight,omitempty"`
+	Postures                []Posture      `json:"postures,omitempty"`
+	RiskAversion            float64        `json:"riskAversion,omitempty"`
+	MinTraversalProbability float64        `json:"minTraversalProbability,omitempty"`
+	UnknownCells            UnknownPolicy  `json:"unknownCells,omitempty"`
+	CostLayers              []string       `json:"costLayers,omitempty"`
+	Water                   *WaterProfile  `json:"water,omitempty"`
+	WaterLevel              *float64       `json:"waterLevel,omitempty"`
+	MaxFallHeight           float64        `json:"maxFallHeight,omitempty"`
+	FallCost                float64        `json:"fallCost,omitempty"`
+	RuneLayer               string         `json:"runeLayer,omitempty"`
+	WaypointsOnly           bool           `json:"waypointsOnly,omitempty"`
+	FixedPoint              bool           `json:"fixedPoint,omitempty"`
+	Algorithm               string         `json:"algorithm,omitempty"`
+	Heuristic               string         `json:"heuristic,omitempty"`
+	HeuristicWeight         float64        `json:"heuristicWeight,omitempty"`
+}
+
+// presetRegistry contains the settings registered with RegisterPreset and LoadPresets by name.
+var presetRegistry = struct {
+	lock    sync.RWMutex
+	presets map[string]PathSettings
+}{presets: make(map[string]PathSettings)}
+
+// RegisterPreset registers the movement rules of the settings under the name, replacing a preset with the same name.
+// The start, end, Goal, Knowledge, GoalBounds, Landmarks and OnSearchComplete of the settings aren't part of the preset.
+func RegisterPreset(name string, settings PathSettings) {
+
+	settings.start, settings.end, settings.Goal = nil, nil, nil
+	settings.Knowledge, settings.OnSearchComplete = nil, nil
+	settings.GoalBounds, settings.Landmarks = nil, nil
+
+	registry := &presetRegistry
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	registry.presets[name] = settings
+}
+
+// LoadPresets reads a JSON object mapping names to presets, e.g. from a config file, and registers them like
+// RegisterPreset. Algorithms referenced by name have to be registered before. If a preset can't be read, none of them
+// are registered.
+func LoadPresets(r io.Reader) error {
+
+	raw := make(map[string]json.RawMessage)
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return err
+	}
+
+	presets := make(map[string]PathSettings, len(raw))
+	for name, data := range raw {
+		preset := newPreset(NewDefaultPathSettings(nil, nil))
+		if err := json.Unmarshal(data, &preset); err != nil {
+			return fmt.Errorf("preset %q: %w", name, err)
+		}
+		settings, err := preset.PathSettings(nil, nil)
+		if err != nil {
+			return fmt.Errorf("preset %q: %w", name, err)
+		}
+		presets[name] = *settings
+	}
+
+	for name, settings := range presets {
+		RegisterPreset(name, settings)
+	}
+	return nil
+}
+
+// SavePresets writes all registered presets as a JSON object, which can be read by LoadPresets.
+func SavePresets(w io.Writer) error {
+
+	registry := &presetRegistry
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	presets := make(map[string]Preset, len(registry.presets))
+	for name, settings := range registry.presets {
+		presets[name] = newPreset(&settings)
+	}
+	return json.NewEncoder(w).Encode(presets)
+}
+
+// Presets returns the names of all registered presets in alphabetical order.
+func Presets() []string {
+
+	registry := &presetRegistry
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	names := make([]string, 0, len(registry.presets))
+	for name := range registry.presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PresetSettings returns new PathSettings with the movement rules of the preset registered under the name and the
+// passed start and end. If there is no such preset, an error wrapping ErrUnknownPreset is returned.
+func PresetSettings(name string, start, end *Cell) (*PathSettings, error) {
+
+	registry := &presetRegistry
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	settings, exists := registry.presets[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPreset, name)
+	}
+	settings.start, settings.end = start, end
+	return &settings, nil
+}
+
+// GetPathWithPreset works like GetPathFromSettings with the settings of the preset registered under the name, see
+// PresetSettings.
+func (m *Grid) GetPathWithPreset(name string, start, end *Cell) (*Path, error) {
+
+	settings, err := PresetSettings(name, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return m.findPath(settings, nil), nil
+}
+
+// PathSettings returns PathSettings with the movement rules of the Preset and the passed start and end. If the
+// Algorithm or the Heuristic is unknown, an error is returned.
+func (p Preset) PathSettings(start, end *Cell) (*PathSettings, error) {
+
+	algorithm := AStar
+	if p.Algorithm != "" {
+		var exists bool
+		if algorithm, exists = AlgorithmByName(p.Algorithm); !exists {
+			return nil, fmt.Errorf("unknown algorithm %q", p.Algorithm)
+		}
+	}
+
+	heuristic := DefaultHeuristic
+	if p.Heuristic != "" {
+		var exists bool
+		if heuristic, exists = heuristicByName(p.Heuristic); !exists {
+			return nil, fmt.Errorf("unknown heuristic %q", p.Heuristic)
+		}
+	}
+
+	return &PathSettings{
+		start:                   start,
+		end:                     end,
+		MaxStepHeight:           p.MaxStepHeight,
+		MaxDropHeight:           p.MaxDropHeight,
+		diagonals:               p.Diagonals,
+		wallBlocksDiagonals:     p.WallBlocksDiagonals,
+		StopWithinRange:         p.StopWithinRange,
+		RangeMetric:             p.RangeMetric,
+		RequireLineOfSight:      p.RequireLineOfSight,
+		RequireOptimal:          p.RequireOptimal,
+		MaxOccupancy:            p.MaxOccupancy,
+		MemoryLimit:             p.MemoryLimit,
+		Directions:              p.Directions,
+		BlockedCategories:       p.BlockedCategories,
+		AgentHeight:             p.AgentHeight,
+		Postures:                p.Postures,
+		RiskAversion:            p.RiskAversion,
+		MinTraversalProbability: p.MinTraversalProbability,
+		UnknownCells:            p.UnknownCells,
+		CostLayers:              p.CostLayers,
+		Water:                   p.Water,
+		WaterLevel:              p.WaterLevel,
+		MaxFallHeight:           p.MaxFallHeight,
+		FallCost:                p.FallCost,
+		RuneLayer:               p.RuneLayer,
+		WaypointsOnly:           p.WaypointsOnly,
+		FixedPoint:              p.FixedPoint,
+		Algorithm:               algorithm,
+		Heuristic:               heuristic,
+		HeuristicWeight:         p.HeuristicWeight,
+	}, nil
+}
+
+// newPreset returns the Preset of the movement rules of the settings.
+func newPreset(settings *PathSettings) Preset {
+
+	algorithm := ""
+	if settings.Algorithm != AStar {
+		algorithm = settings.Algorithm.String()
+	}
+	heuristic := ""
+	if settings.Heuristic != DefaultHeuristic {
+		heuristic = settings.Heuristic.String()
+	}
+
+	return Preset{
+		MaxStepHeight:           settings.MaxStepHeight,
+		MaxDropHeight:           settings.MaxDropHeight,
+		Diagonals:               settings.diagonals,
+		WallBlocksDiagonals:     settings.wallBlocksDiagonals,
+		StopWithinRange:         settings.StopWithinRange,
+		RangeMetric:             settings.RangeMetric,
+		RequireLineOfSight:      settings.RequireLineOfSight,
+		RequireOptimal:          settings.RequireOptimal,
+		MaxOccupancy:            settings.MaxOccupancy,
+		MemoryLimit:             settings.MemoryLimit,
+		Directions:              settings.Directions,
+		BlockedCategories:       settings.BlockedCategories,
+		AgentHeight:             settings.AgentHeight,
+		Postures:                settings.Postures,
+		RiskAversion:            settings.RiskAversion,
+		MinTraversalProbability: settings.MinTraversalProbability,
+		UnknownCells:            settings.UnknownCells,
+		CostLayers:              settings.CostLayers,
+		Water:                   settings.Water,
+		WaterLevel:              settings.WaterLevel,
+		MaxFallHeight:           settings.MaxFallHeight,
+		FallCost:                settings.FallCost,
+		RuneLayer:               settings.RuneLayer,
+		WaypointsOnly:           settings.WaypointsOnly,
+		FixedPoint:              settings.FixedPoint,
+		Algorithm:               algorithm,
+		Heuristic:               heuristic,
+		HeuristicWeight:         settings.HeuristicWeight,
+	}
+}
+
+// heuristicByName returns the Heuristic with the name returned by Heuristic.String.
+func heuristicByName(name string) (Heuristic, bool) {
+
+	for heuristic := DefaultHeuristic; heuristic <= OctileHeuristic; heuristic++ {
+		if heuristic.String() == name {
+			return heuristic, true
+		}
+	}
+	return 0, false
+}
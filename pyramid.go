@@ -0,0 +1,197 @@
+package paths
+
+import (
+	"math"
+	"sync"
+)
+
+// pyramidFactors are the factors the levels of a GridPyramid are downsampled by, from the finest to the coarsest one.
+var pyramidFactors = []int{2, 4}
+
+// A GridPyramid answers path requests coarse to fine, as a simpler alternative to a HierarchicalGrid: it keeps
+// downsampled versions of the Grid (levels), plans the path on the coarsest level first and then searches the Grid
+// only within the blocks of cells along the planned path and their neighboring blocks. If the refined search fails,
+// e.g. because the downsampled walkability closed a narrow passage, the next finer level is tried and finally the whole
+// Grid is searched, so a path is found whenever there is one. The paths follow the route of the plan, so they may be
+// slightly more expensive than the cheapest ones.
+//
+// The levels are built with the movement rules of the settings passed to Grid.NewGridPyramid. If the Grid has been
+// changed, the next request rebuilds them. A GridPyramid can be used by multiple goroutines.
+type GridPyramid struct {
+	grid     *Grid
+	settings PathSettings
+	// levels contains the downsampled grids in the order of pyramidFactors.
+	levels []*Grid
+	// revision is the revision of the Grid the levels have been built for.
+	revision uint64
+	// lock is held for the whole request, as the corridor and the walkability of the levels are changed by requests.
+	lock     sync.Mutex
+	corridor []bool
+}
+
+// NewGridPyramid builds the levels of a GridPyramid, downsampled by 2 and by 4, for the movement rules of the settings.
+// Their start and end are ignored.
+func (m *Grid) NewGridPyramid(settings PathSettings) *GridPyramid {
+
+	settings.start, settings.end, settings.Goal = nil, nil, nil
+
+	p := &GridPyramid{grid: m, settings: settings}
+	p.build()
+	return p
+}
+
+// build builds all levels.
+func (p *GridPyramid) build() {
+
+	p.revision = p.grid.Revision()
+	p.corridor = make([]bool, p.grid.Width()*p.grid.Height())
+	p.levels = p.levels[:0]
+	for _, factor := range pyramidFactors {
+		p.levels = append(p.levels, p.grid.Downsample(factor, p.settings))
+	}
+}
+
+// Levels returns the downsampled grids of the GridPyramid, from the finest to the coarsest one. They must not be
+// changed.
+func (p *GridPyramid) Levels() []*Grid {
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.revision != p.grid.Revision() {
+		p.build()
+	}
+	return append([]*Grid{}, p.levels...)
+}
+
+// Downsample returns a Grid with one Cell for each block of factor x factor cells (at least 1). Its walkability is
+// conservative: a Cell is only walkable if all cells of its block are walkable for the settings and their heights
+// differ by at most the MaxStepHeight and MaxDropHeight, so paths can move freely within the block. The Cost of a Cell
+// is the average cost of moving through its block, i.e. the average cost of the cells for the settings times the
+// factor, and its Elevation the highest TotalHeight of the block. Blocks at the right and bottom edge may be smaller.
+func (m *Grid) Downsample(factor int, settings PathSettings) *Grid {
+
+	if factor < 1 {
+		factor = 1
+	}
+	width, height := (m.Width()+factor-1)/factor, (m.Height()+factor-1)/factor
+	coarse := NewGrid(width, height)
+
+	for _, cell := range coarse.AllCells() {
+
+		walkable := true
+		cost, count := 0.0, 0
+		lowest, highest := math.Inf(1), math.Inf(-1)
+		for y := cell.Y * factor; y < minInt((cell.Y+1)*factor, m.Height()); y++ {
+			for x := cell.X * factor; x < minInt((cell.X+1)*factor, m.Width()); x++ {
+				fine := m.Get(x, y)
+				walkable = walkable && settings.assumeWalkable(fine)
+				cost += m.cellCost(fine, &settings)
+				count++
+				lowest, highest = math.Min(lowest, fine.TotalHeight()), math.Max(highest, fine.TotalHeight())
+			}
+		}
+
+		if (settings.MaxStepHeight >= 0 && highest-lowest > settings.MaxStepHeight) ||
+			(settings.MaxDropHeight >= 0 && highest-lowest > settings.MaxDropHeight) {
+			walkable = false
+		}
+		cell.Walkable = walkable
+		cell.Cost = cost / float64(count) * float64(factor)
+		cell.Elevation = highest
+	}
+
+	return coarse
+}
+
+// GetPath returns a Path from the start to the end Cell, following the movement rules of the GridPyramid. If the start
+// or end isn't walkable, nil is returned; if no path can be found, the Path is empty.
+func (p *GridPyramid) GetPath(start, end *Cell) *Path {
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.revision != p.grid.Revision() {
+		p.build()
+	}
+
+	m := p.grid
+	settings := p.settings
+	settings.start, settings.end = start, end
+	if !settings.endpointsWalkable() {
+		return nil
+	}
+
+	for i := len(p.levels) - 1; i >= 0; i-- {
+		if path := p.refine(pyramidFactors[i], p.levels[i], &settings); path != nil {
+			return path
+		}
+	}
+
+	// none of the levels lead to a path, so only the whole Grid can tell if there is one
+	return m.findPath(&settings, nil)
+}
+
+// refine plans the path on the level downsampled by the factor and searches the Grid along the planned path. If there
+// is no planned path or it can't be refined, nil is returned.
+func (p *GridPyramid) refine(factor int, level *Grid, settings *PathSettings) *Path {
+
+	m := p.grid
+	from := level.Get(settings.start.X/factor, settings.start.Y/factor)
+	to := level.Get(settings.end.X/factor, settings.end.Y/factor)
+
+	// the levels only know the walkability, costs and heights of the cells, so only the rules for these apply. The
+	// blocks of the start and the end may contain unwalkable cells, but the path has to start and end there.
+	coarse := PathSettings{
+		start:               from,
+		end:                 to,
+		MaxStepHeight:       settings.MaxStepHeight,
+		MaxDropHeight:       settings.MaxDropHeight,
+		diagonals:           settings.diagonals,
+		wallBlocksDiagonals: settings.wallBlocksDiagonals,
+		Directions:          settings.Directions,
+		Heuristic:           settings.Heuristic,
+	}
+	fromWalkable, toWalkable := from.Walkable, to.Walkable
+	from.Walkable, to.Walkable = true, true
+	plan := level.findPath(&coarse, nil)
+	from.Walkable, to.Walkable = fromWalkable, toWalkable
+
+	if plan == nil || len(plan.Cells) == 0 {
+		return nil
+	}
+
+	// the search is restricted to the planned blocks and their neighbors, which leaves room to go around the obstacles
+	// within the blocks
+	blocks := make(map[Point]bool)
+	for _, cell := range plan.Cells {
+		for y := cell.Y - 1; y <= cell.Y+1; y++ {
+			for x := cell.X - 1; x <= cell.X+1; x++ {
+				blocks[Point{x, y}] = true
+			}
+		}
+	}
+	p.setCorridor(blocks, factor, true)
+	defer p.setCorridor(blocks, factor, false)
+
+	refined := *settings
+	refined.corridor = p.corridor
+	path := m.findPath(&refined, nil)
+	if path == nil || len(path.Cells) == 0 {
+		return nil
+	}
+	return path
+}
+
+// setCorridor adds the cells of the blocks of the level downsampled by the factor to the corridor or removes them.
+func (p *GridPyramid) setCorridor(blocks map[Point]bool, factor int, value bool) {
+
+	m := p.grid
+	for block := range blocks {
+		for y := maxInt(block.Y*factor, 0); y < minInt((block.Y+1)*factor, m.Height()); y++ {
+			for x := maxInt(block.X*factor, 0); x < minInt((block.X+1)*factor, m.Width()); x++ {
+				p.corridor[y*m.Width()+x] = value
+			}
+		}
+	}
+}
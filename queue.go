@@ -0,0 +1,262 @@
+package paths
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A RequestQueue collects path requests and runs them later, e.g. a limited amount per frame, so many agents asking
+// for paths at once don't cause a lag spike. Identical requests (same Grid, revision and settings) waiting in the queue
+// are coalesced into a single search, whose result is shared by all of them; this way hundreds of units ordered to the
+// same spot trigger only one search. Requests with a higher priority run first, requests with the same priority in
+// the order they have been made. With an AgingPolicy (see SetAging), waiting requests gain priority over time, so
+// low-priority requests eventually run even under constant high-priority load. A RequestQueue can be used by multiple
+// goroutines.
+type RequestQueue struct {
+	lock     sync.Mutex
+	pending  requestHeap
+	byKey    map[string]*pathRequest
+	sequence uint64
+	aging    AgingPolicy
+}
+
+// An AgingPolicy defines how requests waiting in a RequestQueue gain priority, e.g. to keep background NPCs from
+// freezing during combat-heavy frames. The zero value disables aging.
+type AgingPolicy struct {
+	// Interval is the waiting time after which a request gains Step priority. Zero disables aging.
+	Interval time.Duration
+	// Step is the priority gained per Interval.
+	Step int
+	// MaxBoost is the most priority a request can gain by aging. Zero means unlimited.
+	MaxBoost int
+}
+
+// A PathTicket is handed out for each request of a RequestQueue to get its result once the search has run.
+type PathTicket struct {
+	lock sync.Mutex
+	done bool
+	path *Path
+}
+
+// pathRequest is a search waiting in a RequestQueue, including all requests coalesced into it.
+type pathRequest struct {
+	key      string
+	grid     *Grid
+	settings PathSettings
+	priority int
+	// effective is the priority including the boost of the AgingPolicy, which the requests are ordered by.
+	effective int
+	requested time.Time
+	sequence  uint64
+	index     int
+	tickets   []*PathTicket
+}
+
+// NewRequestQueue returns an empty RequestQueue.
+func NewRequestQueue() *RequestQueue {
+	return &RequestQueue{byKey: make(map[string]*pathRequest)}
+}
+
+// Request queues a search on the Grid with the passed settings and returns a ticket to get its Path. If an identical
+// request is already waiting, no new search is queued; the waiting one gets the higher priority of both. Settings are
+// identical, if all of their values are equal; pointers (like Knowledge) have to be the same instances. Requests with a
+// Goal are never coalesced, as functions can't be compared. Only the OnSearchComplete callback of the first request is
+// called.
+func (q *RequestQueue) Request(grid *Grid, settings PathSettings, priority int) *PathTicket {
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	ticket := &PathTicket{}
+	key := requestKey(grid, &settings)
+
+	if request, exists := q.byKey[key]; exists && key != "" {
+		request.tickets = append(request.tickets, ticket)
+		if priority > request.priority {
+			request.priority = priority
+			request.effective = q.aging.priority(request, time.Now())
+			heap.Fix(&q.pending, request.index)
+		}
+		return ticket
+	}
+
+	q.sequence++
+	request := &pathRequest{
+		key:       key,
+		grid:      grid,
+		settings:  settings,
+		priority:  priority,
+		effective: priority,
+		requested: time.Now(),
+		sequence:  q.sequence,
+		tickets:   []*PathTicket{ticket},
+	}
+	if key != "" {
+		q.byKey[key] = request
+	}
+	heap.Push(&q.pending, request)
+
+	return ticket
+}
+
+// SetAging sets the AgingPolicy of the queue.
+func (q *RequestQueue) SetAging(policy AgingPolicy) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.aging = policy
+	q.reorder()
+}
+
+// reorder updates the effective priorities of all waiting requests.
+func (q *RequestQueue) reorder() {
+
+	now := time.Now()
+	for _, request := range q.pending {
+		request.effective = q.aging.priority(request, now)
+	}
+	heap.Init(&q.pending)
+}
+
+// priority returns the priority of the request including the boost it has gained by waiting until now.
+func (policy AgingPolicy) priority(request *pathRequest, now time.Time) int {
+
+	if policy.Interval <= 0 {
+		return request.priority
+	}
+
+	boost := int(now.Sub(request.requested)/policy.Interval) * policy.Step
+	if policy.MaxBoost > 0 && boost > policy.MaxBoost {
+		boost = policy.MaxBoost
+	}
+	return request.priority + boost
+}
+
+// Len returns the amount of searches waiting in the queue. Coalesced requests count as one.
+func (q *RequestQueue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.pending)
+}
+
+// ProcessNext runs the waiting search with the highest priority and hands its result to all of its tickets. If the
+// queue is empty, false is returned.
+func (q *RequestQueue) ProcessNext() bool {
+
+	q.lock.Lock()
+	if len(q.pending) == 0 {
+		q.lock.Unlock()
+		return false
+	}
+	if q.aging.Interval > 0 {
+		q.reorder()
+	}
+	request := heap.Pop(&q.pending).(*pathRequest)
+	delete(q.byKey, request.key)
+	q.lock.Unlock()
+
+	// the search runs without holding the lock, so new requests can be made meanwhile
+	path := request.grid.GetPathFromSettings(request.settings)
+	for _, ticket := range request.tickets {
+		ticket.finish(path)
+	}
+
+	return true
+}
+
+// Process runs up to maxSearches waiting searches, see ProcessNext, and returns the amount of searches run.
+func (q *RequestQueue) Process(maxSearches int) int {
+
+	processed := 0
+	for processed < maxSearches && q.ProcessNext() {
+		processed++
+	}
+	return processed
+}
+
+// Done returns if the search of the ticket has run.
+func (t *PathTicket) Done() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.done
+}
+
+// Path returns the Path found by the search of the ticket, or nil if it hasn't run yet (see Done) or no path could be
+// found. Each ticket gets its own copy of the Path, so agents sharing a result can walk it independently.
+func (t *PathTicket) Path() *Path {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.path
+}
+
+// finish stores a copy of the found Path in the ticket.
+func (t *PathTicket) finish(path *Path) {
+
+	copied := copyPath(path)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.done = true
+	t.path = copied
+}
+
+// copyPath returns a copy of the Path, which can be walked independently of it. nil is copied as nil.
+func copyPath(path *Path) *Path {
+
+	if path == nil {
+		return nil
+	}
+
+	copied := &Path{}
+	*copied = *path
+	copied.Cells = append([]*Cell{}, path.Cells...)
+	copied.Postures = append([]string(nil), path.Postures...)
+	return copied
+}
+
+// requestKey returns a key, which is equal for identical requests. Pointers (including the start and end cells) are
+// compared by their address. Functions can't be compared (closures of the same function share their address), so
+// requests with a Goal get an empty key, which is never equal to another one; OnSearchComplete is ignored.
+func requestKey(grid *Grid, settings *PathSettings) string {
+
+	if settings.Goal != nil {
+		return ""
+	}
+	keySettings := *settings
+	keySettings.OnSearchComplete = nil
+	return fmt.Sprintf("%p %d %v", grid, grid.Revision(), keySettings)
+}
+
+// requestHeap orders the waiting requests by their effective priority and sequence.
+type requestHeap []*pathRequest
+
+func (h requestHeap) Len() int {
+	return len(h)
+}
+
+func (h requestHeap) Less(i, j int) bool {
+	if h[i].effective != h[j].effective {
+		return h[i].effective > h[j].effective
+	}
+	return h[i].sequence < h[j].sequence
+}
+
+func (h requestHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *requestHeap) Push(x interface{}) {
+	request := x.(*pathRequest)
+	request.index = len(*h)
+	*h = append(*h, request)
+}
+
+func (h *requestHeap) Pop() interface{} {
+	old := *h
+	request := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return request
+}
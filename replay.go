@@ -0,0 +1,533 @@
+package paths
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// replayVersion is the version of the replay format written by Replay.Save.
+const replayVersion = 1
+
+// ErrReplayMismatch is returned by Replay.Verify if a replayed search behaves differently than the recorded one.
+var ErrReplayMismatch = errors.New("replay mismatch")
+
+// A Replay is a log of a single search: the Grid it was run on, the settings, the order in which the cells were
+// expanded and the resulting path. Replays can be saved to and loaded from JSON, so a surprising path can be attached
+// to a bug report and be replayed deterministically by someone else. Create one with Grid.RecordPath.
+type Replay struct {
+	Version      int          `json:"version"`
+	GridRevision uint64       `json:"gridRevision"`
+	Width        int          `json:"width"`
+	Height       int          `json:"height"`
+	Cells        []ReplayCell `json:"cells"`
+	SeaLevel     float64      `json:"seaLevel"`
+	// Categories contains the runes of each category of the Grid, see Grid.Categorize.
+	Categories map[string][]rune `json:"categories,omitempty"`
+	// RuneLayers contains the rows of each rune layer of the Grid, see Grid.RuneLayer.
+	RuneLayers map[string][]string `json:"runeLayers,omitempty"`
+	// CostLayers contains the modifiers of each cost layer of the Grid, see Grid.CostLayer.
+	CostLayers map[string][]ReplayModifier `json:"costLayers,omitempty"`
+	// Edges contains the edge costs of the Grid, see Grid.SetEdgeCost.
+	Edges    []ReplayEdge   `json:"edges,omitempty"`
+	Settings ReplaySettings `json:"settings"`
+	// Expansions contains the positions of all expanded cells in the order they were expanded in.
+	Expansions []Point `json:"expansions"`
+	// Result contains the positions of the cells of the found path. It's empty if no path was found.
+	Result []Point `json:"result"`
+}
+
+// ReplayCell is the serializable state of a Cell. The position is given by the index in Replay.Cells (row by row).
+// Cells with an infinite cost are marked as Blocked, like ReplayEdge does it.
+type ReplayCell struct {
+	HeightLevel      int     `json:"heightLevel"`
+	Elevation        float64 `json:"elevation"`
+	Cost             float64 `json:"cost"`
+	Blocked          bool    `json:"blocked,omitempty"`
+	Walkable         bool    `json:"walkable"`
+	Rune             rune    `json:"rune"`
+	Occupancy        int     `json:"occupancy"`
+	Clearance        float64 `json:"clearance"`
+	CostVariance     float64 `json:"costVariance"`
+	BlockProbability float64 `json:"blockProbability"`
+	Unknown          bool    `json:"unknown"`
+	EnterCost        float64 `json:"enterCost,omitempty"`
+	ExitCost         float64 `json:"exitCost,omitempty"`
+}
+
+// ReplayEdge is the serializable cost of the move from one Cell to another, see Grid.SetEdgeCost. Blocked moves have
+// an infinite cost, which can't be represented in JSON.
+type ReplayEdge struct {
+	From    Point   `json:"from"`
+	To      Point   `json:"to"`
+	Cost    float64 `json:"cost"`
+	Blocked bool    `json:"blocked,omitempty"`
+}
+
+// ReplayModifier is the serializable modifier of a CostLayer for the Cell at Position.
+type ReplayModifier struct {
+	Position Point   `json:"position"`
+	Add      float64 `json:"add"`
+	Factor   float64 `json:"factor"`
+}
+
+// ReplayKnowledge is the serializable form of a KnowledgeLayer.
+type ReplayKnowledge struct {
+	UnknownCost float64           `json:"unknownCost"`
+	Cells       []ReplayKnownCell `json:"cells"`
+}
+
+// ReplayKnownCell is the remembered state of the Cell at Position in a KnowledgeLayer.
+type ReplayKnownCell struct {
+	Position Point   `json:"position"`
+	Walkable bool    `json:"walkable"`
+	Cost     float64 `json:"cost"`
+}
+
+// ReplayLandmarks are the serializable Landmarks of a search: the landmark cells and the movement rules they have been
+// computed with. Their costs are computed again when the Replay is run. The KnowledgeLayer of their settings isn't
+// recorded.
+type ReplayLandmarks struct {
+	Cells    []Point `json:"cells"`
+	Settings Preset  `json:"settings"`
+}
+
+// ReplaySettings is the serializable form of PathSettings.
+type ReplaySettings struct {
+	Start Point `json:"start"`
+	// End is nil for searches ending at a Goal.
+	End *Point `json:"end,omitempty"`
+	// GoalCells contains all cells the Goal of the settings accepted, when the search was recorded. It's null for
+	// searches without a Goal.
+	GoalCells               []Point          `json:"goalCells"`
+	StopWithinRange         float64          `json:"stopWithinRange"`
+	RangeMetric             DistanceMetric   `json:"rangeMetric"`
+	RequireLineOfSight      bool             `json:"requireLineOfSight"`
+	MaxStepHeight           float64          `json:"maxStepHeight"`
+	MaxDropHeight           float64          `json:"maxDropHeight"`
+	MaxFallHeight           float64          `json:"maxFallHeight,omitempty"`
+	FallCost                float64          `json:"fallCost,omitempty"`
+	Diagonals               bool             `json:"diagonals"`
+	WallBlocksDiagonals     bool             `json:"wallBlocksDiagonals"`
+	RequireOptimal          bool             `json:"requireOptimal"`
+	MaxOccupancy            int              `json:"maxOccupancy"`
+	MemoryLimit             int              `json:"memoryLimit"`
+	Directions              Direction        `json:"directions"`
+	BlockedCategories       []string         `json:"blockedCategories,omitempty"`
+	AgentHeight             float64          `json:"agentHeight"`
+	Postures                []Posture        `json:"postures,omitempty"`
+	RiskAversion            float64          `json:"riskAversion"`
+	Knowledge               *ReplayKnowledge `json:"knowledge,omitempty"`
+	MinTraversalProbability float64          `json:"minTraversalProbability"`
+	UnknownCells            UnknownPolicy    `json:"unknownCells"`
+	CostLayers              []string         `json:"costLayers,omitempty"`
+	Water                   *WaterProfile    `json:"water,omitempty"`
+	WaterLevel              *float64         `json:"waterLevel,omitempty"`
+	WaypointsOnly           bool             `json:"waypointsOnly"`
+	FixedPoint              bool             `json:"fixedPoint"`
+	RuneLayer               string           `json:"runeLayer,omitempty"`
+	Algorithm               Algorithm        `json:"algorithm"`
+	Heuristic               Heuristic        `json:"heuristic"`
+	HeuristicWeight         float64          `json:"heuristicWeight,omitempty"`
+	// Landmarks is nil if the search didn't use Landmarks, e.g. because they were outdated.
+	Landmarks *ReplayLandmarks `json:"landmarks,omitempty"`
+	// GoalBounds contains the movement rules the GoalBounds of the search have been computed with. They are computed
+	// again when the Replay is run. It's nil if the search didn't use GoalBounds.
+	GoalBounds *Preset `json:"goalBounds,omitempty"`
+}
+
+// RecordPath works like GetPathFromSettings, but additionally returns a Replay of the search.
+func (m *Grid) RecordPath(settings PathSettings) (*Path, *Replay) {
+
+	replay := &Replay{
+		Version:      replayVersion,
+		GridRevision: m.Revision(),
+		Width:        m.Width(),
+		Height:       m.Height(),
+		SeaLevel:     m.seaLevel,
+		Settings:     newReplaySettings(m, &settings),
+	}
+
+	for _, category := range m.Categories() {
+		if replay.Categories == nil {
+			replay.Categories = make(map[string][]rune)
+		}
+		replay.Categories[category] = m.CategoryRunes(category)
+	}
+
+	for _, name := range m.RuneLayers() {
+		if replay.RuneLayers == nil {
+			replay.RuneLayers = make(map[string][]string)
+		}
+		rows := []string{}
+		for _, row := range m.runeLayers[name].runes {
+			rows = append(rows, string(row))
+		}
+		replay.RuneLayers[name] = rows
+	}
+
+	for _, name := range m.CostLayers() {
+		if replay.CostLayers == nil {
+			replay.CostLayers = make(map[string][]ReplayModifier)
+		}
+		modifiers := []ReplayModifier{}
+		for _, cell := range m.AllCells() {
+			if modifier, exists := m.costLayers[name].modifiers[cell]; exists {
+				modifiers = append(modifiers, ReplayModifier{Point{cell.X, cell.Y}, modifier.add, modifier.factor})
+			}
+		}
+		replay.CostLayers[name] = modifiers
+	}
+
+	for _, key := range m.sortedEdges() {
+		from, to := m.cellAt(key.from), m.cellAt(key.to)
+		edge := ReplayEdge{From: Point{from.X, from.Y}, To: Point{to.X, to.Y}, Cost: m.edgeCosts[key]}
+		if math.IsInf(edge.Cost, 1) {
+			edge.Cost, edge.Blocked = 0, true
+		}
+		replay.Edges = append(replay.Edges, edge)
+	}
+
+	for _, cell := range m.AllCells() {
+		replayCell := ReplayCell{
+			HeightLevel:      cell.HeightLevel,
+			Elevation:        cell.Elevation,
+			Cost:             cell.Cost,
+			Walkable:         cell.Walkable,
+			Rune:             cell.Rune,
+			Occupancy:        cell.Occupancy,
+			Clearance:        cell.Clearance,
+			CostVariance:     cell.CostVariance,
+			EnterCost:        cell.EnterCost,
+			ExitCost:         cell.ExitCost,
+			BlockProbability: cell.BlockProbability,
+			Unknown:          cell.Unknown,
+		}
+		if math.IsInf(replayCell.Cost, 1) {
+			replayCell.Cost, replayCell.Blocked = 0, true
+		}
+		replay.Cells = append(replay.Cells, replayCell)
+	}
+
+	path := m.findPath(&settings, &searchObserver{
+		expanded: func(cell *Cell) {
+			replay.Expansions = append(replay.Expansions, Point{cell.X, cell.Y})
+		},
+	})
+
+	if path != nil {
+		for _, cell := range path.Cells {
+			replay.Result = append(replay.Result, Point{cell.X, cell.Y})
+		}
+	}
+
+	return path, replay
+}
+
+// LoadReplay reads a Replay, which has been written by Replay.Save. Replays whose size doesn't match their cells or
+// whose start, end, goals or landmarks lie outside of the Grid are rejected with an error, so they can't break Run.
+func LoadReplay(r io.Reader) (*Replay, error) {
+
+	replay := &Replay{}
+	if err := json.NewDecoder(r).Decode(replay); err != nil {
+		return nil, err
+	}
+
+	if replay.Version != replayVersion {
+		return nil, fmt.Errorf("unsupported replay version %d", replay.Version)
+	}
+	// the size is checked without multiplying it, which could overflow
+	if replay.Width <= 0 || replay.Height <= 0 || len(replay.Cells)%replay.Width != 0 ||
+		len(replay.Cells)/replay.Width != replay.Height {
+		return nil, fmt.Errorf("replay contains %d cells, but the grid is %dx%d", len(replay.Cells), replay.Width, replay.Height)
+	}
+
+	// the positions of the settings are used to look up cells without further checks
+	inside := func(position Point) bool {
+		return position.X >= 0 && position.Y >= 0 && position.X < replay.Width && position.Y < replay.Height
+	}
+	settings := replay.Settings
+	if !inside(settings.Start) {
+		return nil, fmt.Errorf("replay starts at %v outside of the %dx%d grid", settings.Start, replay.Width, replay.Height)
+	}
+	if settings.End != nil && !inside(*settings.End) {
+		return nil, fmt.Errorf("replay ends at %v outside of the %dx%d grid", *settings.End, replay.Width, replay.Height)
+	}
+	for _, position := range settings.GoalCells {
+		if !inside(position) {
+			return nil, fmt.Errorf("replay has a goal at %v outside of the %dx%d grid", position, replay.Width, replay.Height)
+		}
+	}
+	if landmarks := settings.Landmarks; landmarks != nil {
+		for _, position := range landmarks.Cells {
+			if !inside(position) {
+				return nil, fmt.Errorf("replay has a landmark at %v outside of the %dx%d grid", position, replay.Width,
+					replay.Height)
+			}
+		}
+		if _, err := landmarks.Settings.PathSettings(nil, nil); err != nil {
+			return nil, err
+		}
+	}
+	if settings.GoalBounds != nil {
+		if _, err := settings.GoalBounds.PathSettings(nil, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return replay, nil
+}
+
+// Save writes the Replay as JSON.
+func (r *Replay) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// Grid restores the Grid the search has been run on.
+func (r *Replay) Grid() *Grid {
+
+	grid := NewGrid(r.Width, r.Height)
+	for i, replayCell := range r.Cells {
+		cell := grid.Get(i%r.Width, i/r.Width)
+		cell.HeightLevel = replayCell.HeightLevel
+		cell.Elevation = replayCell.Elevation
+		cell.Cost = replayCell.Cost
+		if replayCell.Blocked {
+			cell.Cost = math.Inf(1)
+		}
+		cell.Walkable = replayCell.Walkable
+		cell.Rune = replayCell.Rune
+		cell.Occupancy = replayCell.Occupancy
+		cell.Clearance = replayCell.Clearance
+		cell.CostVariance = replayCell.CostVariance
+		cell.EnterCost, cell.ExitCost = replayCell.EnterCost, replayCell.ExitCost
+		cell.BlockProbability = replayCell.BlockProbability
+		cell.Unknown = replayCell.Unknown
+	}
+	if len(r.Categories) > 0 {
+		grid.Categorize(r.Categories)
+	}
+	for name, rows := range r.RuneLayers {
+		grid.AddRuneLayer(name, rows)
+	}
+	for name, modifiers := range r.CostLayers {
+		layer := grid.CostLayer(name)
+		for _, modifier := range modifiers {
+			if cell := grid.Get(modifier.Position.X, modifier.Position.Y); cell != nil {
+				layer.modifiers[cell] = costModifier{modifier.Add, modifier.Factor}
+			}
+		}
+	}
+	for _, edge := range r.Edges {
+		from, to := grid.Get(edge.From.X, edge.From.Y), grid.Get(edge.To.X, edge.To.Y)
+		if from == nil || to == nil {
+			continue
+		}
+		cost := edge.Cost
+		if edge.Blocked {
+			cost = math.Inf(1)
+		}
+		grid.SetEdgeCost(from, to, cost)
+	}
+	grid.seaLevel = r.SeaLevel
+	grid.revision = r.GridRevision
+
+	return grid
+}
+
+// Run runs the recorded search again on the restored Grid and returns the resulting Path and its Replay.
+func (r *Replay) Run() (*Path, *Replay) {
+	grid := r.Grid()
+	return grid.RecordPath(*r.Settings.pathSettings(grid))
+}
+
+// Verify runs the recorded search again and checks, that the cells are expanded in the same order and that the same
+// path is found. If not, an error wrapping ErrReplayMismatch is returned.
+func (r *Replay) Verify() error {
+
+	_, replayed := r.Run()
+
+	if err := comparePoints("expansion", r.Expansions, replayed.Expansions); err != nil {
+		return err
+	}
+	return comparePoints("path cell", r.Result, replayed.Result)
+}
+
+// comparePoints returns an error wrapping ErrReplayMismatch if the recorded and the replayed points differ.
+func comparePoints(name string, recorded, replayed []Point) error {
+
+	for i := 0; i < len(recorded) && i < len(replayed); i++ {
+		if recorded[i] != replayed[i] {
+			return fmt.Errorf("%w: %s %d is %v instead of %v", ErrReplayMismatch, name, i, replayed[i], recorded[i])
+		}
+	}
+
+	if len(recorded) != len(replayed) {
+		return fmt.Errorf("%w: %d instead of %d %ss", ErrReplayMismatch, len(replayed), len(recorded), name)
+	}
+	return nil
+}
+
+func newReplaySettings(grid *Grid, settings *PathSettings) ReplaySettings {
+
+	var end *Point
+	if settings.end != nil {
+		end = &Point{settings.end.X, settings.end.Y}
+	}
+
+	// a GoalFunc can't be saved, but the cells it accepts can
+	var goalCells []Point
+	if settings.Goal != nil {
+		goalCells = []Point{}
+		for _, cell := range grid.AllCells() {
+			if settings.Goal(cell) {
+				goalCells = append(goalCells, Point{cell.X, cell.Y})
+			}
+		}
+	}
+
+	var knowledge *ReplayKnowledge
+	if settings.Knowledge != nil {
+		knowledge = &ReplayKnowledge{UnknownCost: settings.Knowledge.UnknownCost, Cells: []ReplayKnownCell{}}
+		for _, cell := range settings.Knowledge.KnownCells() {
+			walkable, cost, _ := settings.Knowledge.Remembered(cell)
+			knowledge.Cells = append(knowledge.Cells, ReplayKnownCell{Point{cell.X, cell.Y}, walkable, cost})
+		}
+	}
+
+	var landmarks *ReplayLandmarks
+	if settings.Landmarks != nil && settings.Landmarks.usable(grid, settings) {
+		landmarks = &ReplayLandmarks{Cells: []Point{}, Settings: newPreset(&settings.Landmarks.settings)}
+		for _, cell := range settings.Landmarks.cells {
+			landmarks.Cells = append(landmarks.Cells, Point{cell.X, cell.Y})
+		}
+	}
+
+	var goalBounds *Preset
+	if settings.GoalBounds != nil && settings.GoalBounds.usable(grid, settings) {
+		preset := newPreset(&settings.GoalBounds.settings)
+		goalBounds = &preset
+	}
+
+	return ReplaySettings{
+		Start:                   Point{settings.start.X, settings.start.Y},
+		End:                     end,
+		GoalCells:               goalCells,
+		StopWithinRange:         settings.StopWithinRange,
+		RangeMetric:             settings.RangeMetric,
+		RequireLineOfSight:      settings.RequireLineOfSight,
+		MaxStepHeight:           settings.MaxStepHeight,
+		MaxDropHeight:           settings.MaxDropHeight,
+		MaxFallHeight:           settings.MaxFallHeight,
+		FallCost:                settings.FallCost,
+		Diagonals:               settings.diagonals,
+		WallBlocksDiagonals:     settings.wallBlocksDiagonals,
+		RequireOptimal:          settings.RequireOptimal,
+		MaxOccupancy:            settings.MaxOccupancy,
+		MemoryLimit:             settings.MemoryLimit,
+		Directions:              settings.Directions,
+		BlockedCategories:       settings.BlockedCategories,
+		AgentHeight:             settings.AgentHeight,
+		Postures:                settings.Postures,
+		RiskAversion:            settings.RiskAversion,
+		Knowledge:               knowledge,
+		MinTraversalProbability: settings.MinTraversalProbability,
+		UnknownCells:            settings.UnknownCells,
+		CostLayers:              settings.CostLayers,
+		Water:                   settings.Water,
+		WaterLevel:              settings.WaterLevel,
+		WaypointsOnly:           settings.WaypointsOnly,
+		FixedPoint:              settings.FixedPoint,
+		RuneLayer:               settings.RuneLayer,
+		Algorithm:               settings.Algorithm,
+		Heuristic:               settings.Heuristic,
+		HeuristicWeight:         settings.HeuristicWeight,
+		Landmarks:               landmarks,
+		GoalBounds:              goalBounds,
+	}
+}
+
+// pathSettings converts the ReplaySettings back into PathSettings for the passed Grid.
+func (s ReplaySettings) pathSettings(grid *Grid) *PathSettings {
+
+	var end *Cell
+	if s.End != nil {
+		end = grid.Get(s.End.X, s.End.Y)
+	}
+
+	var goal GoalFunc
+	if s.GoalCells != nil {
+		goals := []*Cell{}
+		for _, position := range s.GoalCells {
+			goals = append(goals, grid.Get(position.X, position.Y))
+		}
+		goal = GoalCells(goals...)
+	}
+
+	var knowledge *KnowledgeLayer
+	if s.Knowledge != nil {
+		knowledge = NewKnowledgeLayer(grid)
+		knowledge.UnknownCost = s.Knowledge.UnknownCost
+		for _, known := range s.Knowledge.Cells {
+			if cell := grid.Get(known.Position.X, known.Position.Y); cell != nil {
+				knowledge.remembered[cell] = rememberedCell{walkable: known.Walkable, cost: known.Cost}
+			}
+		}
+	}
+
+	var landmarks *Landmarks
+	if s.Landmarks != nil {
+		// the settings of the Landmarks and the GoalBounds have been checked by LoadReplay
+		settings, _ := s.Landmarks.Settings.PathSettings(nil, nil)
+		cells := []*Cell{}
+		for _, position := range s.Landmarks.Cells {
+			cells = append(cells, grid.Get(position.X, position.Y))
+		}
+		landmarks = grid.landmarksAt(cells, *settings)
+	}
+
+	var goalBounds *GoalBounds
+	if s.GoalBounds != nil {
+		settings, _ := s.GoalBounds.PathSettings(nil, nil)
+		goalBounds = grid.NewGoalBounds(*settings)
+	}
+
+	return &PathSettings{
+		start:                   grid.Get(s.Start.X, s.Start.Y),
+		end:                     end,
+		Goal:                    goal,
+		StopWithinRange:         s.StopWithinRange,
+		RangeMetric:             s.RangeMetric,
+		RequireLineOfSight:      s.RequireLineOfSight,
+		MaxStepHeight:           s.MaxStepHeight,
+		MaxDropHeight:           s.MaxDropHeight,
+		MaxFallHeight:           s.MaxFallHeight,
+		FallCost:                s.FallCost,
+		diagonals:               s.Diagonals,
+		wallBlocksDiagonals:     s.WallBlocksDiagonals,
+		RequireOptimal:          s.RequireOptimal,
+		MaxOccupancy:            s.MaxOccupancy,
+		MemoryLimit:             s.MemoryLimit,
+		Directions:              s.Directions,
+		BlockedCategories:       s.BlockedCategories,
+		AgentHeight:             s.AgentHeight,
+		Postures:                s.Postures,
+		RiskAversion:            s.RiskAversion,
+		Knowledge:               knowledge,
+		MinTraversalProbability: s.MinTraversalProbability,
+		UnknownCells:            s.UnknownCells,
+		CostLayers:              s.CostLayers,
+		Water:                   s.Water,
+		WaterLevel:              s.WaterLevel,
+		WaypointsOnly:           s.WaypointsOnly,
+		FixedPoint:              s.FixedPoint,
+		RuneLayer:               s.RuneLayer,
+		Algorithm:               s.Algorithm,
+		Heuristic:               s.Heuristic,
+		HeuristicWeight:         s.HeuristicWeight,
+		Landmarks:               landmarks,
+		GoalBounds:              goalBounds,
+	}
+}
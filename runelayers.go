@@ -0,0 +1,131 @@
+package paths
+
+import "sort"
+
+// A RuneLayer is an additional named layer of runes on a Grid, e.g. a "collision" layer next to the visual runes of
+// the cells, so display characters don't have to double as gameplay semantics. Layers are stored on the Grid (see
+// Grid.RuneLayer). Searches check PathSettings.BlockedCategories against the layer named in PathSettings.RuneLayer;
+// the walkability, cost and height of the cells can be derived from a layer with its setters.
+type RuneLayer struct {
+	grid  *Grid
+	runes [][]rune
+}
+
+// RuneLayer returns the rune layer with the passed name. If the Grid doesn't contain a layer with this name yet, one
+// filled with spaces is created.
+func (m *Grid) RuneLayer(name string) *RuneLayer {
+
+	if m.runeLayers == nil {
+		m.runeLayers = make(map[string]*RuneLayer)
+	}
+
+	layer, exists := m.runeLayers[name]
+	if !exists {
+		layer = &RuneLayer{grid: m, runes: make([][]rune, m.Height())}
+		for y := range layer.runes {
+			layer.runes[y] = make([]rune, m.Width())
+			for x := range layer.runes[y] {
+				layer.runes[y][x] = ' '
+			}
+		}
+		m.runeLayers[name] = layer
+	}
+
+	return layer
+}
+
+// AddRuneLayer creates or replaces the rune layer with the passed name and fills it from the strings, each of which
+// is a row of the Grid like with NewGridFromStringArrays. Positions the strings don't cover are filled with spaces.
+func (m *Grid) AddRuneLayer(name string, rows []string) *RuneLayer {
+
+	m.RemoveRuneLayer(name)
+	layer := m.RuneLayer(name)
+
+	for y, row := range rows {
+		for x, r := range []rune(row) {
+			if cell := m.Get(x, y); cell != nil {
+				layer.runes[y][x] = r
+			}
+		}
+	}
+
+	m.MarkChanged()
+	return layer
+}
+
+// RemoveRuneLayer removes the rune layer with the passed name from the Grid.
+func (m *Grid) RemoveRuneLayer(name string) {
+	if _, exists := m.runeLayers[name]; exists {
+		delete(m.runeLayers, name)
+		m.MarkChanged()
+	}
+}
+
+// RuneLayers returns the names of all rune layers of the Grid in alphabetical order.
+func (m *Grid) RuneLayers() []string {
+
+	names := make([]string, 0, len(m.runeLayers))
+	for name := range m.runeLayers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Get returns the rune of the Cell in the layer.
+func (l *RuneLayer) Get(cell *Cell) rune {
+	return l.runes[cell.Y][cell.X]
+}
+
+// Set sets the rune of the Cell in the layer.
+func (l *RuneLayer) Set(cell *Cell, r rune) {
+	l.runes[cell.Y][cell.X] = r
+	l.grid.MarkChanged()
+}
+
+// CellsByRune returns all cells, which have the rune in the layer.
+func (l *RuneLayer) CellsByRune(r rune) []*Cell {
+
+	cells := []*Cell{}
+	for _, cell := range l.grid.AllCells() {
+		if l.Get(cell) == r {
+			cells = append(cells, cell)
+		}
+	}
+
+	return cells
+}
+
+// SetWalkable sets the walkability of all cells, which have the rune in the layer, see Grid.SetWalkable.
+func (l *RuneLayer) SetWalkable(r rune, walkable bool) {
+	for _, cell := range l.CellsByRune(r) {
+		l.grid.Stage(cell).Walkable = walkable
+	}
+	l.grid.markCellsChanged()
+}
+
+// SetCost sets the cost of all cells, which have the rune in the layer, see Grid.SetCost.
+func (l *RuneLayer) SetCost(r rune, cost float64) {
+	for _, cell := range l.CellsByRune(r) {
+		l.grid.Stage(cell).Cost = cost
+	}
+	l.grid.markCellsChanged()
+}
+
+// SetHeightLevel sets the height level of all cells, which have the rune in the layer, see Grid.SetHeightLevel.
+func (l *RuneLayer) SetHeightLevel(r rune, heightLevel int) {
+	for _, cell := range l.CellsByRune(r) {
+		l.grid.Stage(cell).HeightLevel = heightLevel
+	}
+	l.grid.markCellsChanged()
+}
+
+// runeOf returns the rune of the Cell in the rune layer of the settings, or the Rune of the Cell, if the settings
+// don't name a layer or the Grid doesn't contain it.
+func (m *Grid) runeOf(cell *Cell, settings *PathSettings) rune {
+	if layer, exists := m.runeLayers[settings.RuneLayer]; exists && settings.RuneLayer != "" {
+		return layer.Get(cell)
+	}
+	return cell.Rune
+}
@@ -0,0 +1,92 @@
+package paths
+
+import "time"
+
+// A Task is work, which is split into small steps, e.g. a time-sliced search or a precomputation. Tasks are run by a
+// Scheduler.
+type Task interface {
+	// Step does a small part of the work and returns true, once the task is finished.
+	Step() bool
+}
+
+// A Scheduler runs the searches of a RequestQueue and tasks within a time budget per frame, so pathfinding never
+// causes a lag spike. Call Run once per frame. A Scheduler must only be used by one goroutine.
+type Scheduler struct {
+	// Queue is the RequestQueue, whose searches are run. It may be nil.
+	Queue *RequestQueue
+	tasks []Task
+	// next is the index of the task to step next, so all tasks progress evenly.
+	next int
+}
+
+// A SchedulerReport tells what a Scheduler has done during Run and what has been deferred to the next frame.
+type SchedulerReport struct {
+	// Searches is the amount of searches run, Steps the amount of task steps done and Finished the amount of tasks
+	// finished.
+	Searches, Steps, Finished int
+	// DeferredSearches is the amount of searches still waiting in the queue, DeferredTasks the amount of unfinished
+	// tasks.
+	DeferredSearches, DeferredTasks int
+	// Elapsed is the time Run took. It can exceed the budget by the duration of the last search or step.
+	Elapsed time.Duration
+}
+
+// NewScheduler returns a Scheduler running the searches of the queue, which may be nil.
+func NewScheduler(queue *RequestQueue) *Scheduler {
+	return &Scheduler{Queue: queue}
+}
+
+// Add adds a task, which is run step by step during the following calls of Run.
+func (s *Scheduler) Add(task Task) {
+	s.tasks = append(s.tasks, task)
+}
+
+// Tasks returns the amount of unfinished tasks.
+func (s *Scheduler) Tasks() int {
+	return len(s.tasks)
+}
+
+// Run runs waiting searches and steps of the tasks until the budget is used up or there is nothing left to do.
+// Searches and task steps take turns, so neither of them starves; the tasks are stepped in turns as well. A search or
+// step is never interrupted, so the budget can be exceeded by the duration of the last one.
+func (s *Scheduler) Run(budget time.Duration) SchedulerReport {
+
+	start := time.Now()
+	report := SchedulerReport{}
+
+	for time.Since(start) < budget {
+
+		worked := false
+
+		if s.Queue != nil && s.Queue.ProcessNext() {
+			report.Searches++
+			worked = true
+		}
+
+		if len(s.tasks) > 0 && time.Since(start) < budget {
+			if s.next >= len(s.tasks) {
+				s.next = 0
+			}
+			report.Steps++
+			worked = true
+			if s.tasks[s.next].Step() {
+				s.tasks = append(s.tasks[:s.next], s.tasks[s.next+1:]...)
+				report.Finished++
+			} else {
+				s.next++
+			}
+		}
+
+		if !worked {
+			break
+		}
+	}
+
+	if s.Queue != nil {
+		report.DeferredSearches = s.Queue.Len()
+	}
+	report.DeferredTasks = len(s.tasks)
+	report.Elapsed = time.Since(start)
+
+	return report
+}
@@ -0,0 +1,118 @@
+package paths
+
+import (
+	"math"
+	"sort"
+)
+
+// A Scorer rates a candidate Cell for ScoreCells, e.g. how well it's covered or how close it is to a target. Higher
+// scores are better. Scorers are usually created by the Score... functions and weighted with their weight parameter.
+type Scorer func(cell *Cell) float64
+
+// A CellScore is the total score of a candidate Cell, see ScoreCells.
+type CellScore struct {
+	Cell  *Cell
+	Score float64
+}
+
+// ScoreCells rates the candidates with the sum of the scores of all scorers and returns them ordered by their score,
+// the best one first, e.g. for an AI looking for the best position to stand on. Candidates with the same score keep
+// their order.
+func ScoreCells(candidates []*Cell, scorers ...Scorer) []CellScore {
+
+	scores := make([]CellScore, len(candidates))
+	for i, cell := range candidates {
+		scores[i].Cell = cell
+		for _, scorer := range scorers {
+			scores[i].Score += scorer(cell)
+		}
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+	return scores
+}
+
+// ScoreDistance returns a Scorer preferring cells close to the target: the score is the negative cost of the cheapest
+// path from the Cell to the target times the weight, or -Inf if the target can't be reached. The costs are determined
+// once for all cells with the movement rules of the settings; their start and end are ignored.
+func (m *Grid) ScoreDistance(target *Cell, weight float64, settings PathSettings) Scorer {
+
+	costs := m.costsToGoals([]*Cell{target}, &settings)
+	return func(cell *Cell) float64 {
+		cost, reachable := costs[cell]
+		if !reachable {
+			return math.Inf(-1)
+		}
+		return -cost * weight
+	}
+}
+
+// ScoreCover returns a Scorer preferring cells hidden from the threats: the score is the share of the threats without
+// a line of sight (see Grid.LineOfSight) to the Cell times the weight.
+func (m *Grid) ScoreCover(threats []*Cell, weight float64) Scorer {
+	return func(cell *Cell) float64 {
+
+		if len(threats) == 0 {
+			return weight
+		}
+
+		hidden := 0
+		for _, threat := range threats {
+			if !m.LineOfSight(threat, cell) {
+				hidden++
+			}
+		}
+		return float64(hidden) / float64(len(threats)) * weight
+	}
+}
+
+// ScoreHeightAdvantage returns a Scorer preferring cells above the targets: the score is the average height difference
+// (see Cell.TotalHeight) between the Cell and the targets times the weight.
+func ScoreHeightAdvantage(targets []*Cell, weight float64) Scorer {
+	return func(cell *Cell) float64 {
+
+		if len(targets) == 0 {
+			return 0
+		}
+
+		difference := 0.0
+		for _, target := range targets {
+			difference += cell.TotalHeight() - target.TotalHeight()
+		}
+		return difference / float64(len(targets)) * weight
+	}
+}
+
+// ScoreThreatDistance returns a Scorer preferring cells far away from the threats: the score is the cost of the
+// cheapest path from the Cell to the nearest threat, capped at safeDistance and divided by it, times the weight, so
+// cells the threats can't reach within the safeDistance get the full weight. The distances are determined once like
+// with Grid.GetFleePath.
+func (m *Grid) ScoreThreatDistance(threats []*Cell, safeDistance, weight float64, settings PathSettings) Scorer {
+
+	distances := m.costsToGoals(threats, &settings)
+	return func(cell *Cell) float64 {
+		distance, reachable := distances[cell]
+		if !reachable || distance >= safeDistance || safeDistance <= 0 {
+			return weight
+		}
+		return distance / safeDistance * weight
+	}
+}
+
+// Scorer returns a Scorer avoiding cells seen by the observers of the VisibilityMap: the score is the negative amount of
+// observers seeing the Cell times the weight.
+func (v *VisibilityMap) Scorer(weight float64) Scorer {
+	return func(cell *Cell) float64 {
+		return -float64(v.Watchers(cell)) * weight
+	}
+}
+
+// Scorer returns a Scorer avoiding cells with high values in the DecayLayer: the score is the negative value of the
+// Cell times the weight.
+func (d *DecayLayer) Scorer(weight float64) Scorer {
+	return func(cell *Cell) float64 {
+		return -d.Value(cell) * weight
+	}
+}
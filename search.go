@@ -0,0 +1,138 @@
+package paths
+
+import "time"
+
+// A Search is a search, which is split into steps expanding a limited amount of cells each, so its cost can be spread
+// over multiple frames. It finds the same paths as Grid.GetPathFromSettings, but only as far as Step has been called.
+// IterativeDeepening, FringeSearch and registered algorithms can't be split; they are run completely by the first Step.
+//
+// A Search keeps the memory of the search until it's done or canceled. Changes of the Grid between the steps aren't
+// noticed, so the path may be outdated once it's found (see Grid.Revision). A Search must not be used by multiple
+// goroutines at once.
+type Search struct {
+	grid     *Grid
+	settings PathSettings
+	// buffer and state are the state of the search, which is resumed by each Step. buffer is nil until the first Step
+	// and once the search is done.
+	buffer *searchBuffer
+	state  searchState
+	stats  SearchStats
+	path   *Path
+	found  bool
+	done   bool
+}
+
+// NewSearch returns a Search for a path from the start to the end Cell (or Goal) of the settings. No search is done
+// until Step is called. OnSearchComplete is called once the search is done, with the Duration of all steps.
+func (m *Grid) NewSearch(settings PathSettings) *Search {
+	return &Search{grid: m, settings: settings, path: &Path{}}
+}
+
+// Step expands up to nodes cells (at least one) and returns the resulting Path and true once the search is done. If no
+// path exists, the Path is nil. Until the search is done, nil and false are returned and Step has to be called again.
+// Once it's done, further calls return the same result.
+func (s *Search) Step(nodes int) (*Path, bool) {
+
+	if s.done {
+		return s.Path(), true
+	}
+
+	m, settings := s.grid, &s.settings
+	began := time.Now()
+
+	if s.buffer == nil {
+		if !settings.endpointsWalkable() || !sliceable(settings) {
+			// the search is done right away, and reports itself
+			s.found = m.search(s.path, settings, nil)
+			s.done = true
+			return s.Path(), true
+		}
+		s.buffer = m.searchBuffer()
+		s.state = m.newSearchState(s.buffer, settings, nil)
+		s.path.StepHeight = int(settings.MaxStepHeight)
+	}
+
+	done, found := s.state.expand(s.path, &s.stats, maxInt(1, nodes))
+	s.stats.Duration += time.Since(began)
+	if !done {
+		return nil, false
+	}
+
+	s.found, s.done = found, true
+	s.release()
+	s.stats.Found = found && !s.path.Partial
+	s.stats.Partial = s.path.Partial
+	s.stats.PathLength = len(s.path.Cells)
+	if settings.OnSearchComplete != nil {
+		settings.OnSearchComplete(s.stats)
+	}
+	if m.slowQueries != nil {
+		m.slowQueries.check(m, settings, s.stats)
+	}
+	return s.Path(), true
+}
+
+// Done returns if the search has finished, either with a path or without one.
+func (s *Search) Done() bool {
+	return s.done
+}
+
+// Path returns a copy of the found path once the search is done. If it hasn't finished yet or there is no path, nil
+// is returned.
+func (s *Search) Path() *Path {
+
+	if !s.done || !s.found {
+		return nil
+	}
+	return copyPath(s.path)
+}
+
+// Stats returns the statistics of the steps so far, see SearchStats.
+func (s *Search) Stats() SearchStats {
+	return s.stats
+}
+
+// Cancel stops the search and releases its memory, e.g. once the agent doesn't need the path anymore. The search is
+// done without a path then.
+func (s *Search) Cancel() {
+
+	if s.done {
+		return
+	}
+	s.release()
+	s.done, s.found = true, false
+}
+
+// Task returns a Task running the search with the passed amount of cells per step, so a Scheduler can spread it over
+// the frames. The result can be read from the Search once it's Done.
+func (s *Search) Task(nodes int) Task {
+	return searchTask{s, nodes}
+}
+
+// release puts the memory of the search back into the pool of the Grid.
+func (s *Search) release() {
+
+	if s.buffer != nil {
+		s.grid.searchBuffers.Put(s.buffer)
+		s.buffer = nil
+		s.state = searchState{}
+	}
+}
+
+// sliceable returns if a search with the settings can be split into steps.
+func sliceable(settings *PathSettings) bool {
+
+	custom, _ := settings.Algorithm.custom()
+	return custom == nil && settings.Algorithm != IterativeDeepening && settings.Algorithm != FringeSearch
+}
+
+// searchTask is the Task of a Search, see Search.Task.
+type searchTask struct {
+	search *Search
+	nodes  int
+}
+
+func (t searchTask) Step() bool {
+	_, done := t.search.Step(t.nodes)
+	return done
+}
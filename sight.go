@@ -0,0 +1,101 @@
+package paths
+
+import "math"
+
+// A DistanceMetric defines how the distance between two cells is measured.
+type DistanceMetric int
+
+const (
+	// Euclidean is the straight-line distance.
+	Euclidean DistanceMetric = iota
+	// Chebyshev is the amount of moves needed with diagonal movement, i.e. the bigger one of the X and Y distance.
+	Chebyshev
+	// Manhattan is the amount of moves needed without diagonal movement, i.e. the X plus the Y distance.
+	Manhattan
+)
+
+// Distance returns the distance between the two cells measured with the metric.
+func (metric DistanceMetric) Distance(a, b *Cell) float64 {
+
+	dx, dy := math.Abs(float64(a.X-b.X)), math.Abs(float64(a.Y-b.Y))
+
+	switch metric {
+	case Chebyshev:
+		return math.Max(dx, dy)
+	case Manhattan:
+		return dx + dy
+	}
+	return math.Hypot(dx, dy)
+}
+
+// LineOfSight returns if the straight line between the centers of the two cells isn't blocked. Cells, which aren't
+// walkable, block the sight; the two cells themselves are ignored, so a target standing on an unwalkable Cell can
+// be seen.
+func (m *Grid) LineOfSight(from, to *Cell) bool {
+	return walkLine(from, to, func(x, y, nextX, nextY int) bool {
+		cell := m.Get(x, y)
+		return cell == from || (cell != nil && cell.Walkable)
+	})
+}
+
+// walkLine follows the straight line from one Cell to the other (Bresenham's line algorithm) and calls step for each
+// step from the position (x, y) to the position (nextX, nextY). Returns false as soon as step does.
+func walkLine(from, to *Cell, step func(x, y, nextX, nextY int) bool) bool {
+
+	dx, dy := abs(to.X-from.X), -abs(to.Y-from.Y)
+	stepX, stepY := 1, 1
+	if from.X > to.X {
+		stepX = -1
+	}
+	if from.Y > to.Y {
+		stepY = -1
+	}
+
+	x, y := from.X, from.Y
+	err := dx + dy
+
+	for x != to.X || y != to.Y {
+
+		nextX, nextY := x, y
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			nextX += stepX
+		}
+		if e2 <= dx {
+			err += dx
+			nextY += stepY
+		}
+
+		if !step(x, y, nextX, nextY) {
+			return false
+		}
+		x, y = nextX, nextY
+	}
+
+	return true
+}
+
+// withinRange returns if the Cell is a position to stop at, see PathSettings.StopWithinRange.
+func (m *Grid) withinRange(cell *Cell, settings *PathSettings) bool {
+
+	if settings.StopWithinRange <= 0 || settings.end == nil {
+		return false
+	}
+	if settings.RangeMetric.Distance(cell, settings.end) > settings.StopWithinRange {
+		return false
+	}
+	return !settings.RequireLineOfSight || m.LineOfSight(cell, settings.end)
+}
+
+// isGoal returns if a search may end on the Cell: the end Cell, a Cell accepted by the Goal or one withinRange.
+func (m *Grid) isGoal(cell *Cell, settings *PathSettings) bool {
+	return cell == settings.end || (settings.Goal != nil && settings.Goal(cell)) || m.withinRange(cell, settings)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
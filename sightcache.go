@@ -0,0 +1,82 @@
+package paths
+
+import "sync"
+
+// A SightCache stores the results of Grid.LineOfSight, so repeated checks between the same cells, e.g. of guards
+// watching the same spots every frame, don't need to trace the line again. All results are dropped as soon as the
+// revision of the Grid changes, so changes of the Grid never return outdated results. Changes of the cells, which
+// don't go through the methods of the Grid, have to be followed by Grid.MarkChanged. A SightCache can be used by
+// multiple goroutines.
+type SightCache struct {
+	grid     *Grid
+	lock     sync.Mutex
+	capacity int
+	// revision is the revision of the Grid the results have been determined in.
+	revision uint64
+	// results contains the stored results by the indices of the two cells.
+	results      map[[2]int32]bool
+	hits, misses int
+}
+
+// NewSightCache returns an empty SightCache for the Grid holding up to capacity results. Once the capacity is
+// reached, all results are dropped. Zero or less means no limit.
+func (m *Grid) NewSightCache(capacity int) *SightCache {
+	return &SightCache{grid: m, capacity: capacity, revision: m.Revision(), results: make(map[[2]int32]bool)}
+}
+
+// LineOfSight works like Grid.LineOfSight, but answers the check from the cache if possible.
+func (c *SightCache) LineOfSight(from, to *Cell) bool {
+
+	key := [2]int32{c.grid.cellIndex(from), c.grid.cellIndex(to)}
+	revision := c.grid.Revision()
+
+	c.lock.Lock()
+	if revision != c.revision {
+		c.results = make(map[[2]int32]bool)
+		c.revision = revision
+	}
+	if visible, exists := c.results[key]; exists {
+		c.hits++
+		c.lock.Unlock()
+		return visible
+	}
+	c.misses++
+	c.lock.Unlock()
+
+	visible := c.grid.LineOfSight(from, to)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// another check may have dropped the results of this revision meanwhile
+	if revision == c.revision {
+		if c.capacity > 0 && len(c.results) >= c.capacity {
+			c.results = make(map[[2]int32]bool)
+		}
+		c.results[key] = visible
+	}
+
+	return visible
+}
+
+// Stats returns the amount of checks answered from the cache and the amount of checks, which needed to trace the line.
+func (c *SightCache) Stats() (hits, misses int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.hits, c.misses
+}
+
+// Len returns the amount of stored results.
+func (c *SightCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.results)
+}
+
+// Clear removes all stored results and resets the statistics.
+func (c *SightCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.results = make(map[[2]int32]bool)
+	c.hits, c.misses = 0, 0
+}
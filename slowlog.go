@@ -0,0 +1,85 @@
+package paths
+
+import (
+	"sync"
+	"time"
+)
+
+// A SlowQueryLog records searches exceeding a duration or expansion limit, so performance problems of production
+// servers can be diagnosed. Each entry contains a Replay, which allows to run the search again on a development
+// machine. Attach it to a Grid with Grid.SetSlowQueryLog. A SlowQueryLog can be used by multiple goroutines.
+type SlowQueryLog struct {
+	// MaxDuration and MaxExpanded are the limits; searches exceeding at least one of them are recorded. Zero disables
+	// the limit.
+	MaxDuration time.Duration
+	MaxExpanded int
+	// Capacity is the amount of entries kept; when it's reached, the oldest entry is dropped. Zero means 100.
+	Capacity int
+	// NoReplays disables recording replays. Recording runs the search once more, which may be too expensive.
+	NoReplays bool
+	// OnSlowQuery is called for each recorded entry, e.g. to write it to a log file. It's optional.
+	OnSlowQuery func(query SlowQuery)
+	lock        sync.Mutex
+	entries     []SlowQuery
+}
+
+// A SlowQuery is an entry of a SlowQueryLog.
+type SlowQuery struct {
+	// Time is the time the search has finished.
+	Time time.Time
+	// Stats contains the statistics of the search.
+	Stats SearchStats
+	// Replay allows to run the search again, see Replay.Verify. It's nil with SlowQueryLog.NoReplays.
+	Replay *Replay
+}
+
+// SetSlowQueryLog attaches the log to the Grid, so all searches on it are checked against the limits of the log. nil
+// detaches the current log. Searches recorded with RecordPath aren't checked.
+func (m *Grid) SetSlowQueryLog(log *SlowQueryLog) {
+	m.slowQueries = log
+}
+
+// Entries returns the recorded entries, the oldest one first.
+func (l *SlowQueryLog) Entries() []SlowQuery {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]SlowQuery{}, l.entries...)
+}
+
+// Clear removes all entries.
+func (l *SlowQueryLog) Clear() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.entries = nil
+}
+
+// check records the search, if it exceeded one of the limits.
+func (l *SlowQueryLog) check(grid *Grid, settings *PathSettings, stats SearchStats) {
+
+	slow := (l.MaxDuration > 0 && stats.Duration > l.MaxDuration) || (l.MaxExpanded > 0 && stats.Expanded > l.MaxExpanded)
+	if !slow {
+		return
+	}
+
+	query := SlowQuery{Time: time.Now(), Stats: stats}
+	if !l.NoReplays {
+		replaySettings := *settings
+		replaySettings.OnSearchComplete = nil
+		_, query.Replay = grid.RecordPath(replaySettings)
+	}
+
+	l.lock.Lock()
+	capacity := l.Capacity
+	if capacity <= 0 {
+		capacity = 100
+	}
+	l.entries = append(l.entries, query)
+	if len(l.entries) > capacity {
+		l.entries = l.entries[len(l.entries)-capacity:]
+	}
+	l.lock.Unlock()
+
+	if l.OnSlowQuery != nil {
+		l.OnSlowQuery(query)
+	}
+}
@@ -0,0 +1,100 @@
+package paths
+
+import (
+	"container/heap"
+	"math"
+)
+
+// SoundSettings define how sounds spread over the Grid, see Grid.PropagateSound.
+type SoundSettings struct {
+	// DistanceLoss is the loudness lost per cell the sound travels. Diagonal steps lose the square root of 2 times as
+	// much.
+	DistanceLoss float64
+	// WallLoss is the loudness lost additionally when the sound passes a Cell, which isn't walkable. Negative values
+	// let walls block the sound completely.
+	WallLoss float64
+	// HeightLoss is the loudness lost per unit of height difference (see Cell.TotalHeight) between two neighboring
+	// cells, e.g. for sounds muffled by floors and cliffs.
+	HeightLoss float64
+	// Threshold is the loudness below which a sound can't be heard anymore. The sound doesn't spread any further than
+	// the cells it's audible on.
+	Threshold float64
+}
+
+// NewDefaultSoundSettings returns SoundSettings with these values:
+//   - DistanceLoss: 1
+//   - WallLoss: 5
+//   - HeightLoss: 1
+//   - Threshold: 0
+func NewDefaultSoundSettings() *SoundSettings {
+	return &SoundSettings{DistanceLoss: 1, WallLoss: 5, HeightLoss: 1}
+}
+
+// PropagateSound spreads a sound with the passed volume from the source Cell and returns its loudness on each Cell it
+// can be heard on, e.g. for guards reacting to noises. The sound takes the way with the lowest loss to each Cell, so it
+// travels around walls rather than through them if possible. Cells, on which it's quieter than the Threshold, aren't
+// contained in the returned map.
+func (m *Grid) PropagateSound(source *Cell, volume float64, settings SoundSettings) map[*Cell]float64 {
+
+	loudness := make(map[*Cell]float64)
+	if source == nil || volume < settings.Threshold {
+		return loudness
+	}
+
+	// the search spreads the loss of loudness like the cost of a path
+	losses := map[*Cell]float64{source: 0}
+	openNodes := minHeap{&Node{Cell: source}}
+	closed := make(map[*Cell]bool)
+
+	for len(openNodes) > 0 {
+
+		node := heap.Pop(&openNodes).(*Node)
+		if closed[node.Cell] {
+			continue
+		}
+		closed[node.Cell] = true
+		loudness[node.Cell] = volume - node.Cost
+
+		for _, neighbor := range m.neighbors(node.Cell, true) {
+
+			if closed[neighbor] {
+				continue
+			}
+
+			loss, audible := soundLoss(node.Cell, neighbor, &settings)
+			loss += node.Cost
+			if !audible || volume-loss < settings.Threshold {
+				continue
+			}
+			if previousLoss, reached := losses[neighbor]; reached && previousLoss <= loss {
+				continue
+			}
+
+			losses[neighbor] = loss
+			heap.Push(&openNodes, &Node{Cell: neighbor, Parent: node, Cost: loss})
+		}
+	}
+
+	return loudness
+}
+
+// soundLoss returns the loudness lost by a sound spreading from one Cell to the neighboring Cell "to" and if it can
+// spread there at all.
+func soundLoss(from, to *Cell, settings *SoundSettings) (float64, bool) {
+
+	loss := settings.DistanceLoss
+	if from.X != to.X && from.Y != to.Y {
+		loss *= math.Sqrt2
+	}
+
+	if !to.Walkable {
+		if settings.WallLoss < 0 {
+			return 0, false
+		}
+		loss += settings.WallLoss
+	}
+
+	loss += math.Abs(to.TotalHeight()-from.TotalHeight()) * settings.HeightLoss
+
+	return loss, true
+}
@@ -0,0 +1,89 @@
+package paths
+
+// SetStaging enables or disables the staging mode of the Grid. While it's enabled, the methods of the Grid changing
+// cells (SetWalkable, SetCost, ScaleCosts, AddHeightMap, ...) don't change the cells themselves, but staged copies of
+// them; Commit publishes all the staged changes at once. This way, systems reading the Grid during a simulation tick
+// always see the same state, no matter in which order the systems run. Disabling the staging mode commits pending
+// changes.
+func (m *Grid) SetStaging(enabled bool) {
+
+	if !enabled {
+		m.Commit()
+		m.staged = nil
+		return
+	}
+
+	if m.staged == nil {
+		m.staged = make(map[*Cell]*Cell)
+	}
+}
+
+// Staging returns if the staging mode of the Grid is enabled, see SetStaging.
+func (m *Grid) Staging() bool {
+	return m.staged != nil
+}
+
+// Stage returns the staged copy of the Cell, which can be changed directly. Changes become visible when Commit is
+// called; calling Stage again before returns the same copy. Without staging mode, the Cell itself is returned, so code
+// changing cells works in both modes.
+func (m *Grid) Stage(cell *Cell) *Cell {
+
+	if m.staged == nil {
+		return cell
+	}
+
+	staged, exists := m.staged[cell]
+	if !exists {
+		copied := *cell
+		staged = &copied
+		m.staged[cell] = staged
+	}
+	return staged
+}
+
+// Commit publishes all staged changes by copying the staged cells into the cells of the Grid, and returns the amount
+// of changed cells. The revision of the Grid is increased, if at least one Cell has been staged. Commit waits for
+// running read transactions (see ReadTx) to finish, so they never see half of the changes.
+func (m *Grid) Commit() int {
+
+	if len(m.staged) == 0 {
+		return 0
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for cell, staged := range m.staged {
+		*cell = *staged
+	}
+
+	committed := len(m.staged)
+	m.staged = make(map[*Cell]*Cell)
+	m.MarkChanged()
+
+	return committed
+}
+
+// Discard drops all staged changes.
+func (m *Grid) Discard() {
+	if m.staged != nil {
+		m.staged = make(map[*Cell]*Cell)
+	}
+}
+
+// pending returns the staged copy of the Cell, or the Cell itself, if it hasn't been staged. It's used by the methods
+// changing cells to read their current state.
+func (m *Grid) pending(cell *Cell) *Cell {
+	if staged, exists := m.staged[cell]; exists {
+		return staged
+	}
+	return cell
+}
+
+// markCellsChanged is called by the methods changing cells. In staging mode, the Grid doesn't change until Commit is
+// called.
+func (m *Grid) markCellsChanged() {
+	if m.staged == nil {
+		m.MarkChanged()
+	}
+}
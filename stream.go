@@ -0,0 +1,105 @@
+package paths
+
+// A PathStream returns a Path in segments, see Grid.StreamPath.
+type PathStream struct {
+	grid *Grid
+	// points contains the indices of the cells the search has reached the end through, in their order. The cells
+	// between them (see PathSettings.restoresJumps) are only restored when they are requested.
+	points []int32
+	// restore is set, if the cells between the points have to be restored. point is the index of the last returned
+	// point in points and line the walk from it to the next point.
+	restore bool
+	point   int
+	line    lineWalk
+	// length is the amount of cells of the Path, of which returned have been returned so far.
+	length, returned int
+	segmentLength    int
+	partial          bool
+}
+
+// StreamPath works like GetPathFromSettings, but returns the Path in segments of up to segmentLength cells (at least
+// 1), so the consumer can start moving before all cells of a very long route have been collected. Only the cells the
+// search has reached the end through are stored as compact cell indices; the cells skipped by jumps (see
+// JumpPointSearch) are restored while the segments are requested. Postures aren't streamed. If the start or end isn't
+// walkable, nil is returned; if no path can be found, the stream is empty.
+func (m *Grid) StreamPath(settings PathSettings, segmentLength int) *PathStream {
+
+	if segmentLength < 1 {
+		segmentLength = 1
+	}
+	stream := &PathStream{grid: m, segmentLength: segmentLength, restore: settings.restoresJumps(), point: -1}
+
+	path := &Path{}
+	m.search(path, &settings, &searchObserver{
+		reached: func(buffer *searchBuffer, index int32) {
+			for i := index; i >= 0; i = buffer.parents[i] {
+				stream.points = append(stream.points, i)
+			}
+			// the points have been collected backwards
+			for i, j := 0, len(stream.points)-1; i < j; i, j = i+1, j-1 {
+				stream.points[i], stream.points[j] = stream.points[j], stream.points[i]
+			}
+		},
+	})
+	stream.partial = path.Partial
+
+	stream.length = len(stream.points)
+	if stream.restore {
+		for i := 1; i < len(stream.points); i++ {
+			from, to := m.cellAt(stream.points[i-1]), m.cellAt(stream.points[i])
+			stream.length += maxInt(abs(to.X-from.X), abs(to.Y-from.Y)) - 1
+		}
+	}
+
+	if !settings.endpointsWalkable() {
+		return nil
+	}
+	return stream
+}
+
+// Next returns the next segment of the Path. The segments don't overlap. Once the end has been reached, nil is
+// returned.
+func (s *PathStream) Next() []*Cell {
+
+	if s.returned >= s.length {
+		return nil
+	}
+
+	segment := make([]*Cell, 0, minInt(s.segmentLength, s.length-s.returned))
+	for len(segment) < s.segmentLength && s.returned < s.length {
+		segment = append(segment, s.nextCell())
+		s.returned++
+	}
+
+	return segment
+}
+
+// nextCell returns the Cell following the last returned one.
+func (s *PathStream) nextCell() *Cell {
+
+	if s.line.next() {
+		return s.grid.Get(s.line.x, s.line.y)
+	}
+
+	s.point++
+	cell := s.grid.cellAt(s.points[s.point])
+	if s.restore && s.point+1 < len(s.points) {
+		s.line = newLineWalk(cell, s.grid.cellAt(s.points[s.point+1]))
+	}
+	return cell
+}
+
+// Len returns the total amount of cells of the Path.
+func (s *PathStream) Len() int {
+	return s.length
+}
+
+// Remaining returns the amount of cells, which haven't been returned by Next yet.
+func (s *PathStream) Remaining() int {
+	return s.length - s.returned
+}
+
+// Partial returns if the search has been stopped before reaching the destination, see Path.Partial.
+func (s *PathStream) Partial() bool {
+	return s.partial
+}
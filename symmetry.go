@@ -0,0 +1,121 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotSquare is returned for symmetries, which only exist on square grids.
+var ErrNotSquare = errors.New("grid isn't square")
+
+// A Symmetry is a transformation, under which a map can be symmetric, e.g. to be fair for all players of a competitive
+// multiplayer map.
+type Symmetry int
+
+const (
+	// MirrorX mirrors the Grid at its vertical center line, so the left and the right half are mirror images.
+	MirrorX Symmetry = iota
+	// MirrorY mirrors the Grid at its horizontal center line, so the top and the bottom half are mirror images.
+	MirrorY
+	// Rotational180 rotates the Grid by 180 degrees around its center, e.g. for two player maps.
+	Rotational180
+	// Rotational90 rotates the Grid by 90 degrees around its center, e.g. for four player maps. It only exists on
+	// square grids.
+	Rotational90
+)
+
+// counterpart returns the position the position is transformed to.
+func (s Symmetry) counterpart(m *Grid, x, y int) (int, int) {
+	switch s {
+	case MirrorX:
+		return m.Width() - 1 - x, y
+	case MirrorY:
+		return x, m.Height() - 1 - y
+	case Rotational90:
+		return m.Width() - 1 - y, x
+	}
+	return m.Width() - 1 - x, m.Height() - 1 - y
+}
+
+// orbit returns all cells the Cell is transformed to by applying the Symmetry again and again, starting with the Cell
+// itself.
+func (s Symmetry) orbit(m *Grid, cell *Cell) []*Cell {
+
+	orbit := []*Cell{cell}
+	for x, y := s.counterpart(m, cell.X, cell.Y); x != cell.X || y != cell.Y; x, y = s.counterpart(m, x, y) {
+		orbit = append(orbit, m.Get(x, y))
+	}
+
+	return orbit
+}
+
+// check returns an error, if the Symmetry doesn't exist on the Grid.
+func (s Symmetry) check(m *Grid) error {
+	if s == Rotational90 && m.Width() != m.Height() {
+		return fmt.Errorf("%w: %dx%d", ErrNotSquare, m.Width(), m.Height())
+	}
+	return nil
+}
+
+// AsymmetricCells returns all cells of the Grid, which differ from one of the cells they are transformed to by the
+// Symmetry, in the order of AllCells. Cells are compared by all attributes affecting the pathfinding, i.e. all but
+// their position, Rune and Occupancy. An empty slice means the Grid is symmetric.
+func (m *Grid) AsymmetricCells(symmetry Symmetry) ([]*Cell, error) {
+
+	if err := symmetry.check(m); err != nil {
+		return nil, err
+	}
+
+	cells := []*Cell{}
+	for _, cell := range m.AllCells() {
+		for _, other := range symmetry.orbit(m, cell)[1:] {
+			if !sameTerrain(cell, other) {
+				cells = append(cells, cell)
+				break
+			}
+		}
+	}
+
+	return cells, nil
+}
+
+// Mirror makes the Grid symmetric by copying one part of it onto the others: of all cells transformed into each other,
+// the first one in the order of AllCells is copied to the others. With MirrorX, this is the left half, with MirrorY and
+// Rotational180 the top half and with Rotational90 the triangle at the top edge. All attributes but the position and
+// the Occupancy are copied.
+func (m *Grid) Mirror(symmetry Symmetry) error {
+
+	if err := symmetry.check(m); err != nil {
+		return err
+	}
+
+	for _, cell := range m.AllCells() {
+
+		// the source of each orbit is its first cell in the order of AllCells
+		source := cell
+		for _, other := range symmetry.orbit(m, cell) {
+			if other.Y < source.Y || (other.Y == source.Y && other.X < source.X) {
+				source = other
+			}
+		}
+		if source == cell {
+			continue
+		}
+
+		target := m.Stage(cell)
+		x, y, occupancy := target.X, target.Y, target.Occupancy
+		*target = *m.pending(source)
+		target.X, target.Y, target.Occupancy = x, y, occupancy
+	}
+
+	m.markCellsChanged()
+	return nil
+}
+
+// sameTerrain returns if both cells are equal for the pathfinding.
+func sameTerrain(a, b *Cell) bool {
+	return a.HeightLevel == b.HeightLevel && a.Elevation == b.Elevation && a.Cost == b.Cost &&
+		a.Walkable == b.Walkable && a.Clearance == b.Clearance && a.CostVariance == b.CostVariance &&
+		a.BlockProbability == b.BlockProbability && a.Unknown == b.Unknown && a.EnterCost == b.EnterCost &&
+		a.ExitCost == b.ExitCost
+}
@@ -0,0 +1,180 @@
+package paths
+
+// A TacticalMap contains tactical annotations of the cells of a Grid derived from their heights, e.g. for the AI of
+// tactics games looking for cover or for positions overlooking an area. It's computed once by Grid.AnalyzeTactics;
+// after changes of the Grid, the analysis has to be run again (see Outdated).
+type TacticalMap struct {
+	grid     *Grid
+	revision uint64
+	settings TacticalSettings
+	// info contains the annotations of each cell, indexed like the cells in a search.
+	info []TacticalInfo
+}
+
+// TacticalSettings define what AnalyzeTactics considers as cover and as overlooking.
+type TacticalSettings struct {
+	// CoverHeight is how much higher (see Cell.TotalHeight) than a Cell a neighbor has to be to give cover.
+	CoverHeight float64
+	// OverlookHeight is how much lower than a Cell another one has to be to be overlooked by it.
+	OverlookHeight float64
+	// OverlookRange is the maximum distance (measured like with Euclidean) of the cells overlooked by a Cell.
+	OverlookRange float64
+}
+
+// NewDefaultTacticalSettings returns TacticalSettings with these values:
+//   - CoverHeight: 1
+//   - OverlookHeight: 1
+//   - OverlookRange: 8
+func NewDefaultTacticalSettings() *TacticalSettings {
+	return &TacticalSettings{CoverHeight: 1, OverlookHeight: 1, OverlookRange: 8}
+}
+
+// TacticalInfo contains the tactical annotations of a Cell.
+type TacticalInfo struct {
+	// Cover contains the directions, in which a neighbor of the Cell is high enough to give cover.
+	Cover Direction
+	// Overlooks is the amount of cells overlooked by the Cell: cells within the OverlookRange, which are low enough and
+	// can be seen from the Cell (see Grid.LineOfSight).
+	Overlooks int
+}
+
+// AnalyzeTactics returns the TacticalMap of the Grid with the passed settings. Only walkable cells are annotated, as
+// agents can't stand on the others.
+func (m *Grid) AnalyzeTactics(settings TacticalSettings) *TacticalMap {
+
+	t := &TacticalMap{
+		grid:     m,
+		revision: m.Revision(),
+		settings: settings,
+		info:     make([]TacticalInfo, m.Width()*m.Height()),
+	}
+
+	for _, cell := range m.AllCells() {
+
+		if !cell.Walkable {
+			continue
+		}
+		info := &t.info[m.cellIndex(cell)]
+
+		for direction, offset := range directionOffsets {
+			neighbor := m.Get(cell.X+offset[0], cell.Y+offset[1])
+			if neighbor != nil && neighbor.TotalHeight()-cell.TotalHeight() >= settings.CoverHeight {
+				info.Cover |= direction
+			}
+		}
+
+		info.Overlooks = len(t.overlooked(cell))
+	}
+
+	return t
+}
+
+// overlooked returns the cells overlooked by the Cell.
+func (t *TacticalMap) overlooked(cell *Cell) []*Cell {
+
+	m := t.grid
+	cells := []*Cell{}
+	reach := int(t.settings.OverlookRange)
+	for y := cell.Y - reach; y <= cell.Y+reach; y++ {
+		for x := cell.X - reach; x <= cell.X+reach; x++ {
+			other := m.Get(x, y)
+			if other == nil || other == cell || cell.TotalHeight()-other.TotalHeight() < t.settings.OverlookHeight {
+				continue
+			}
+			if Euclidean.Distance(cell, other) <= t.settings.OverlookRange && m.LineOfSight(cell, other) {
+				cells = append(cells, other)
+			}
+		}
+	}
+
+	return cells
+}
+
+// Outdated returns if the Grid has been changed since the analysis.
+func (t *TacticalMap) Outdated() bool {
+	return t.grid.Revision() != t.revision
+}
+
+// Info returns the tactical annotations of the Cell.
+func (t *TacticalMap) Info(cell *Cell) TacticalInfo {
+	return t.info[t.grid.cellIndex(cell)]
+}
+
+// CoveredFrom returns if the Cell has cover against the threat, i.e. a neighbor giving cover in the direction of the
+// threat. Threats in between two directions are covered by either of them.
+func (t *TacticalMap) CoveredFrom(cell, threat *Cell) bool {
+
+	if cell == threat {
+		return false
+	}
+
+	cover := t.Info(cell).Cover
+	dx, dy := threat.X-cell.X, threat.Y-cell.Y
+	// the directions pointing towards the threat: the diagonal and the straight ones closest to it
+	switch {
+	case abs(dx) > 2*abs(dy):
+		return cover&directionOf(sign(dx), 0) != 0
+	case abs(dy) > 2*abs(dx):
+		return cover&directionOf(0, sign(dy)) != 0
+	}
+	return cover&(directionOf(sign(dx), sign(dy))|directionOf(sign(dx), 0)|directionOf(0, sign(dy))) != 0
+}
+
+// CoverCells returns the walkable cells, which have cover against all of the threats.
+func (t *TacticalMap) CoverCells(threats ...*Cell) []*Cell {
+
+	cells := []*Cell{}
+	for _, cell := range t.grid.AllCells() {
+		if !cell.Walkable || t.Info(cell).Cover == 0 {
+			continue
+		}
+		covered := true
+		for _, threat := range threats {
+			if !t.CoveredFrom(cell, threat) {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			cells = append(cells, cell)
+		}
+	}
+
+	return cells
+}
+
+// OverlookingCells returns the walkable cells overlooking at least one Cell of the area, ordered by the amount of cells
+// of the area they overlook, the most first.
+func (t *TacticalMap) OverlookingCells(area []*Cell) []*Cell {
+
+	inArea := make(map[*Cell]bool, len(area))
+	for _, cell := range area {
+		inArea[cell] = true
+	}
+
+	candidates := []*Cell{}
+	for _, cell := range t.grid.AllCells() {
+		if cell.Walkable && t.Info(cell).Overlooks > 0 {
+			candidates = append(candidates, cell)
+		}
+	}
+
+	// the candidates are scored with the amount of overlooked cells of the area
+	scores := ScoreCells(candidates, func(cell *Cell) float64 {
+		count := 0
+		for _, other := range t.overlooked(cell) {
+			if inArea[other] {
+				count++
+			}
+		}
+		return float64(count)
+	})
+
+	cells := []*Cell{}
+	for _, score := range scores {
+		if score.Score > 0 {
+			cells = append(cells, score.Cell)
+		}
+	}
+	return cells
+}
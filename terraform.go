@@ -0,0 +1,295 @@
+package paths
+
+import "math"
+
+// A TerraformPlan proposes height changes making a path cheaper, see Grid.PlanTerraforming.
+type TerraformPlan struct {
+	grid *Grid
+	// Changes contains the proposed height changes in the order of the Path.
+	Changes []HeightChange
+	// Cost is the total cost of the Changes.
+	Cost float64
+	// Path is the path after the Changes have been applied, PathCost its cost like SearchStats.Cost and PreviousCost
+	// the cost of the cheapest path without any changes, or +Inf if there has been none.
+	Path                   *Path
+	PathCost, PreviousCost float64
+}
+
+// A HeightChange is a change of the height of a Cell proposed by a TerraformPlan.
+type HeightChange struct {
+	Cell *Cell
+	// Height is the new TotalHeight of the Cell, Cost the cost of changing it.
+	Height, Cost float64
+}
+
+// terraformSearches is the amount of searches PlanTerraforming uses to find the cost-benefit trade-off.
+const terraformSearches = 24
+
+// PlanTerraforming proposes which cells to lower or raise to make the path between the start and the end Cell of the
+// settings as cheap as possible with a terraforming budget, e.g. for a colony sim suggesting where to build ramps.
+// Changing the height of a Cell by one unit costs costPerHeight(cell); nil costs 1 per unit for all cells, and cells
+// with a negative or infinite cost can't be changed. Only the heights are changed, so paths still only lead over
+// walkable cells.
+//
+// The planner trades the cost of the path against the terraforming cost: it searches routes which may exceed the
+// MaxStepHeight and MaxDropHeight of the settings, with the excess heights priced increasingly high, and regrades each
+// route into a passable ramp. The cheapest route within the budget is proposed; if terraforming doesn't help, the plan
+// doesn't contain any changes. The plan is a good trade-off, but not necessarily the best one. If there is no path
+// even with terraforming within the budget, or the start or end isn't walkable, nil is returned.
+func (m *Grid) PlanTerraforming(settings PathSettings, budget float64, costPerHeight func(cell *Cell) float64) *TerraformPlan {
+
+	settings.Goal, settings.StopWithinRange = nil, 0
+	if !settings.endpointsWalkable() || settings.end == nil {
+		return nil
+	}
+	if costPerHeight == nil {
+		costPerHeight = func(*Cell) float64 { return 1 }
+	}
+
+	var best *TerraformPlan
+	consider := func(route []*Cell) {
+		if route == nil {
+			return
+		}
+		plan := m.regrade(route, &settings, costPerHeight)
+		if plan == nil || plan.Cost > budget {
+			return
+		}
+		if best == nil || plan.PathCost < best.PathCost || (plan.PathCost == best.PathCost && plan.Cost < best.Cost) {
+			best = plan
+		}
+	}
+
+	// without terraforming, the cheapest path is the reference
+	previous := m.terraformRoute(&settings, costPerHeight, math.Inf(1))
+	consider(previous)
+
+	// the excess heights are priced between free and prohibitive; the price is searched in between (bisection in
+	// logarithmic scale), as higher prices lead to routes needing less terraforming
+	consider(m.terraformRoute(&settings, costPerHeight, 0))
+	if best == nil || len(best.Changes) > 0 {
+		low, high := -10.0, 10.0
+		for i := 0; i < terraformSearches; i++ {
+			price := (low + high) / 2
+			route := m.terraformRoute(&settings, costPerHeight, math.Pow(10, price))
+			if plan := m.regrade(route, &settings, costPerHeight); plan != nil && plan.Cost <= budget {
+				high = price
+			} else {
+				low = price
+			}
+			consider(route)
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	best.PreviousCost = math.Inf(1)
+	if previous != nil {
+		best.PreviousCost = m.routeCost(previous, &settings)
+	}
+	return best
+}
+
+// Apply changes the heights of the cells according to the plan by adjusting their Elevation.
+func (p *TerraformPlan) Apply() {
+
+	m := p.grid
+	for _, change := range p.Changes {
+		cell := m.Stage(change.Cell)
+		cell.Elevation += change.Height - cell.TotalHeight()
+	}
+	m.markCellsChanged()
+}
+
+// terraformRoute returns the cheapest route from the start to the end Cell of the settings, whose moves may exceed the
+// step limits at the price per excess height times the costPerHeight of the Cell moved to. An infinite price keeps the
+// step limits. If there is no route, nil is returned.
+func (m *Grid) terraformRoute(settings *PathSettings, costPerHeight func(cell *Cell) float64, price float64) []*Cell {
+
+	relaxed := *settings
+	if !math.IsInf(price, 1) {
+		relaxed.MaxStepHeight, relaxed.MaxDropHeight = -1, -1
+	}
+
+	size := m.Width() * m.Height()
+	costs := make([]float64, size)
+	parents := make([]int32, size)
+	closed := make([]bool, size)
+	for i := range costs {
+		costs[i] = math.Inf(1)
+	}
+
+	start, end := m.cellIndex(settings.start), m.cellIndex(settings.end)
+	costs[start] = 0
+	parents[start] = -1
+	open := openList{{start, 0}}
+
+	for len(open) > 0 {
+
+		item := open.pop()
+		if closed[item.cell] {
+			continue
+		}
+		closed[item.cell] = true
+
+		if item.cell == end {
+			route := []*Cell{}
+			for index := end; index != -1; index = parents[index] {
+				route = append(route, m.cellAt(index))
+			}
+			reverse(route)
+			return route
+		}
+
+		current := m.cellAt(item.cell)
+		for _, neighbor := range m.neighbors(current, settings.diagonals) {
+
+			index := m.cellIndex(neighbor)
+			if closed[index] || !m.canMove(current, neighbor, &relaxed) {
+				continue
+			}
+
+			cost := costs[item.cell] + m.moveCost(current, neighbor, settings)
+			if excess := excessHeight(current, neighbor, settings); excess > 0 {
+				perHeight := costPerHeight(neighbor)
+				if perHeight < 0 || math.IsInf(perHeight, 1) {
+					continue
+				}
+				cost += price * excess * perHeight
+			}
+			if cost < costs[index] {
+				costs[index] = cost
+				parents[index] = item.cell
+				open.push(openItem{index, cost})
+			}
+		}
+	}
+
+	return nil
+}
+
+// excessHeight returns how much the height difference of a move exceeds the step limits of the settings.
+func excessHeight(from, to *Cell, settings *PathSettings) float64 {
+
+	difference := to.TotalHeight() - from.TotalHeight()
+	if settings.MaxStepHeight >= 0 && difference > settings.MaxStepHeight {
+		return difference - settings.MaxStepHeight
+	}
+	if settings.MaxDropHeight >= 0 && -difference > settings.MaxDropHeight {
+		return -difference - settings.MaxDropHeight
+	}
+	return 0
+}
+
+// regrade returns the plan changing the heights along the route, so all of its moves stay within the step limits of
+// the settings. The heights are adjusted along the route and along the reversed route, i.e. ramps are built after or
+// before the steps, and the cheaper way is taken. If the route can't be regraded, nil is returned.
+func (m *Grid) regrade(route []*Cell, settings *PathSettings, costPerHeight func(cell *Cell) float64) *TerraformPlan {
+
+	if route == nil {
+		return nil
+	}
+
+	var best map[*Cell]float64
+	bestCost := math.Inf(1)
+	for _, backwards := range []bool{false, true} {
+
+		heights := make(map[*Cell]float64, len(route))
+		cost := 0.0
+		for i := range route {
+			cell, previous := route[i], (*Cell)(nil)
+			if backwards {
+				cell = route[len(route)-1-i]
+			}
+			if i > 0 {
+				previous = route[i-1]
+				if backwards {
+					previous = route[len(route)-i]
+				}
+			}
+
+			height := cell.TotalHeight()
+			if previous != nil {
+				// moving backwards, the limits of steps and drops swap
+				up, down := settings.MaxStepHeight, settings.MaxDropHeight
+				if backwards {
+					up, down = down, up
+				}
+				if up >= 0 {
+					height = math.Min(height, heights[previous]+up)
+				}
+				if down >= 0 {
+					height = math.Max(height, heights[previous]-down)
+				}
+			}
+			heights[cell] = height
+
+			if height != cell.TotalHeight() {
+				perHeight := costPerHeight(cell)
+				if perHeight < 0 || math.IsInf(perHeight, 1) {
+					cost = math.Inf(1)
+					break
+				}
+				cost += math.Abs(height-cell.TotalHeight()) * perHeight
+			}
+		}
+
+		if cost < bestCost && m.regradedPassable(route, heights, settings) {
+			best, bestCost = heights, cost
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	plan := &TerraformPlan{grid: m, Changes: []HeightChange{}, Cost: bestCost}
+	for _, cell := range route {
+		if height := best[cell]; height != cell.TotalHeight() {
+			plan.Changes = append(plan.Changes, HeightChange{cell, height, math.Abs(height-cell.TotalHeight()) * costPerHeight(cell)})
+		}
+	}
+	plan.Path = &Path{Cells: append([]*Cell{}, route...), StepHeight: int(settings.MaxStepHeight)}
+	m.finishCells(plan.Path, settings)
+	plan.PathCost = m.routeCost(route, settings)
+	return plan
+}
+
+// regradedPassable returns if the moves along the route keep the step limits of the settings with the passed heights,
+// including the cells diagonal moves pass. Cells without a passed height keep their height.
+func (m *Grid) regradedPassable(route []*Cell, heights map[*Cell]float64, settings *PathSettings) bool {
+
+	height := func(cell *Cell) float64 {
+		if h, changed := heights[cell]; changed {
+			return h
+		}
+		return cell.TotalHeight()
+	}
+
+	for i := 1; i < len(route); i++ {
+		from, to := route[i-1], route[i]
+		difference := height(to) - height(from)
+		if (settings.MaxStepHeight >= 0 && difference > settings.MaxStepHeight) ||
+			(settings.MaxDropHeight >= 0 && -difference > settings.MaxDropHeight) {
+			return false
+		}
+		if from.X != to.X && from.Y != to.Y && settings.MaxStepHeight >= 0 {
+			corner1, corner2 := m.Get(from.X, to.Y), m.Get(to.X, from.Y)
+			if height(corner1)-height(to) > settings.MaxStepHeight && height(corner2)-height(to) > settings.MaxStepHeight {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// routeCost returns the cost of the route like SearchStats.Cost.
+func (m *Grid) routeCost(route []*Cell, settings *PathSettings) float64 {
+
+	cost := settings.quantize(m.cellCost(route[0], settings))
+	for i := 1; i < len(route); i++ {
+		cost += settings.quantize(m.moveCost(route[i-1], route[i], settings))
+	}
+	return settings.unquantize(cost)
+}
@@ -0,0 +1,223 @@
+package paths
+
+import "math"
+
+// RiverSettings define how Grid.CarveRiver shapes a river and what it does to the cells it covers.
+type RiverSettings struct {
+	// Rune is the Rune of the river cells. 0 keeps their Rune.
+	Rune rune
+	// Walkable is the walkability of the river cells, e.g. true for shallow streams.
+	Walkable bool
+	// Cost is the cost of the river cells, e.g. for slow fords if they are Walkable.
+	Cost float64
+	// Depth is subtracted from the Elevation of the river cells.
+	Depth float64
+	// Widening is the Accumulation (see Watershed.Accumulation) per additional cell of width, so rivers widen the more
+	// water they collect. Zero keeps them one cell wide.
+	Widening int
+	// MaxWidth is the maximum width of the river in cells. Zero or less means unlimited.
+	MaxWidth int
+}
+
+// NewDefaultRiverSettings returns RiverSettings with these values:
+//   - Rune: '~'
+//   - Walkable: false
+//   - Cost: 1
+//   - Depth: 0
+//   - Widening: 50
+//   - MaxWidth: 5
+func NewDefaultRiverSettings() *RiverSettings {
+	return &RiverSettings{Rune: '~', Cost: 1, Widening: 50, MaxWidth: 5}
+}
+
+// CarveRiver carves a river into the Grid, which flows downhill from the source along the Watershed of the Grid until it
+// reaches a local minimum, and returns the changed cells. The river widens downstream according to the settings.
+func (m *Grid) CarveRiver(source *Cell, settings RiverSettings) []*Cell {
+
+	if source == nil {
+		return []*Cell{}
+	}
+
+	w := m.AnalyzeWatershed()
+	carved := make(map[*Cell]bool)
+	cells := []*Cell{}
+
+	for _, cell := range w.FlowPath(source) {
+
+		width := 1
+		if settings.Widening > 0 {
+			width += w.Accumulation(cell) / settings.Widening
+		}
+		if settings.MaxWidth > 0 {
+			width = minInt(width, settings.MaxWidth)
+		}
+
+		// the width is spread around the course of the river
+		radius := float64(width-1) / 2
+		reach := int(math.Ceil(radius))
+		for y := cell.Y - reach; y <= cell.Y+reach; y++ {
+			for x := cell.X - reach; x <= cell.X+reach; x++ {
+				covered := m.Get(x, y)
+				if covered == nil || carved[covered] || math.Hypot(float64(x-cell.X), float64(y-cell.Y)) > radius {
+					continue
+				}
+				carved[covered] = true
+				cells = append(cells, covered)
+			}
+		}
+	}
+
+	for _, cell := range cells {
+		staged := m.Stage(cell)
+		staged.Walkable = settings.Walkable
+		staged.Cost = settings.Cost
+		staged.Elevation -= settings.Depth
+		if settings.Rune != 0 {
+			staged.Rune = settings.Rune
+		}
+	}
+	m.markCellsChanged()
+
+	return cells
+}
+
+// RoadSettings define where Grid.BuildRoads lays roads and what it does to the cells they cover.
+type RoadSettings struct {
+	// Rune is the Rune of the road cells. 0 keeps their Rune.
+	Rune rune
+	// Cost is the cost of the road cells, usually lower than the cost of the terrain, so paths prefer the roads.
+	Cost float64
+	// SlopeCost is added to the cost of each move for each unit of height difference (see Cell.TotalHeight), so roads
+	// follow the terrain instead of climbing straight over hills.
+	SlopeCost float64
+	// MaxSlope is the maximum height difference between two neighboring road cells. A negative value allows any slope.
+	MaxSlope float64
+}
+
+// NewDefaultRoadSettings returns RoadSettings with these values:
+//   - Rune: '='
+//   - Cost: 0.5
+//   - SlopeCost: 5
+//   - MaxSlope: 1
+func NewDefaultRoadSettings() *RoadSettings {
+	return &RoadSettings{Rune: '=', Cost: 0.5, SlopeCost: 5, MaxSlope: 1}
+}
+
+// BuildRoads connects the towns with a network of roads and returns the changed cells. Each town is connected to the
+// network of the towns connected before with the cheapest road, where the cost of each move is its cost according to
+// the movement rules of the settings plus the slope cost of the roadSettings; existing roads of the network are free.
+// Towns, which can't be reached, stay unconnected. The start and end of the settings are ignored.
+func (m *Grid) BuildRoads(towns []*Cell, settings PathSettings, roadSettings RoadSettings) []*Cell {
+
+	size := m.Width() * m.Height()
+	network := make([]bool, size)
+	unconnected := make(map[int32]bool)
+	for _, town := range towns {
+		if town != nil && town.Walkable {
+			unconnected[m.cellIndex(town)] = true
+		}
+	}
+
+	cells := []*Cell{}
+	for first := true; len(unconnected) > 0; first = false {
+
+		if first {
+			// the network starts with the first town
+			for _, town := range towns {
+				if index := m.cellIndex(town); unconnected[index] {
+					network[index] = true
+					delete(unconnected, index)
+					cells = append(cells, town)
+					break
+				}
+			}
+			continue
+		}
+
+		road := m.roadToNetwork(network, unconnected, &settings, &roadSettings)
+		if road == nil {
+			break
+		}
+		for _, cell := range road {
+			index := m.cellIndex(cell)
+			if !network[index] {
+				network[index] = true
+				cells = append(cells, cell)
+			}
+			delete(unconnected, index)
+		}
+	}
+
+	for _, cell := range cells {
+		staged := m.Stage(cell)
+		staged.Cost = roadSettings.Cost
+		if roadSettings.Rune != 0 {
+			staged.Rune = roadSettings.Rune
+		}
+	}
+	m.markCellsChanged()
+
+	return cells
+}
+
+// roadToNetwork returns the cheapest road from the network to the nearest of the unconnected towns, or nil if none of
+// them can be reached.
+func (m *Grid) roadToNetwork(network []bool, unconnected map[int32]bool, settings *PathSettings, roadSettings *RoadSettings) []*Cell {
+
+	costs := make([]float64, len(network))
+	parents := make([]int32, len(network))
+	closed := make([]bool, len(network))
+	open := openList{}
+	for i := range costs {
+		costs[i] = math.Inf(1)
+		if network[i] {
+			costs[i] = 0
+			parents[i] = -1
+			open.push(openItem{int32(i), 0})
+		}
+	}
+
+	for len(open) > 0 {
+
+		item := open.pop()
+		if closed[item.cell] {
+			continue
+		}
+		closed[item.cell] = true
+
+		if unconnected[item.cell] {
+			road := []*Cell{}
+			for index := item.cell; index != -1; index = parents[index] {
+				road = append(road, m.cellAt(index))
+			}
+			return road
+		}
+
+		current := m.cellAt(item.cell)
+		for _, neighbor := range m.neighbors(current, settings.diagonals) {
+
+			index := m.cellIndex(neighbor)
+			if closed[index] || !m.canMove(current, neighbor, settings) {
+				continue
+			}
+
+			slope := math.Abs(neighbor.TotalHeight() - current.TotalHeight())
+			if roadSettings.MaxSlope >= 0 && slope > roadSettings.MaxSlope {
+				continue
+			}
+
+			// existing roads are free to use
+			cost := costs[item.cell]
+			if !network[index] {
+				cost += settings.quantize(m.moveCost(current, neighbor, settings) + slope*roadSettings.SlopeCost)
+			}
+			if cost < costs[index] {
+				costs[index] = cost
+				parents[index] = item.cell
+				open.push(openItem{index, cost})
+			}
+		}
+	}
+
+	return nil
+}
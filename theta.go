@@ -0,0 +1,66 @@
+package paths
+
+import "math"
+
+// straightenParent returns the parent of the neighbor of the cell with the index and the cost of reaching the neighbor
+// for ThetaStar: if the parent of the cell can reach the neighbor in a straight line at most as expensive as the way
+// over the cell, the line replaces the way; otherwise, the cell stays the parent with the passed cost.
+func (m *Grid) straightenParent(index int32, neighbor *Cell, cost float64, settings *PathSettings, buffer *searchBuffer) (int32, float64) {
+
+	parent := buffer.parents[index]
+	if parent < 0 {
+		return index, cost
+	}
+
+	lineCost, straight := m.lineCost(m.cellAt(parent), neighbor, settings)
+	if !straight || buffer.costs[parent]+lineCost > cost {
+		return index, cost
+	}
+	return parent, buffer.costs[parent] + lineCost
+}
+
+// lineCost returns the cost of moving from one Cell to the other in a straight line and if it's possible at all. Each
+// step along the line has to be a possible move; the costs of the moves are scaled down by the ratio of the length of
+// the line to the length of the steps, as the line is shorter than the steps going back and forth around it.
+func (m *Grid) lineCost(from, to *Cell, settings *PathSettings) (float64, bool) {
+
+	cost, length := 0.0, 0.0
+	possible := walkLine(from, to, func(x, y, nextX, nextY int) bool {
+
+		cell, next := m.Get(x, y), m.Get(nextX, nextY)
+		if next == nil {
+			return false
+		}
+
+		// without diagonal movement, the line passes a corner by moving around one of the cells next to it
+		if x != nextX && y != nextY && !settings.diagonals {
+			for _, corner := range []*Cell{m.Get(nextX, y), m.Get(x, nextY)} {
+				if m.canMove(cell, corner, settings) && m.canMove(corner, next, settings) {
+					cost += m.moveCost(cell, corner, settings) + m.moveCost(corner, next, settings)
+					length += 2
+					return true
+				}
+			}
+			return false
+		}
+
+		if !m.canMove(cell, next, settings) {
+			return false
+		}
+		cost += m.moveCost(cell, next, settings)
+		if x != nextX && y != nextY {
+			length += math.Sqrt2
+		} else {
+			length++
+		}
+		return true
+	})
+	if !possible {
+		return 0, false
+	}
+	if length == 0 {
+		return 0, true
+	}
+
+	return settings.quantize(cost * math.Hypot(float64(to.X-from.X), float64(to.Y-from.Y)) / length), true
+}
@@ -0,0 +1,116 @@
+package paths
+
+import "math"
+
+// A Tour is a route visiting multiple stops, see Grid.PlanTour.
+type Tour struct {
+	// Stops contains the reachable stops in the order they are visited.
+	Stops []*Cell
+	// Unreachable contains the stops, which can't be reached from the start or from the stops visited before them.
+	Unreachable []*Cell
+	// Path is the whole route from the start through all the reachable stops.
+	Path *Path
+	// Cost is the cost of the Path, without the cost of the start Cell.
+	Cost float64
+}
+
+// PlanTour finds a short route from the start Cell visiting all the stops, e.g. for patrols, delivery quests or
+// harvesters. The order of the stops is found with the nearest neighbor heuristic and improved with 2-opt, both using
+// the real path costs between the stops. The route doesn't return to the start. The movement rules are taken from the
+// settings; their start and end are ignored.
+func (m *Grid) PlanTour(start *Cell, stops []*Cell, settings PathSettings) *Tour {
+
+	tour := &Tour{Stops: []*Cell{}, Unreachable: []*Cell{}}
+
+	// the start has the index 0 in the matrix, the stops are shifted by one
+	points := append([]*Cell{start}, stops...)
+	matrix := m.DistanceMatrix(points, settings)
+	index := make(map[*Cell]int, len(points))
+	for i := len(points) - 1; i >= 0; i-- {
+		index[points[i]] = i
+	}
+	cost := func(from, to *Cell) float64 {
+		return matrix[index[from]][index[to]]
+	}
+
+	// nearest neighbor: always go to the cheapest stop not visited yet
+	remaining := []*Cell{}
+	for _, stop := range stops {
+		if !math.IsInf(cost(start, stop), 1) && start.Walkable {
+			remaining = append(remaining, stop)
+		} else {
+			tour.Unreachable = append(tour.Unreachable, stop)
+		}
+	}
+
+	route := []*Cell{start}
+	for len(remaining) > 0 {
+
+		current := route[len(route)-1]
+		nearest := -1
+		for i, stop := range remaining {
+			if !math.IsInf(cost(current, stop), 1) && (nearest < 0 || cost(current, stop) < cost(current, remaining[nearest])) {
+				nearest = i
+			}
+		}
+		// stops, which can't be reached from the last stop, aren't reachable from the route (e.g. behind drops)
+		if nearest < 0 {
+			tour.Unreachable = append(tour.Unreachable, remaining...)
+			break
+		}
+
+		route = append(route, remaining[nearest])
+		remaining = append(remaining[:nearest], remaining[nearest+1:]...)
+	}
+
+	routeCost := func(route []*Cell) float64 {
+		total := 0.0
+		for i := 1; i < len(route); i++ {
+			total += cost(route[i-1], route[i])
+		}
+		return total
+	}
+
+	// 2-opt: reverse parts of the route as long as this makes it cheaper. As costs may differ per direction (e.g. on
+	// slopes), the whole route is compared.
+	best := routeCost(route)
+	for improved := true; improved; {
+		improved = false
+		for i := 1; i < len(route)-1; i++ {
+			for k := i + 1; k < len(route); k++ {
+				reverse(route[i : k+1])
+				if candidate := routeCost(route); candidate < best {
+					best, improved = candidate, true
+				} else {
+					reverse(route[i : k+1])
+				}
+			}
+		}
+	}
+
+	tour.Stops = append(tour.Stops, route[1:]...)
+	tour.Cost = best
+	tour.Path = &Path{Cells: []*Cell{start}, StepHeight: int(settings.MaxStepHeight)}
+
+	// the paths between the stops are concatenated
+	for i := 1; i < len(route); i++ {
+		segmentSettings := settings
+		segmentSettings.start, segmentSettings.end = route[i-1], route[i]
+		segmentSettings.Goal = nil
+		segmentSettings.RequireOptimal = true
+		segment := m.findPath(&segmentSettings, nil)
+		if len(segment.Cells) == 0 {
+			continue
+		}
+		tour.Path.Cells = append(tour.Path.Cells, segment.Cells[1:]...)
+	}
+
+	return tour
+}
+
+// reverse reverses the order of the cells.
+func reverse(cells []*Cell) {
+	for i, j := 0, len(cells)-1; i < j; i, j = i+1, j-1 {
+		cells[i], cells[j] = cells[j], cells[i]
+	}
+}
@@ -0,0 +1,113 @@
+package paths
+
+// A TrailMap counts how often agents walk over each Cell of a Grid and turns popular routes into trails, which are
+// cheaper to walk on. As agents prefer the trails, they get even more popular; this way roads emerge organically
+// where agents actually walk. With Decay, unused trails fade away again.
+type TrailMap struct {
+	grid *Grid
+	// counts contains the (decayed) amount of traversals of each cell.
+	counts map[*Cell]float64
+	// originalCosts contains the costs the baked cells had before they became trails.
+	originalCosts map[*Cell]float64
+}
+
+// NewTrailMap returns an empty TrailMap for the Grid.
+func NewTrailMap(grid *Grid) *TrailMap {
+	return &TrailMap{
+		grid:          grid,
+		counts:        make(map[*Cell]float64),
+		originalCosts: make(map[*Cell]float64),
+	}
+}
+
+// Record counts one traversal of each Cell of the Path, e.g. after an agent has walked it.
+func (t *TrailMap) Record(path *Path) {
+
+	if path == nil {
+		return
+	}
+
+	for _, cell := range path.Cells {
+		t.counts[cell]++
+	}
+}
+
+// Traversals returns how often the Cell has been walked over, reduced by Decay.
+func (t *TrailMap) Traversals(cell *Cell) float64 {
+	return t.counts[cell]
+}
+
+// Decay multiplies all traversal counts with the factor, which should be between 0 and 1. Calling it regularly, e.g.
+// once per in-game day, makes old traversals count less than recent ones. Counts below 0.01 are removed.
+func (t *TrailMap) Decay(factor float64) {
+
+	for cell, count := range t.counts {
+		count *= factor
+		if count < .01 {
+			delete(t.counts, cell)
+			continue
+		}
+		t.counts[cell] = count
+	}
+}
+
+// Bake turns all walkable cells, which have been walked over at least threshold times, into trails by setting their
+// cost to trailCost. Cells which are already cheaper keep their cost. Trails whose traversals dropped below the
+// threshold (see Decay) get their original cost back. The amount of changed cells is returned.
+func (t *TrailMap) Bake(threshold, trailCost float64) int {
+
+	changed := 0
+
+	for cell, original := range t.originalCosts {
+		if t.counts[cell] < threshold {
+			cell.Cost = original
+			delete(t.originalCosts, cell)
+			changed++
+		}
+	}
+
+	for cell, count := range t.counts {
+		if count < threshold || !cell.Walkable || cell.Cost <= trailCost {
+			continue
+		}
+		if _, baked := t.originalCosts[cell]; !baked {
+			t.originalCosts[cell] = cell.Cost
+		}
+		cell.Cost = trailCost
+		changed++
+	}
+
+	if changed > 0 {
+		t.grid.MarkChanged()
+	}
+
+	return changed
+}
+
+// Trails returns all cells, which are currently baked into trails.
+func (t *TrailMap) Trails() []*Cell {
+
+	cells := []*Cell{}
+	for _, cell := range t.grid.AllCells() {
+		if _, baked := t.originalCosts[cell]; baked {
+			cells = append(cells, cell)
+		}
+	}
+
+	return cells
+}
+
+// Reset restores the original costs of all trails and forgets all traversals.
+func (t *TrailMap) Reset() {
+
+	for cell, original := range t.originalCosts {
+		cell.Cost = original
+	}
+
+	if len(t.originalCosts) > 0 {
+		t.grid.MarkChanged()
+	}
+
+	t.counts = make(map[*Cell]float64)
+	t.originalCosts = make(map[*Cell]float64)
+}
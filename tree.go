@@ -0,0 +1,139 @@
+package paths
+
+import "strings"
+
+// A PathTree contains the cheapest paths from every cell of a Grid to the nearest of one or more goals. Each reachable
+// cell points to the next cell on its path, so the tree shows the global routing structure towards the goals, e.g.
+// to check why agents from one region take a detour. Create one with Grid.ShortestPathTree.
+type PathTree struct {
+	grid  *Grid
+	goals []*Cell
+	costs map[*Cell]float64
+	next  map[*Cell]*Cell
+}
+
+// ShortestPathTree computes the PathTree towards the passed goals. The movement rules are taken from the settings;
+// the start and end of the settings are ignored.
+func (m *Grid) ShortestPathTree(goals []*Cell, settings PathSettings) *PathTree {
+
+	walkableGoals := []*Cell{}
+	for _, goal := range goals {
+		if goal != nil && goal.Walkable {
+			walkableGoals = append(walkableGoals, goal)
+		}
+	}
+
+	costs, next := m.searchToGoals(walkableGoals, &settings)
+	return &PathTree{grid: m, goals: walkableGoals, costs: costs, next: next}
+}
+
+// Goals returns the goals of the PathTree. Goals, which aren't walkable, aren't contained.
+func (t *PathTree) Goals() []*Cell {
+	return t.goals
+}
+
+// Reachable returns if a goal can be reached from the Cell.
+func (t *PathTree) Reachable(cell *Cell) bool {
+	_, reachable := t.costs[cell]
+	return reachable
+}
+
+// Cost returns the cost of the cheapest path from the Cell to the nearest goal. The cost of the Cell itself isn't
+// included. If no goal can be reached, false is returned.
+func (t *PathTree) Cost(cell *Cell) (float64, bool) {
+	cost, reachable := t.costs[cell]
+	return cost, reachable
+}
+
+// Next returns the next Cell on the cheapest path from the Cell to the nearest goal. For goals and cells, from which
+// no goal can be reached, nil is returned.
+func (t *PathTree) Next(cell *Cell) *Cell {
+	return t.next[cell]
+}
+
+// Children returns all cells, whose next Cell is the passed one.
+func (t *PathTree) Children(cell *Cell) []*Cell {
+
+	children := []*Cell{}
+	for _, other := range t.grid.AllCells() {
+		if t.next[other] == cell {
+			children = append(children, other)
+		}
+	}
+
+	return children
+}
+
+// PathFrom returns the cheapest Path from the Cell to the nearest goal, or nil if no goal can be reached.
+func (t *PathTree) PathFrom(cell *Cell) *Path {
+
+	if !t.Reachable(cell) {
+		return nil
+	}
+
+	path := &Path{}
+	for c := cell; c != nil; c = t.next[c] {
+		path.Cells = append(path.Cells, c)
+	}
+
+	return path
+}
+
+// Directions exports the tree as the Direction each cell points to, indexed by [y][x]. Goals and cells, from which
+// no goal can be reached, have the Direction 0.
+func (t *PathTree) Directions() [][]Direction {
+
+	directions := make([][]Direction, t.grid.Height())
+	for y := range directions {
+		directions[y] = make([]Direction, t.grid.Width())
+		for x := range directions[y] {
+			cell := t.grid.Get(x, y)
+			if next := t.next[cell]; next != nil {
+				directions[y][x] = DirectionBetween(cell, next)
+			}
+		}
+	}
+
+	return directions
+}
+
+// treeArrows contains the character used by PathTree.Visualise for each Direction.
+var treeArrows = map[Direction]rune{
+	North:     '↑',
+	East:      '→',
+	South:     '↓',
+	West:      '←',
+	NorthEast: '↗',
+	SouthEast: '↘',
+	SouthWest: '↙',
+	NorthWest: '↖',
+}
+
+// Visualise returns a string visualisation of the tree. Each reachable cell is represented by an arrow pointing to its
+// next cell, goals by an 'o'. Cells, from which no goal can be reached, are represented by a blank space.
+func (t *PathTree) Visualise() []string {
+
+	isGoal := make(map[*Cell]bool)
+	for _, goal := range t.goals {
+		isGoal[goal] = true
+	}
+
+	visualisation := []string{}
+	for y, row := range t.Directions() {
+		var currentString = strings.Builder{}
+		for x, direction := range row {
+			cell := t.grid.Get(x, y)
+			switch {
+			case isGoal[cell]:
+				currentString.WriteRune('o')
+			case direction != 0:
+				currentString.WriteRune(treeArrows[direction])
+			default:
+				currentString.WriteRune(' ')
+			}
+		}
+		visualisation = append(visualisation, currentString.String())
+	}
+
+	return visualisation
+}
@@ -0,0 +1,133 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ErrInvalidSettings is returned by PathSettings.Validate if the settings don't fit together or to the Grid.
+var ErrInvalidSettings = errors.New("invalid path settings")
+
+// Validate checks if the settings fit together and to the Grid, so a search with them can behave as expected. Problems
+// like a start outside the Grid, unknown cost layers or an agent, which doesn't fit on any Cell, would otherwise
+// silently lead to no path or a different one. If there are problems, an error wrapping ErrInvalidSettings is returned,
+// which describes all of them. Negative step and drop heights are valid, as they disable the limits.
+func (settings *PathSettings) Validate(grid *Grid) error {
+
+	problems := []string{}
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	invalid := func(value float64) bool {
+		return math.IsNaN(value) || math.IsInf(value, 0)
+	}
+
+	// the endpoints
+	onGrid := func(cell *Cell) bool {
+		return grid.Get(cell.X, cell.Y) == cell
+	}
+	switch {
+	case settings.start == nil:
+		problem("there is no start Cell")
+	case !onGrid(settings.start):
+		problem("the start Cell at X:%d Y:%d isn't a Cell of the Grid", settings.start.X, settings.start.Y)
+	}
+	switch {
+	case settings.end == nil && settings.Goal == nil && settings.goals == nil:
+		problem("there is neither an end Cell nor a Goal")
+	case settings.end != nil && !onGrid(settings.end):
+		problem("the end Cell at X:%d Y:%d isn't a Cell of the Grid", settings.end.X, settings.end.Y)
+	}
+	if settings.StopWithinRange > 0 && settings.end == nil {
+		problem("StopWithinRange needs an end Cell")
+	}
+
+	// the numbers
+	if math.IsNaN(settings.MaxStepHeight) || math.IsNaN(settings.MaxDropHeight) {
+		problem("MaxStepHeight and MaxDropHeight must be numbers, negative values disable the limits")
+	}
+	if settings.StopWithinRange < 0 || invalid(settings.StopWithinRange) {
+		problem("StopWithinRange is %v, but must be zero or positive", settings.StopWithinRange)
+	}
+	if settings.MaxOccupancy < 0 {
+		problem("MaxOccupancy is %d, but must be zero (unlimited) or positive", settings.MaxOccupancy)
+	}
+	if settings.MemoryLimit < 0 {
+		problem("MemoryLimit is %d, but must be zero (unlimited) or positive", settings.MemoryLimit)
+	}
+	if settings.AgentHeight < 0 || invalid(settings.AgentHeight) {
+		problem("AgentHeight is %v, but must be zero (ignored) or positive", settings.AgentHeight)
+	}
+	if settings.RiskAversion < 0 || invalid(settings.RiskAversion) {
+		problem("RiskAversion is %v, but must be zero or positive", settings.RiskAversion)
+	}
+	if settings.MinTraversalProbability < 0 || settings.MinTraversalProbability > 1 ||
+		math.IsNaN(settings.MinTraversalProbability) {
+		problem("MinTraversalProbability is %v, but must be between 0 and 1", settings.MinTraversalProbability)
+	}
+	if settings.HeuristicWeight < 0 || (settings.HeuristicWeight > 0 && settings.HeuristicWeight < 1) ||
+		invalid(settings.HeuristicWeight) {
+		problem("HeuristicWeight is %v, but must be zero (no inflation) or at least 1", settings.HeuristicWeight)
+	}
+	for _, posture := range settings.Postures {
+		if posture.Height <= 0 || invalid(posture.Height) || posture.CostMultiplier < 0 || invalid(posture.CostMultiplier) {
+			problem("posture %q needs a positive Height and a CostMultiplier of zero or more", posture.Name)
+		}
+	}
+
+	// the combinations
+	if settings.Directions != 0 && !settings.diagonals && settings.Directions&CardinalDirections == 0 {
+		problem("Directions only contains diagonal directions, but diagonal movement is disabled")
+	}
+	if settings.Water != nil && !settings.Water.Land && !settings.Water.Water {
+		problem("the WaterProfile allows neither land nor water")
+	}
+
+	// the Grid
+	if _, exists := AlgorithmByName(settings.Algorithm.String()); !exists {
+		problem("the Algorithm %v is neither built-in nor registered", settings.Algorithm)
+	}
+	if settings.Heuristic < DefaultHeuristic || settings.Heuristic > OctileHeuristic {
+		problem("the Heuristic %v is unknown", settings.Heuristic)
+	}
+	for _, name := range settings.CostLayers {
+		if _, exists := grid.costLayers[name]; !exists {
+			problem("the Grid has no cost layer %q", name)
+		}
+	}
+	if settings.RuneLayer != "" {
+		if _, exists := grid.runeLayers[settings.RuneLayer]; !exists {
+			problem("the Grid has no rune layer %q", settings.RuneLayer)
+		}
+	}
+	for _, name := range settings.BlockedCategories {
+		if _, exists := grid.categories[name]; !exists {
+			problem("the Grid has no category %q", name)
+		}
+	}
+	if settings.GoalBounds != nil && settings.GoalBounds.grid != grid {
+		problem("the GoalBounds have been computed for another Grid")
+	}
+	if settings.Landmarks != nil && settings.Landmarks.grid != grid {
+		problem("the Landmarks have been computed for another Grid")
+	}
+	if settings.AgentHeight > 0 {
+		fits := false
+		for _, cell := range grid.AllCells() {
+			if cell.Walkable && fitsClearance(cell.Clearance, settings) {
+				fits = true
+				break
+			}
+		}
+		if !fits {
+			problem("an agent with an AgentHeight of %v doesn't fit on any walkable Cell of the Grid", settings.AgentHeight)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidSettings, strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrGridChanged is returned by ReadTx, if the Grid has been changed during the transaction.
+var ErrGridChanged = errors.New("grid changed during read transaction")
+
+// A GridView gives read access to a Grid during a read transaction, see Grid.ReadTx.
+type GridView struct {
+	grid     *Grid
+	revision uint64
+}
+
+// Get returns the Cell at the position, see Grid.Get. The Cell must not be changed.
+func (v GridView) Get(x, y int) *Cell {
+	return v.grid.Get(x, y)
+}
+
+// Width returns the width of the Grid.
+func (v GridView) Width() int {
+	return v.grid.Width()
+}
+
+// Height returns the height of the Grid.
+func (v GridView) Height() int {
+	return v.grid.Height()
+}
+
+// Revision returns the revision of the Grid at the start of the transaction.
+func (v GridView) Revision() uint64 {
+	return v.revision
+}
+
+// GetPath searches a path on the Grid, see Grid.GetPathFromSettings.
+func (v GridView) GetPath(settings PathSettings) *Path {
+	return v.grid.GetPathFromSettings(settings)
+}
+
+// ReadTx runs the function with a view of the Grid, e.g. to run a search on a server while other goroutines change the
+// Grid. During the transaction, the Grid is locked for reading, so Commit waits for it to finish. Changes, which don't
+// go through Commit, can't be prevented; but if the revision of the Grid changes during the transaction, an error
+// wrapping ErrGridChanged is returned, so the results can be dropped and the transaction retried. Calling Commit
+// inside of the function blocks forever.
+func (m *Grid) ReadTx(read func(view GridView)) error {
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	revision := m.Revision()
+	read(GridView{grid: m, revision: revision})
+
+	if current := m.Revision(); current != revision {
+		return fmt.Errorf("%w: revision %d changed to %d", ErrGridChanged, revision, current)
+	}
+	return nil
+}
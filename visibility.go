@@ -0,0 +1,166 @@
+package paths
+
+import "math"
+
+// A VisibilityMap keeps track of the cells seen by a set of observers, e.g. guards or cameras, as the union of their
+// fields of view. A Cell is seen by an Observer, if it's within its range (measured like with Euclidean) and there's a
+// line of sight (see Grid.LineOfSight) between them. The penalty of the map is added to the additive modifiers (see
+// CostLayer.SetAdditive) of all seen cells, so searches using its CostLayer sneak past the observers where possible.
+// A Cell seen by several observers gets the penalty only once: the map counts the observers watching each Cell and
+// removes the penalty when the last of them looks away. Moving an Observer only updates the cells, whose visibility has
+// changed, so many observers can move every frame. Create it with CostLayer.NewVisibilityMap.
+type VisibilityMap struct {
+	layer   *CostLayer
+	penalty float64
+	// observers contains all observers of the map in the order they have been added.
+	observers []*Observer
+	// watchers contains the amount of observers seeing each seen Cell.
+	watchers map[*Cell]int
+}
+
+// An Observer is a position watched by a VisibilityMap, see VisibilityMap.AddObserver.
+type Observer struct {
+	visibility *VisibilityMap
+	position   *Cell
+	viewRange  float64
+	// seen contains the cells within the field of view.
+	seen []*Cell
+}
+
+// NewVisibilityMap returns a VisibilityMap without observers, which adds the penalty to the cells of the layer seen by
+// its observers.
+func (l *CostLayer) NewVisibilityMap(penalty float64) *VisibilityMap {
+	return &VisibilityMap{layer: l, penalty: penalty, watchers: make(map[*Cell]int)}
+}
+
+// AddObserver adds an Observer at the position seeing the cells within the view range.
+func (v *VisibilityMap) AddObserver(position *Cell, viewRange float64) *Observer {
+
+	observer := &Observer{visibility: v, viewRange: viewRange}
+	v.observers = append(v.observers, observer)
+	observer.update(position, viewRange)
+	return observer
+}
+
+// Observers returns all observers of the VisibilityMap.
+func (v *VisibilityMap) Observers() []*Observer {
+	return append([]*Observer(nil), v.observers...)
+}
+
+// Visible returns if the Cell is seen by at least one Observer.
+func (v *VisibilityMap) Visible(cell *Cell) bool {
+	return v.watchers[cell] > 0
+}
+
+// Watchers returns the amount of observers seeing the Cell.
+func (v *VisibilityMap) Watchers(cell *Cell) int {
+	return v.watchers[cell]
+}
+
+// Cells returns all cells seen by at least one Observer.
+func (v *VisibilityMap) Cells() []*Cell {
+
+	cells := make([]*Cell, 0, len(v.watchers))
+	for cell := range v.watchers {
+		cells = append(cells, cell)
+	}
+	return cells
+}
+
+// SetPenalty changes the penalty added to the seen cells.
+func (v *VisibilityMap) SetPenalty(penalty float64) {
+
+	if penalty == v.penalty {
+		return
+	}
+
+	for cell := range v.watchers {
+		v.layer.addPenalty(cell, penalty-v.penalty)
+	}
+	v.penalty = penalty
+	if len(v.watchers) > 0 {
+		v.layer.grid.MarkChanged()
+	}
+}
+
+// Refresh determines the fields of view of all observers again. Moving observers updates their fields of view
+// automatically, but changes of the Grid blocking or clearing the sight (e.g. opened doors) need a Refresh.
+func (v *VisibilityMap) Refresh() {
+	for _, observer := range v.observers {
+		observer.update(observer.position, observer.viewRange)
+	}
+}
+
+// Position returns the Cell the Observer is standing on, or nil if it has been removed.
+func (o *Observer) Position() *Cell {
+	return o.position
+}
+
+// MoveTo moves the Observer to the position.
+func (o *Observer) MoveTo(position *Cell) {
+	if position != o.position {
+		o.update(position, o.viewRange)
+	}
+}
+
+// SetRange changes the view range of the Observer.
+func (o *Observer) SetRange(viewRange float64) {
+	o.update(o.position, viewRange)
+}
+
+// Remove removes the Observer from its VisibilityMap.
+func (o *Observer) Remove() {
+
+	o.update(nil, o.viewRange)
+
+	observers := o.visibility.observers
+	for i, observer := range observers {
+		if observer == o {
+			o.visibility.observers = append(observers[:i], observers[i+1:]...)
+			break
+		}
+	}
+}
+
+// update moves the Observer to the position (nil removes it) with the view range and changes the visibility of the
+// cells, which have entered or left its field of view.
+func (o *Observer) update(position *Cell, viewRange float64) {
+
+	v := o.visibility
+	var seen []*Cell
+	if position != nil {
+		grid := v.layer.grid
+		reach := int(math.Floor(viewRange))
+		for y := position.Y - reach; y <= position.Y+reach; y++ {
+			for x := position.X - reach; x <= position.X+reach; x++ {
+				cell := grid.Get(x, y)
+				if cell != nil && Euclidean.Distance(cell, position) <= viewRange && grid.LineOfSight(position, cell) {
+					seen = append(seen, cell)
+				}
+			}
+		}
+	}
+
+	// the cells seen before and after the update keep their amount of watchers
+	changed := false
+	for _, cell := range seen {
+		v.watchers[cell]++
+		if v.watchers[cell] == 1 {
+			v.layer.addPenalty(cell, v.penalty)
+			changed = true
+		}
+	}
+	for _, cell := range o.seen {
+		v.watchers[cell]--
+		if v.watchers[cell] == 0 {
+			delete(v.watchers, cell)
+			v.layer.addPenalty(cell, -v.penalty)
+			changed = true
+		}
+	}
+
+	o.position, o.viewRange, o.seen = position, viewRange, seen
+	if changed {
+		v.layer.grid.MarkChanged()
+	}
+}
@@ -0,0 +1,198 @@
+package paths
+
+import (
+	"math"
+	"sort"
+)
+
+// A PathWalker moves an agent smoothly along a Path. While Path.Advance jumps from one Cell to the next, the PathWalker
+// keeps track of the exact distance the agent has travelled along the Path, which is needed for steering behaviors.
+// Positions are measured in cell coordinates: the Cell at X:2 Y:3 is located at the point (2, 3).
+// The CurrentIndex of the Path is kept in sync with the last Cell the PathWalker passed.
+//
+// The callbacks are optional and are called while the PathWalker is moved with Move or Update, so game logic can react to
+// the progress without polling it every frame.
+type PathWalker struct {
+	Path *Path
+	// OnWaypoint is called for each Cell of the Path the PathWalker reaches, including the last one.
+	OnWaypoint func(index int, cell *Cell)
+	// OnArrive is called once, when the PathWalker reaches the end of the Path. It's never called for an empty Path.
+	OnArrive func()
+	// OnBlocked is called when the next Cell on the Path isn't walkable anymore, e.g. because a door has been closed.
+	// It's checked on each Move and Update, even if the PathWalker doesn't get any further. It is called once per
+	// blocked Cell; if the Cell becomes walkable and blocked again, it is called again.
+	OnBlocked func(cell *Cell)
+	// distances contains the distance from the start of the Path to each of its Cells.
+	distances []float64
+	travelled float64
+	arrived   bool
+	blocked   *Cell
+}
+
+// NewPathWalker returns a new PathWalker standing at the start of the passed Path. A nil Path is treated like an empty
+// one, e.g. if no path has been found.
+func NewPathWalker(path *Path) *PathWalker {
+
+	if path == nil {
+		path = &Path{}
+	}
+	w := &PathWalker{Path: path}
+
+	for i, cell := range path.Cells {
+		if i == 0 {
+			w.distances = append(w.distances, 0)
+			continue
+		}
+		previous := path.Cells[i-1]
+		w.distances = append(w.distances, w.distances[i-1]+math.Hypot(float64(cell.X-previous.X), float64(cell.Y-previous.Y)))
+	}
+
+	path.Restart()
+	return w
+}
+
+// Length returns the length of the whole Path.
+func (w *PathWalker) Length() float64 {
+	if len(w.distances) == 0 {
+		return 0
+	}
+	return w.distances[len(w.distances)-1]
+}
+
+// Travelled returns the distance the PathWalker has travelled along the Path so far.
+func (w *PathWalker) Travelled() float64 {
+	return w.travelled
+}
+
+// Remaining returns the distance left until the end of the Path is reached.
+func (w *PathWalker) Remaining() float64 {
+	return w.Length() - w.travelled
+}
+
+// Position returns the current position of the PathWalker on the Path.
+func (w *PathWalker) Position() (x, y float64) {
+	return w.pointAt(w.travelled)
+}
+
+// Move moves the PathWalker the passed distance further along the Path. It stops at the end of the Path.
+func (w *PathWalker) Move(distance float64) {
+	w.setTravelled(w.travelled + distance)
+	w.checkBlocked()
+}
+
+// Update tells the PathWalker where the agent actually is. Agents controlled by steering behaviors don't follow the
+// Path exactly, so the position is projected onto the closest point of the remaining Path. The PathWalker never moves
+// backwards.
+func (w *PathWalker) Update(x, y float64) {
+
+	closest, closestDistance := w.travelled, math.Inf(1)
+
+	for i := w.segment(); i < len(w.distances)-1; i++ {
+
+		from, to := w.Path.Cells[i], w.Path.Cells[i+1]
+		dx, dy := float64(to.X-from.X), float64(to.Y-from.Y)
+		segmentLength := w.distances[i+1] - w.distances[i]
+
+		// position of the projection on the segment, from 0 (at from) to 1 (at to)
+		t := 0.0
+		if segmentLength > 0 {
+			t = ((x-float64(from.X))*dx + (y-float64(from.Y))*dy) / (segmentLength * segmentLength)
+			t = math.Max(0, math.Min(1, t))
+		}
+
+		distance := math.Hypot(float64(from.X)+dx*t-x, float64(from.Y)+dy*t-y)
+		if distance < closestDistance {
+			closest, closestDistance = w.distances[i]+segmentLength*t, distance
+		}
+	}
+
+	if closest > w.travelled {
+		w.setTravelled(closest)
+	}
+	// the next cell may become blocked while the agent is standing still
+	w.checkBlocked()
+}
+
+// SteerTarget returns the point on the Path, which lies the passed distance ahead of the PathWalker. Steering the agent
+// towards this point instead of the next Cell results in smooth movement, which cuts corners slightly. Near the end,
+// the end of the Path is returned.
+func (w *PathWalker) SteerTarget(lookahead float64) (x, y float64) {
+	return w.pointAt(w.travelled + lookahead)
+}
+
+// IsAtEnd returns if the PathWalker has reached the end of the Path.
+func (w *PathWalker) IsAtEnd() bool {
+	return w.travelled >= w.Length()
+}
+
+func (w *PathWalker) setTravelled(distance float64) {
+
+	// there is nothing to arrive at on an empty Path
+	if len(w.Path.Cells) == 0 {
+		return
+	}
+
+	previous := w.segment()
+	w.travelled = math.Max(0, math.Min(w.Length(), distance))
+	current := w.segment()
+	w.Path.SetIndex(current)
+
+	if w.OnWaypoint != nil {
+		for i := previous + 1; i <= current; i++ {
+			w.OnWaypoint(i, w.Path.Cells[i])
+		}
+	}
+
+	if w.IsAtEnd() && !w.arrived {
+		w.arrived = true
+		if w.OnArrive != nil {
+			w.OnArrive()
+		}
+	}
+}
+
+// checkBlocked calls OnBlocked, if the next cell on the Path became unwalkable.
+func (w *PathWalker) checkBlocked() {
+
+	next := w.Path.Next()
+	if next == nil || next.Walkable {
+		w.blocked = nil
+		return
+	}
+
+	if next != w.blocked {
+		w.blocked = next
+		if w.OnBlocked != nil {
+			w.OnBlocked(next)
+		}
+	}
+}
+
+// segment returns the index of the Cell the PathWalker passed last.
+func (w *PathWalker) segment() int {
+	// the first cell further away than the travelled distance ends the current segment
+	i := sort.Search(len(w.distances), func(i int) bool { return w.distances[i] > w.travelled })
+	if i == 0 {
+		return 0
+	}
+	return i - 1
+}
+
+// pointAt returns the point on the Path at the passed distance from the start.
+func (w *PathWalker) pointAt(distance float64) (x, y float64) {
+
+	if len(w.distances) == 0 {
+		return 0, 0
+	}
+
+	distance = math.Max(0, math.Min(w.Length(), distance))
+	i := sort.Search(len(w.distances), func(i int) bool { return w.distances[i] >= distance })
+	if i == 0 {
+		return float64(w.Path.Cells[0].X), float64(w.Path.Cells[0].Y)
+	}
+
+	from, to := w.Path.Cells[i-1], w.Path.Cells[i]
+	t := (distance - w.distances[i-1]) / (w.distances[i] - w.distances[i-1])
+
+	return float64(from.X) + float64(to.X-from.X)*t, float64(from.Y) + float64(to.Y-from.Y)*t
+}
@@ -0,0 +1,116 @@
+package paths
+
+// A WaterProfile defines how an agent moves on land and in water. Cells, whose TotalHeight is below the sea level of
+// the Grid (see Grid.SetSeaLevel), are water; their depth is the distance between the sea level and their height.
+// This way, imported heightmaps can be used for naval and amphibious routing without marking the water by hand.
+// Use one of the predefined profiles or configure one yourself.
+type WaterProfile struct {
+	// Land and Water define, on which of both the agent can move.
+	Land  bool `json:"land"`
+	Water bool `json:"water"`
+	// MinDepth is the minimum depth of water cells, e.g. the draft of a boat.
+	MinDepth float64 `json:"minDepth"`
+	// MaxDepth is the maximum depth of water cells, e.g. for wading agents. Zero means unlimited.
+	MaxDepth float64 `json:"maxDepth"`
+	// WaterCostMultiplier is multiplied with the cost of moves into water. Zero means 1.
+	WaterCostMultiplier float64 `json:"waterCostMultiplier"`
+}
+
+// WalkerProfile returns a WaterProfile for agents, which can't enter water at all.
+func WalkerProfile() *WaterProfile {
+	return &WaterProfile{Land: true}
+}
+
+// WaderProfile returns a WaterProfile for agents, which can walk through water up to the passed depth, but only slowly.
+func WaderProfile(maxDepth float64) *WaterProfile {
+	return &WaterProfile{Land: true, Water: true, MaxDepth: maxDepth, WaterCostMultiplier: 2}
+}
+
+// SwimmerProfile returns a WaterProfile for agents, which can swim through water of any depth, but slower than they
+// walk.
+func SwimmerProfile() *WaterProfile {
+	return &WaterProfile{Land: true, Water: true, WaterCostMultiplier: 3}
+}
+
+// BoatProfile returns a WaterProfile for boats, which can't go on land and need water at least as deep as their draft.
+func BoatProfile(draft float64) *WaterProfile {
+	return &WaterProfile{Water: true, MinDepth: draft}
+}
+
+// AmphibiousProfile returns a WaterProfile for agents, which move on land and in water equally well.
+func AmphibiousProfile() *WaterProfile {
+	return &WaterProfile{Land: true, Water: true}
+}
+
+// SetSeaLevel sets the height of the water surface of the Grid. Cells with a lower TotalHeight are water. The sea level
+// is only used by searches with a WaterProfile (see PathSettings.Water). The default sea level is 0.
+func (m *Grid) SetSeaLevel(level float64) {
+	m.seaLevel = level
+	m.MarkChanged()
+}
+
+// SeaLevel returns the height of the water surface of the Grid, see SetSeaLevel.
+func (m *Grid) SeaLevel() float64 {
+	return m.seaLevel
+}
+
+// Depth returns how deep the Cell is below the sea level. Land cells have a depth of 0.
+func (m *Grid) Depth(cell *Cell) float64 {
+	if depth := m.seaLevel - cell.TotalHeight(); depth > 0 {
+		return depth
+	}
+	return 0
+}
+
+// waterLevel returns the height of the water surface used by a search with the passed settings.
+func (m *Grid) waterLevel(settings *PathSettings) float64 {
+	if settings.WaterLevel != nil {
+		return *settings.WaterLevel
+	}
+	return m.seaLevel
+}
+
+// depth works like Depth, but uses the water level of the settings.
+func (m *Grid) depth(cell *Cell, settings *PathSettings) float64 {
+	if depth := m.waterLevel(settings) - cell.TotalHeight(); depth > 0 {
+		return depth
+	}
+	return 0
+}
+
+// walkerProfile is used by searches with a WaterLevel, but without a WaterProfile.
+var walkerProfile = WalkerProfile()
+
+// waterProfile returns the WaterProfile of the settings. If there is none but a WaterLevel is set, the agent can't
+// enter the water, like with WalkerProfile.
+func waterProfile(settings *PathSettings) *WaterProfile {
+	if settings.Water == nil && settings.WaterLevel != nil {
+		return walkerProfile
+	}
+	return settings.Water
+}
+
+// canTraverseWater returns if the WaterProfile of the settings allows to enter the Cell.
+func (m *Grid) canTraverseWater(cell *Cell, settings *PathSettings) bool {
+
+	profile := waterProfile(settings)
+	if profile == nil {
+		return true
+	}
+
+	depth := m.depth(cell, settings)
+	if depth <= 0 {
+		return profile.Land
+	}
+
+	return profile.Water && depth >= profile.MinDepth && (profile.MaxDepth <= 0 || depth <= profile.MaxDepth)
+}
+
+// surfaceHeight returns the height an agent is at on the Cell. With a WaterProfile, agents in water are at the water
+// surface, so waves of the ground below don't count as steps.
+func (m *Grid) surfaceHeight(cell *Cell, settings *PathSettings) float64 {
+	if waterProfile(settings) != nil && m.depth(cell, settings) > 0 {
+		return m.waterLevel(settings)
+	}
+	return cell.TotalHeight()
+}
@@ -0,0 +1,221 @@
+package paths
+
+import "math"
+
+// A Watershed describes how water would flow over the heights (see Cell.TotalHeight) of a Grid: each Cell drains to
+// its steepest lower neighbor, the water pools in local minima and the cells draining to the same minimum form a
+// basin, e.g. for placing rivers and lakes in generated maps. It's computed once by Grid.AnalyzeWatershed; after
+// changes of the Grid, the analysis has to be run again (see Outdated).
+type Watershed struct {
+	grid     *Grid
+	revision uint64
+	// directions contains the Direction each cell drains to, basins the index of its basin and accumulation the amount
+	// of cells draining through it, all indexed like the cells in a search.
+	directions   []Direction
+	basins       []int
+	accumulation []int
+	// minima contains the cells of the local minimum of each basin.
+	minima [][]*Cell
+}
+
+// AnalyzeWatershed returns the Watershed of the Grid. The walkability of the cells is ignored. Water on flat areas
+// flows to the nearest lower Cell at their edge; flat areas without any lower neighbor are local minima.
+func (m *Grid) AnalyzeWatershed() *Watershed {
+
+	size := m.Width() * m.Height()
+	w := &Watershed{
+		grid:         m,
+		revision:     m.Revision(),
+		directions:   make([]Direction, size),
+		basins:       make([]int, size),
+		accumulation: make([]int, size),
+	}
+
+	// each cell drains to the neighbor with the steepest slope down
+	drained := []*Cell{}
+	for _, cell := range m.AllCells() {
+		steepest := 0.0
+		for _, neighbor := range m.neighbors(cell, true) {
+			slope := (cell.TotalHeight() - neighbor.TotalHeight()) / Euclidean.Distance(cell, neighbor)
+			if slope > steepest {
+				steepest = slope
+				w.directions[m.cellIndex(cell)] = DirectionBetween(cell, neighbor)
+			}
+		}
+		if steepest > 0 {
+			drained = append(drained, cell)
+		}
+	}
+
+	// on flat areas, the water flows towards the nearest cell draining off the area
+	for len(drained) > 0 {
+		next := []*Cell{}
+		for _, cell := range drained {
+			for _, neighbor := range m.neighbors(cell, true) {
+				index := m.cellIndex(neighbor)
+				if w.directions[index] == 0 && neighbor.TotalHeight() == cell.TotalHeight() {
+					w.directions[index] = DirectionBetween(neighbor, cell)
+					next = append(next, neighbor)
+				}
+			}
+		}
+		drained = next
+	}
+
+	// the remaining cells without a Direction form the minima, flat areas count as one minimum
+	for i := range w.basins {
+		w.basins[i] = -1
+	}
+	for _, cell := range m.AllCells() {
+		index := m.cellIndex(cell)
+		if w.directions[index] != 0 || w.basins[index] != -1 {
+			continue
+		}
+		basin := len(w.minima)
+		w.basins[index] = basin
+		minimum := []*Cell{cell}
+		for i := 0; i < len(minimum); i++ {
+			for _, neighbor := range m.neighbors(minimum[i], true) {
+				neighborIndex := m.cellIndex(neighbor)
+				if w.basins[neighborIndex] == -1 && neighbor.TotalHeight() == cell.TotalHeight() {
+					w.basins[neighborIndex] = basin
+					minimum = append(minimum, neighbor)
+				}
+			}
+		}
+		w.minima = append(w.minima, minimum)
+	}
+
+	// the cells are visited downstream: a cell is visited once all cells draining to it have been, so its accumulation
+	// is complete
+	upstream := make([]int, size)
+	for _, cell := range m.AllCells() {
+		if next := w.Downstream(cell); next != nil {
+			upstream[m.cellIndex(next)]++
+		}
+	}
+	sources := []*Cell{}
+	for _, cell := range m.AllCells() {
+		if upstream[m.cellIndex(cell)] == 0 {
+			sources = append(sources, cell)
+		}
+	}
+	order := []*Cell{}
+	for len(sources) > 0 {
+		cell := sources[len(sources)-1]
+		sources = sources[:len(sources)-1]
+		order = append(order, cell)
+		index := m.cellIndex(cell)
+		w.accumulation[index]++
+		if next := w.Downstream(cell); next != nil {
+			nextIndex := m.cellIndex(next)
+			w.accumulation[nextIndex] += w.accumulation[index]
+			if upstream[nextIndex]--; upstream[nextIndex] == 0 {
+				sources = append(sources, next)
+			}
+		}
+	}
+
+	// the basins are passed upstream from the minima
+	for i := len(order) - 1; i >= 0; i-- {
+		if next := w.Downstream(order[i]); next != nil {
+			w.basins[m.cellIndex(order[i])] = w.basins[m.cellIndex(next)]
+		}
+	}
+
+	return w
+}
+
+// Outdated returns if the Grid has been changed since the analysis.
+func (w *Watershed) Outdated() bool {
+	return w.grid.Revision() != w.revision
+}
+
+// FlowDirection returns the Direction the water on the Cell flows to. For cells of local minima, 0 is returned.
+func (w *Watershed) FlowDirection(cell *Cell) Direction {
+	return w.directions[w.grid.cellIndex(cell)]
+}
+
+// Downstream returns the neighbor the water on the Cell flows to, or nil if the Cell is part of a local minimum.
+func (w *Watershed) Downstream(cell *Cell) *Cell {
+
+	direction := w.FlowDirection(cell)
+	if direction == 0 {
+		return nil
+	}
+	dx, dy := direction.Offset()
+	return w.grid.Get(cell.X+dx, cell.Y+dy)
+}
+
+// FlowPath returns the cells the water flows along from the Cell to its local minimum, including both.
+func (w *Watershed) FlowPath(cell *Cell) []*Cell {
+
+	cells := []*Cell{}
+	for c := cell; c != nil; c = w.Downstream(c) {
+		cells = append(cells, c)
+	}
+	return cells
+}
+
+// Accumulation returns the amount of cells, whose water flows through the Cell, including the Cell itself. Cells with
+// a high accumulation are where rivers would form.
+func (w *Watershed) Accumulation(cell *Cell) int {
+	return w.accumulation[w.grid.cellIndex(cell)]
+}
+
+// Basin returns the index of the basin of the Cell, see Minima.
+func (w *Watershed) Basin(cell *Cell) int {
+	return w.basins[w.grid.cellIndex(cell)]
+}
+
+// Minima returns the cells of the local minimum of each basin, indexed like the basins. Flat minima contain multiple
+// cells.
+func (w *Watershed) Minima() [][]*Cell {
+	return w.minima
+}
+
+// BasinCells returns all cells of the basin with the index.
+func (w *Watershed) BasinCells(basin int) []*Cell {
+
+	cells := []*Cell{}
+	for i, b := range w.basins {
+		if b == basin {
+			cells = append(cells, w.grid.cellAt(int32(i)))
+		}
+	}
+	return cells
+}
+
+// Streams returns the cells with an Accumulation of at least minAccumulation, ordered by their Accumulation, the most
+// first, e.g. as the course of rivers.
+func (w *Watershed) Streams(minAccumulation int) []*Cell {
+
+	candidates := []*Cell{}
+	for i, accumulation := range w.accumulation {
+		if accumulation >= minAccumulation {
+			candidates = append(candidates, w.grid.cellAt(int32(i)))
+		}
+	}
+
+	cells := []*Cell{}
+	for _, score := range ScoreCells(candidates, func(cell *Cell) float64 { return float64(w.Accumulation(cell)) }) {
+		cells = append(cells, score.Cell)
+	}
+	return cells
+}
+
+// PourHeight returns the lowest height (see Cell.TotalHeight) the water of the basin with the index has to rise to for
+// flowing over into a neighboring basin, e.g. for the surface of a lake filling it. If the basin covers the whole Grid,
+// +Inf is returned.
+func (w *Watershed) PourHeight(basin int) float64 {
+
+	height := math.Inf(1)
+	for _, cell := range w.BasinCells(basin) {
+		for _, neighbor := range w.grid.neighbors(cell, true) {
+			if w.Basin(neighbor) != basin {
+				height = math.Min(height, math.Max(cell.TotalHeight(), neighbor.TotalHeight()))
+			}
+		}
+	}
+	return height
+}
@@ -0,0 +1,312 @@
+package paths
+
+import (
+	"container/heap"
+	"errors"
+)
+
+// ErrUnknownCell is returned if a Cell doesn't belong to any Grid of a World.
+var ErrUnknownCell = errors.New("cell doesn't belong to any grid of the world")
+
+// A World connects multiple Grids, e.g. an overworld and its dungeons, with Exits. Paths can be searched from a Cell
+// on one Grid to a Cell on another one; they are made up of one segment per Grid the path goes through.
+type World struct {
+	grids map[string]*Grid
+	exits []*Exit
+}
+
+// An Exit connects a Cell of one Grid with a Cell of another (or the same) Grid, like a door, a staircase or a map
+// edge. Exits are one-way; use World.ConnectBoth for two-way connections.
+type Exit struct {
+	Name     string
+	From, To *Cell
+	// Cost is the cost of using the Exit, in addition to the cost of the cells.
+	Cost float64
+	// fromGrid and toGrid are the names of the grids From and To belong to.
+	fromGrid, toGrid string
+}
+
+// FromGrid returns the name of the Grid the Exit starts on.
+func (exit *Exit) FromGrid() string {
+	return exit.fromGrid
+}
+
+// ToGrid returns the name of the Grid the Exit leads to.
+func (exit *Exit) ToGrid() string {
+	return exit.toGrid
+}
+
+// A WorldPath is a path through a World. It consists of one Segment per Grid visited.
+type WorldPath struct {
+	Segments []PathSegment
+	// Cost is the total cost of the path, including the costs of the used exits. The cost of the very first cell
+	// isn't included.
+	Cost float64
+}
+
+// A PathSegment is the part of a WorldPath on one Grid.
+type PathSegment struct {
+	// Grid is the name of the Grid the segment is on.
+	Grid string
+	Path *Path
+	// Exit is the Exit, which is used at the end of this segment to get to the next one. It's nil for the last segment.
+	Exit *Exit
+}
+
+// NewWorld returns an empty World.
+func NewWorld() *World {
+	return &World{grids: make(map[string]*Grid)}
+}
+
+// AddGrid adds the Grid to the World under the passed name. A Grid with the same name is replaced.
+func (w *World) AddGrid(name string, grid *Grid) {
+	w.grids[name] = grid
+}
+
+// Grid returns the Grid with the passed name, or nil if there is none.
+func (w *World) Grid(name string) *Grid {
+	return w.grids[name]
+}
+
+// GridOf returns the name of the Grid the Cell belongs to. If the Cell doesn't belong to any Grid of the World, false
+// is returned.
+func (w *World) GridOf(cell *Cell) (string, bool) {
+	for name, grid := range w.grids {
+		if grid.Get(cell.X, cell.Y) == cell {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Connect adds a one-way Exit from one Cell to another. Both Cells have to belong to grids of the World, otherwise
+// ErrUnknownCell is returned.
+func (w *World) Connect(name string, from, to *Cell, cost float64) (*Exit, error) {
+
+	fromGrid, found := w.GridOf(from)
+	if !found {
+		return nil, ErrUnknownCell
+	}
+	toGrid, found := w.GridOf(to)
+	if !found {
+		return nil, ErrUnknownCell
+	}
+
+	exit := &Exit{Name: name, From: from, To: to, Cost: cost, fromGrid: fromGrid, toGrid: toGrid}
+	w.exits = append(w.exits, exit)
+	return exit, nil
+}
+
+// ConnectBoth adds two Exits with the same name: one from a to b and one from b to a.
+func (w *World) ConnectBoth(name string, a, b *Cell, cost float64) error {
+	if _, err := w.Connect(name, a, b, cost); err != nil {
+		return err
+	}
+	_, err := w.Connect(name, b, a, cost)
+	return err
+}
+
+// Exits returns all Exits of the World.
+func (w *World) Exits() []*Exit {
+	return w.exits
+}
+
+// GetPath searches the cheapest path from the start Cell to the destination Cell, which may be on different grids. The
+// movement rules (step height, diagonals, ...) are taken from the settings and apply on all grids; the start and end of
+// the settings are ignored. If no path can be found, nil is returned. ErrUnknownCell is returned if start or dest
+// don't belong to the World.
+func (w *World) GetPath(start, dest *Cell, settings PathSettings) (*WorldPath, error) {
+
+	startGrid, found := w.GridOf(start)
+	if !found {
+		return nil, ErrUnknownCell
+	}
+	destGrid, found := w.GridOf(dest)
+	if !found {
+		return nil, ErrUnknownCell
+	}
+
+	// The world is searched on an abstract graph: each node is a cell the path can be on after entering a grid (the
+	// start and the targets of the exits) or the destination. Their costs are taken from backwards cost fields towards
+	// the exits and the destination.
+	fields := make(map[*Cell]map[*Cell]float64)
+	costTo := func(gridName string, from, to *Cell) (float64, bool) {
+		if _, ok := fields[to]; !ok {
+			fields[to] = w.grids[gridName].costsToGoals([]*Cell{to}, &settings)
+		}
+		cost, reachable := fields[to][from]
+		return cost, reachable
+	}
+
+	gridOf := map[*Cell]string{start: startGrid, dest: destGrid}
+	// usedExit contains the exit, which has been used to get to a node
+	usedExit := make(map[*Cell]*Exit)
+	closed := make(map[*Cell]bool)
+	bestCosts := map[*Cell]float64{start: 0}
+
+	openNodes := minHeap{}
+	heap.Push(&openNodes, &Node{Cell: start})
+
+	var reached *Node
+
+	for len(openNodes) > 0 {
+
+		node := heap.Pop(&openNodes).(*Node)
+		if closed[node.Cell] {
+			continue
+		}
+		closed[node.Cell] = true
+
+		if node.Cell == dest {
+			reached = node
+			break
+		}
+
+		gridName := gridOf[node.Cell]
+
+		push := func(cell *Cell, cost float64, exit *Exit) {
+			if previousCost, ok := bestCosts[cell]; closed[cell] || (ok && previousCost <= cost) {
+				return
+			}
+			bestCosts[cell] = cost
+			usedExit[cell] = exit
+			heap.Push(&openNodes, &Node{Cell: cell, Parent: node, Cost: cost})
+		}
+
+		if gridName == destGrid {
+			if cost, reachable := costTo(gridName, node.Cell, dest); reachable {
+				push(dest, node.Cost+cost, nil)
+			}
+		}
+
+		for _, exit := range w.exits {
+			if exit.fromGrid != gridName || !exit.To.Walkable {
+				continue
+			}
+			if cost, reachable := costTo(gridName, node.Cell, exit.From); reachable {
+				gridOf[exit.To] = exit.toGrid
+				push(exit.To, node.Cost+cost+exit.Cost, exit)
+			}
+		}
+	}
+
+	if reached == nil {
+		return nil, nil
+	}
+
+	// walk back through the nodes to get the cells each segment starts on. Usually, the destination only ends the last
+	// segment. If an exit leads directly onto it or the path doesn't move at all, it gets a segment on its own.
+	segmentStarts := []*Cell{}
+	if usedExit[dest] != nil || reached.Parent == nil {
+		segmentStarts = append(segmentStarts, dest)
+	}
+	for n := reached.Parent; n != nil; n = n.Parent {
+		segmentStarts = append([]*Cell{n.Cell}, segmentStarts...)
+	}
+
+	worldPath := &WorldPath{Cost: reached.Cost}
+
+	for i, segmentStart := range segmentStarts {
+
+		segment := PathSegment{Grid: gridOf[segmentStart]}
+		if i < len(segmentStarts)-1 {
+			segment.Exit = usedExit[segmentStarts[i+1]]
+		}
+
+		segmentEnd := dest
+		if segment.Exit != nil {
+			segmentEnd = segment.Exit.From
+		}
+
+		segmentSettings := settings
+		segmentSettings.start, segmentSettings.end = segmentStart, segmentEnd
+		segmentSettings.RequireOptimal = true
+		segment.Path = w.grids[segment.Grid].findPath(&segmentSettings, nil)
+
+		worldPath.Segments = append(worldPath.Segments, segment)
+	}
+
+	return worldPath, nil
+}
+
+// ErrInvalidSide is returned by World.LinkChunks if the side isn't one of North, East, South or West.
+var ErrInvalidSide = errors.New("side has to be North, East, South or West")
+
+// LinkChunks connects the border cells of two grids, which lie next to each other in a chunked world: b lies on the
+// passed side of a. Each pair of border cells facing each other gets an Exit in each direction, in which the move is
+// possible with the settings (walkable, step and drop height, occupancy, categories, water), so paths cross the seam
+// as if both grids were one. Moves across the seam have to be allowed by the Directions of the settings as well. The
+// exits cost as much as entering the cell they lead to. Diagonal moves across the seam aren't linked. The amount of
+// created exits is returned.
+func (w *World) LinkChunks(a, b string, side Direction, settings PathSettings) (int, error) {
+
+	gridA, gridB := w.grids[a], w.grids[b]
+	if gridA == nil || gridB == nil {
+		return 0, ErrUnknownCell
+	}
+
+	// borderCells returns the cells facing each other at position i along the seam
+	var borderCells func(i int) (*Cell, *Cell)
+	var length int
+
+	switch side {
+	case North:
+		length = minInt(gridA.Width(), gridB.Width())
+		borderCells = func(i int) (*Cell, *Cell) { return gridA.Get(i, 0), gridB.Get(i, gridB.Height()-1) }
+	case South:
+		length = minInt(gridA.Width(), gridB.Width())
+		borderCells = func(i int) (*Cell, *Cell) { return gridA.Get(i, gridA.Height()-1), gridB.Get(i, 0) }
+	case West:
+		length = minInt(gridA.Height(), gridB.Height())
+		borderCells = func(i int) (*Cell, *Cell) { return gridA.Get(0, i), gridB.Get(gridB.Width()-1, i) }
+	case East:
+		length = minInt(gridA.Height(), gridB.Height())
+		borderCells = func(i int) (*Cell, *Cell) { return gridA.Get(gridA.Width()-1, i), gridB.Get(0, i) }
+	default:
+		return 0, ErrInvalidSide
+	}
+
+	created := 0
+	for i := 0; i < length; i++ {
+
+		cellA, cellB := borderCells(i)
+
+		if cellA.Walkable && settings.allowsDirection(side.Offset()) && checkStep(gridA, gridB, cellA, cellB, &settings) == NotBlocked {
+			w.exits = append(w.exits, &Exit{Name: a + ">" + b, From: cellA, To: cellB, Cost: cellB.pathCost(), fromGrid: a, toGrid: b})
+			created++
+		}
+		if cellB.Walkable && settings.allowsDirection(side.Opposite().Offset()) && checkStep(gridB, gridA, cellB, cellA, &settings) == NotBlocked {
+			w.exits = append(w.exits, &Exit{Name: b + ">" + a, From: cellB, To: cellA, Cost: cellA.pathCost(), fromGrid: b, toGrid: a})
+			created++
+		}
+	}
+
+	return created, nil
+}
+
+// LinkChunkLayout links all chunks of a chunked world, which lie next to each other, with LinkChunks. The layout
+// maps the position of each chunk (in chunks, not cells) to the name of its grid.
+func (w *World) LinkChunkLayout(layout map[Point]string, settings PathSettings) error {
+
+	for position, name := range layout {
+		if east, exists := layout[Point{position.X + 1, position.Y}]; exists {
+			if _, err := w.LinkChunks(name, east, East, settings); err != nil {
+				return err
+			}
+		}
+		if south, exists := layout[Point{position.X, position.Y + 1}]; exists {
+			if _, err := w.LinkChunks(name, south, South, settings); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}